@@ -10,6 +10,13 @@ type SpinnakerApplicationResource struct {
 	ApplicationName string `json:"applicationName,omitempty"`
 }
 
+// ApplicationPermissions defines the roles permitted to access Spinnaker Application
+type ApplicationPermissions struct {
+	Read    []string `json:"READ,omitempty"`
+	Write   []string `json:"WRITE,omitempty"`
+	Execute []string `json:"EXECUTE,omitempty"`
+}
+
 // ApplicationConditionType defines codition type
 type ApplicationConditionType string
 
@@ -18,12 +25,20 @@ const (
 	ApplicationCreationComplete ApplicationConditionType = "CreationComplete"
 	// ApplicationDeletionComplete means deletion has finished
 	ApplicationDeletionComplete ApplicationConditionType = "DeletionComplete"
+	// ApplicationPermissionsValid means all roles of permissions are known to Fiat, and is Unknown when some of them
+	// cannot be verified through Gate
+	ApplicationPermissionsValid ApplicationConditionType = "PermissionsValid"
+	// ApplicationControllerLockedOut means permissions do not allow the controller to update Application
+	ApplicationControllerLockedOut ApplicationConditionType = "ControllerLockedOut"
+	// ApplicationPaused means reconciliation is paused and no change is pushed to Spinnaker
+	ApplicationPaused ApplicationConditionType = "Paused"
 )
 
 // ApplicationCondition defines condition struct
 type ApplicationCondition struct {
-	Type   ApplicationConditionType `json:"type"`
-	Status string                   `json:"status"`
+	Type    ApplicationConditionType `json:"type"`
+	Status  string                   `json:"status"`
+	Message string                   `json:"message,omitempty"`
 }
 
 // ApplicationStatus defines the observed state of Application
@@ -31,6 +46,12 @@ type ApplicationStatus struct {
 	SpinnakerResource SpinnakerApplicationResource `json:"spinnakerResource,omitempty"`
 	Conditions        []ApplicationCondition       `json:"conditions,omitempty"`
 	Hash              string                       `json:"hash,omitempty"`
+	Permissions       *ApplicationPermissions      `json:"permissions,omitempty"`
+	// NotificationHash is the hash of the Notifications applied to the application
+	NotificationHash string `json:"notificationHash,omitempty"`
+	// ManagedNotifications are the notifications applied from Notification resources as type:address, so that they
+	// are removed from Spinnaker once no longer applied while the ones set by hand are left alone
+	ManagedNotifications []string `json:"managedNotifications,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CanaryScope defines where and when metrics are queried
+type CanaryScope struct {
+	Scope               string            `json:"scope"`
+	Location            string            `json:"location,omitempty"`
+	ExtendedScopeParams map[string]string `json:"extendedScopeParams,omitempty"`
+}
+
+// CanaryAnalysisScope defines the pair of control and experiment scopes
+type CanaryAnalysisScope struct {
+	// Name is the scope name referenced by the metrics of canary config, which defaults to "default"
+	Name       string      `json:"name,omitempty"`
+	Control    CanaryScope `json:"control"`
+	Experiment CanaryScope `json:"experiment"`
+}
+
+// CanaryAnalysisThresholds defines the score thresholds of verdict
+type CanaryAnalysisThresholds struct {
+	Pass     int32 `json:"pass"`
+	Marginal int32 `json:"marginal"`
+}
+
+// CanaryAnalysisSpec defines the desired state of CanaryAnalysis
+type CanaryAnalysisSpec struct {
+	CanaryConfigName   string                   `json:"canaryConfigName"`
+	Application        string                   `json:"application,omitempty"`
+	MetricsAccountName string                   `json:"metricsAccountName,omitempty"`
+	StorageAccountName string                   `json:"storageAccountName,omitempty"`
+	Scopes             []CanaryAnalysisScope    `json:"scopes"`
+	StartTime          metaV1.Time              `json:"startTime"`
+	EndTime            metaV1.Time              `json:"endTime"`
+	StepSeconds        int32                    `json:"stepSeconds,omitempty"`
+	Thresholds         CanaryAnalysisThresholds `json:"thresholds"`
+}
+
+// SpinnakerCanaryAnalysisResource defines the resource of Spinnaker
+type SpinnakerCanaryAnalysisResource struct {
+	CanaryConfigID string `json:"canaryConfigId,omitempty"`
+	ID             string `json:"id,omitempty"`
+}
+
+// CanaryAnalysisMetricResult defines the classification of a metric
+type CanaryAnalysisMetricResult struct {
+	Name                 string `json:"name"`
+	Classification       string `json:"classification"`
+	ClassificationReason string `json:"classificationReason,omitempty"`
+}
+
+// CanaryAnalysisConditionType defines codition type
+type CanaryAnalysisConditionType string
+
+const (
+	// CanaryAnalysisStarted means the analysis has been started
+	CanaryAnalysisStarted CanaryAnalysisConditionType = "Started"
+	// CanaryAnalysisComplete means the analysis has finished with a verdict
+	CanaryAnalysisComplete CanaryAnalysisConditionType = "AnalysisComplete"
+	// CanaryAnalysisPaused means reconciliation is paused and no change is pushed to Spinnaker
+	CanaryAnalysisPaused CanaryAnalysisConditionType = "Paused"
+)
+
+// CanaryAnalysisCondition defines condition struct
+type CanaryAnalysisCondition struct {
+	Type   CanaryAnalysisConditionType `json:"type"`
+	Status string                      `json:"status"`
+	Reason string                      `json:"reason,omitempty"`
+}
+
+// CanaryAnalysisStatus defines the observed state of CanaryAnalysis
+type CanaryAnalysisStatus struct {
+	SpinnakerResource SpinnakerCanaryAnalysisResource `json:"spinnakerResource,omitempty"`
+	Status            string                          `json:"status,omitempty"`
+	Verdict           string                          `json:"verdict,omitempty"`
+	Score             string                          `json:"score,omitempty"`
+	Metrics           []CanaryAnalysisMetricResult    `json:"metrics,omitempty"`
+	ReportURL         string                          `json:"reportUrl,omitempty"`
+	Conditions        []CanaryAnalysisCondition       `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,path=canaryanalyses
+// +kubebuilder:printcolumn:name="CANARY-CONFIG",type=string,JSONPath=`.spec.canaryConfigName`
+// +kubebuilder:printcolumn:name="STATUS",type=string,JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="VERDICT",type=string,JSONPath=`.status.verdict`
+// +kubebuilder:printcolumn:name="SCORE",type=string,JSONPath=`.status.score`
+
+// CanaryAnalysis is the schema for standalone Kayenta canary analysis
+type CanaryAnalysis struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CanaryAnalysisSpec   `json:"spec,omitempty"`
+	Status CanaryAnalysisStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CanaryAnalysisList contains a list of CanaryAnalysis
+type CanaryAnalysisList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+	Items           []CanaryAnalysis `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CanaryAnalysis{}, &CanaryAnalysisList{})
+}
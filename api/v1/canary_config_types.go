@@ -19,12 +19,19 @@ const (
 	CanaryConfigCreationComplete CanaryConfigConditionType = "CreationComplete"
 	// CanaryConfigDeletionComplete means deletion has finished
 	CanaryConfigDeletionComplete CanaryConfigConditionType = "DeletionComplete"
+	// CanaryConfigValid means metrics, groups and classifier are consistent with Kayenta
+	CanaryConfigValid CanaryConfigConditionType = "Valid"
+	// CanaryConfigConflict means the ID is owned by another CanaryConfig or by a config created outside the controller
+	CanaryConfigConflict CanaryConfigConditionType = "Conflict"
+	// CanaryConfigPaused means reconciliation is paused and no change is pushed to Spinnaker
+	CanaryConfigPaused CanaryConfigConditionType = "Paused"
 )
 
 // CanaryConfigCondition defines condition struct
 type CanaryConfigCondition struct {
-	Type   CanaryConfigConditionType `json:"type"`
-	Status string                    `json:"status"`
+	Type    CanaryConfigConditionType `json:"type"`
+	Status  string                    `json:"status"`
+	Message string                    `json:"message,omitempty"`
 }
 
 // CanaryConfigStatus defines the observed state of CanaryConfig
@@ -32,6 +39,7 @@ type CanaryConfigStatus struct {
 	SpinnakerResource SpinnakerCanaryConfigResource `json:"spinnakerResource,omitempty"`
 	Conditions        []CanaryConfigCondition       `json:"conditions,omitempty"`
 	Hash              string                        `json:"hash,omitempty"`
+	Applications      []string                      `json:"applications,omitempty"`
 }
 
 // +kubebuilder:object:root=true
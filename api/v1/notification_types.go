@@ -0,0 +1,77 @@
+package v1
+
+import (
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotificationTargetKind is the kind of the resources that a Notification applies to
+type NotificationTargetKind string
+
+const (
+	// NotificationTargetApplication applies the notifications to the application in Spinnaker
+	NotificationTargetApplication NotificationTargetKind = "Application"
+	// NotificationTargetPipeline applies the notifications to the pipeline in Spinnaker
+	NotificationTargetPipeline NotificationTargetKind = "Pipeline"
+)
+
+// NotificationTarget selects the resources that a Notification applies to
+type NotificationTarget struct {
+	// +kubebuilder:validation:Enum=Application;Pipeline
+	Kind NotificationTargetKind `json:"kind"`
+	// Name is the name of the resource, or empty to select the resources by Selector
+	Name string `json:"name,omitempty"`
+	// Selector selects the resources by their labels when Name is empty, and all resources of Kind when it is empty too
+	Selector *metaV1.LabelSelector `json:"selector,omitempty"`
+}
+
+// NotificationMessage is the custom message of an event
+type NotificationMessage struct {
+	Text string `json:"text"`
+}
+
+// NotificationConfig is a notification of Spinnaker
+type NotificationConfig struct {
+	// Type is the notification service, e.g. slack, email or pagerDuty
+	Type string `json:"type"`
+	// Address is where the notification is sent, e.g. a Slack channel, an email address or a PagerDuty service key
+	Address string `json:"address"`
+	// Cc is the addresses copied on email notifications
+	Cc string `json:"cc,omitempty"`
+	// When are the events notified, e.g. pipeline.failed
+	When []string `json:"when"`
+	// Message overrides the message of each event in When
+	Message map[string]NotificationMessage `json:"message,omitempty"`
+}
+
+// NotificationSpec defines the desired state of Notification
+type NotificationSpec struct {
+	Target NotificationTarget `json:"target"`
+	// Notifications are merged into the notifications of the targets when they are saved
+	Notifications []NotificationConfig `json:"notifications"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="TARGET-KIND",type=string,JSONPath=`.spec.target.kind`
+// +kubebuilder:printcolumn:name="TARGET-NAME",type=string,JSONPath=`.spec.target.name`
+
+// Notification is the schema for notifications merged into Spinnaker Applications and Pipelines
+type Notification struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NotificationSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NotificationList contains a list of Notification
+type NotificationList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+	Items           []Notification `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Notification{}, &NotificationList{})
+}
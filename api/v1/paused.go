@@ -0,0 +1,234 @@
+package v1
+
+// SetPaused sets the Paused condition with the message of why reconciliation is paused, and sets it to False once it
+// is resumed. It returns true when the status has changed, so that the status is only written on transitions.
+func (in *Application) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == ApplicationPaused {
+			if condition.Status == status && condition.Message == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Message = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, ApplicationCondition{
+		Type:    ApplicationPaused,
+		Status:  status,
+		Message: message,
+	})
+	return true
+}
+
+// SetPaused sets the Paused condition of CanaryAnalysis, see Application.SetPaused
+func (in *CanaryAnalysis) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == CanaryAnalysisPaused {
+			if condition.Status == status && condition.Reason == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Reason = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, CanaryAnalysisCondition{
+		Type:   CanaryAnalysisPaused,
+		Status: status,
+		Reason: message,
+	})
+	return true
+}
+
+// SetPaused sets the Paused condition of CanaryConfig, see Application.SetPaused
+func (in *CanaryConfig) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == CanaryConfigPaused {
+			if condition.Status == status && condition.Message == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Message = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, CanaryConfigCondition{
+		Type:    CanaryConfigPaused,
+		Status:  status,
+		Message: message,
+	})
+	return true
+}
+
+// SetPaused sets the Paused condition of PipelineExecution, see Application.SetPaused
+func (in *PipelineExecution) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == PipelineExecutionPaused {
+			if condition.Status == status && condition.Reason == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Reason = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, PipelineExecutionCondition{
+		Type:   PipelineExecutionPaused,
+		Status: status,
+		Reason: message,
+	})
+	return true
+}
+
+// SetPaused sets the Paused condition of PipelineTemplate, see Application.SetPaused
+func (in *PipelineTemplate) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == PipelineTemplatePaused {
+			if condition.Status == status && condition.Message == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Message = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, PipelineTemplateCondition{
+		Type:    PipelineTemplatePaused,
+		Status:  status,
+		Message: message,
+	})
+	return true
+}
+
+// SetPaused sets the Paused condition of Pipeline, see Application.SetPaused
+func (in *Pipeline) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == PipelinePaused {
+			if condition.Status == status && condition.Message == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Message = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, PipelineCondition{
+		Type:    PipelinePaused,
+		Status:  status,
+		Message: message,
+	})
+	return true
+}
+
+// SetPaused sets the Paused condition of Project, see Application.SetPaused
+func (in *Project) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == ProjectPaused {
+			if condition.Status == status && condition.Message == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Message = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, ProjectCondition{
+		Type:    ProjectPaused,
+		Status:  status,
+		Message: message,
+	})
+	return true
+}
+
+// SetPaused sets the Paused condition of SpinnakerServiceAccount, see Application.SetPaused
+func (in *SpinnakerServiceAccount) SetPaused(paused bool, message string) bool {
+	status := "False"
+	if paused {
+		status = "True"
+	} else {
+		message = ""
+	}
+	for i, condition := range in.Status.Conditions {
+		if condition.Type == SpinnakerServiceAccountPaused {
+			if condition.Status == status && condition.Message == message {
+				return false
+			}
+			in.Status.Conditions[i].Status = status
+			in.Status.Conditions[i].Message = message
+			return true
+		}
+	}
+	if !paused {
+		return false
+	}
+	in.Status.Conditions = append(in.Status.Conditions, SpinnakerServiceAccountCondition{
+		Type:    SpinnakerServiceAccountPaused,
+		Status:  status,
+		Message: message,
+	})
+	return true
+}
@@ -0,0 +1,90 @@
+package v1
+
+import (
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Artifact defines an artifact passed to the trigger of Spinnaker pipeline
+type Artifact struct {
+	Type            string `json:"type"`
+	Name            string `json:"name,omitempty"`
+	Version         string `json:"version,omitempty"`
+	Location        string `json:"location,omitempty"`
+	Reference       string `json:"reference,omitempty"`
+	ArtifactAccount string `json:"artifactAccount,omitempty"`
+}
+
+// PipelineExecutionSpec defines the desired state of PipelineExecution
+type PipelineExecutionSpec struct {
+	PipelineName string            `json:"pipelineName"`
+	Parameters   map[string]string `json:"parameters,omitempty"`
+	Artifacts    []Artifact        `json:"artifacts,omitempty"`
+}
+
+// SpinnakerPipelineExecutionResource defines the resource of Spinnaker
+type SpinnakerPipelineExecutionResource struct {
+	ApplicationName string `json:"applicationName,omitempty"`
+	PipelineName    string `json:"pipelineName,omitempty"`
+	ID              string `json:"id,omitempty"`
+}
+
+// PipelineExecutionConditionType defines codition type
+type PipelineExecutionConditionType string
+
+const (
+	// PipelineExecutionTriggered means the execution has been triggered
+	PipelineExecutionTriggered PipelineExecutionConditionType = "Triggered"
+	// PipelineExecutionComplete means the execution has finished
+	PipelineExecutionComplete PipelineExecutionConditionType = "ExecutionComplete"
+	// PipelineExecutionStagePrefix prefixes the condition type of each stage
+	PipelineExecutionStagePrefix PipelineExecutionConditionType = "Stage/"
+	// PipelineExecutionPaused means reconciliation is paused and no change is pushed to Spinnaker
+	PipelineExecutionPaused PipelineExecutionConditionType = "Paused"
+)
+
+// PipelineExecutionTriggerTimeout is the reason of ExecutionComplete when the triggered execution is never found
+const PipelineExecutionTriggerTimeout = "TriggerTimeout"
+
+// PipelineExecutionCondition defines condition struct
+type PipelineExecutionCondition struct {
+	Type   PipelineExecutionConditionType `json:"type"`
+	Status string                         `json:"status"`
+	Reason string                         `json:"reason,omitempty"`
+	// LastTransitionTime is when the status of the condition last changed
+	LastTransitionTime *metaV1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// PipelineExecutionStatus defines the observed state of PipelineExecution
+type PipelineExecutionStatus struct {
+	SpinnakerResource SpinnakerPipelineExecutionResource `json:"spinnakerResource,omitempty"`
+	Execution         SpinnakerPipelineExecution         `json:"execution,omitempty"`
+	Conditions        []PipelineExecutionCondition       `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="PIPELINE",type=string,JSONPath=`.spec.pipelineName`
+// +kubebuilder:printcolumn:name="SPINNAKER-EXECUTION-ID",type=string,JSONPath=`.status.spinnakerResource.id`
+// +kubebuilder:printcolumn:name="STATUS",type=string,JSONPath=`.status.execution.status`
+
+// PipelineExecution is the schema for Spinnaker pipeline execution
+type PipelineExecution struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PipelineExecutionSpec   `json:"spec,omitempty"`
+	Status PipelineExecutionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PipelineExecutionList contains a list of PipelineExecution
+type PipelineExecutionList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+	Items           []PipelineExecution `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PipelineExecution{}, &PipelineExecutionList{})
+}
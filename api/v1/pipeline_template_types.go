@@ -18,12 +18,15 @@ const (
 	PipelineTemplatePublishingComplete PipelineTemplateConditionType = "PublishingComplete"
 	// PipelineTemplateDeletionComplete means deletion has finished
 	PipelineTemplateDeletionComplete PipelineTemplateConditionType = "DeletionComplete"
+	// PipelineTemplatePaused means reconciliation is paused and no change is pushed to Spinnaker
+	PipelineTemplatePaused PipelineTemplateConditionType = "Paused"
 )
 
 // PipelineTemplateCondition defines condition struct
 type PipelineTemplateCondition struct {
-	Type   PipelineTemplateConditionType `json:"type"`
-	Status string                        `json:"status"`
+	Type    PipelineTemplateConditionType `json:"type"`
+	Status  string                        `json:"status"`
+	Message string                        `json:"message,omitempty"`
 }
 
 // PipelineTemplateStatus defines the observed state of PipelineTemplate
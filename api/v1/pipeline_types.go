@@ -17,27 +17,55 @@ type PipelineConditionType string
 const (
 	// PipelineCreationComplete means creation has finished
 	PipelineCreationComplete PipelineConditionType = "CreationComplete"
+	// PipelineUpdateComplete means update has finished
+	PipelineUpdateComplete PipelineConditionType = "UpdateComplete"
 	// PipelineDeletionComplete means deletion has finished
 	PipelineDeletionComplete PipelineConditionType = "DeletionComplete"
+	// PipelinePaused means reconciliation is paused and no change is pushed to Spinnaker
+	PipelinePaused PipelineConditionType = "Paused"
+	// PipelineConflict means the new name of a renamed or moved pipeline is taken by another pipeline in Spinnaker
+	PipelineConflict PipelineConditionType = "Conflict"
+	// PipelineExecutePolicyValid means the execute annotations are valid, and the pipeline is not executed while false
+	PipelineExecutePolicyValid PipelineConditionType = "ExecutePolicyValid"
 )
 
 // PipelineCondition defines condition struct
 type PipelineCondition struct {
-	Type   PipelineConditionType `json:"type"`
-	Status string                `json:"status"`
+	Type    PipelineConditionType `json:"type"`
+	Status  string                `json:"status"`
+	Message string                `json:"message,omitempty"`
+}
+
+// SpinnakerPipelineExecution defines the observed state of a Spinnaker pipeline execution
+type SpinnakerPipelineExecution struct {
+	ID          string       `json:"id,omitempty"`
+	Status      string       `json:"status,omitempty"`
+	StartTime   *metaV1.Time `json:"startTime,omitempty"`
+	EndTime     *metaV1.Time `json:"endTime,omitempty"`
+	FailedStage string       `json:"failedStage,omitempty"`
+	TriggerHash string       `json:"triggerHash,omitempty"`
 }
 
 // PipelineStatus defines the observed state of Pipeline
 type PipelineStatus struct {
-	SpinnakerResource SpinnakerPipelineResource `json:"spinnakerResource,omitempty"`
-	Conditions        []PipelineCondition       `json:"conditions,omitempty"`
-	Hash              string                    `json:"hash,omitempty"`
+	SpinnakerResource SpinnakerPipelineResource  `json:"spinnakerResource,omitempty"`
+	Conditions        []PipelineCondition        `json:"conditions,omitempty"`
+	Hash              string                     `json:"hash,omitempty"`
+	LastExecution     SpinnakerPipelineExecution `json:"lastExecution,omitempty"`
+	LastScheduleTime  *metaV1.Time               `json:"lastScheduleTime,omitempty"`
+	// PendingTriggerHash is the spec hash of an execution that is due but has not been triggered yet
+	PendingTriggerHash string `json:"pendingTriggerHash,omitempty"`
+	// ControlHash is the hash of the disable, lock and run-as-user annotations applied to the pipeline
+	ControlHash string `json:"controlHash,omitempty"`
+	// NotificationHash is the hash of the Notifications applied to the pipeline
+	NotificationHash string `json:"notificationHash,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
 // +kubebuilder:printcolumn:name="SPINNAKER-APPLICATION-NAME",type=string,JSONPath=`.status.spinnakerResource.applicationName`
 // +kubebuilder:printcolumn:name="SPINNAKER-PIPELINE-ID",type=string,JSONPath=`.status.spinnakerResource.id`
+// +kubebuilder:printcolumn:name="LAST-EXECUTION-STATUS",type=string,JSONPath=`.status.lastExecution.status`
 
 // Pipeline is the schema for Spinnaker Pipeline
 type Pipeline struct {
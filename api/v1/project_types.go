@@ -0,0 +1,70 @@
+package v1
+
+import (
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// SpinnakerProjectResource defines the resource of Spinnaker
+type SpinnakerProjectResource struct {
+	ProjectName string `json:"projectName,omitempty"`
+	ID          string `json:"id,omitempty"`
+}
+
+// ProjectConditionType defines codition type
+type ProjectConditionType string
+
+const (
+	// ProjectCreationComplete means creation has finished
+	ProjectCreationComplete ProjectConditionType = "CreationComplete"
+	// ProjectUpdateComplete means update has finished
+	ProjectUpdateComplete ProjectConditionType = "UpdateComplete"
+	// ProjectDeletionComplete means deletion has finished
+	ProjectDeletionComplete ProjectConditionType = "DeletionComplete"
+	// ProjectConflict means a project of the name exists in Spinnaker without having been created by the controller
+	ProjectConflict ProjectConditionType = "Conflict"
+	// ProjectPaused means reconciliation is paused and no change is pushed to Spinnaker
+	ProjectPaused ProjectConditionType = "Paused"
+)
+
+// ProjectCondition defines condition struct
+type ProjectCondition struct {
+	Type    ProjectConditionType `json:"type"`
+	Status  string               `json:"status"`
+	Message string               `json:"message,omitempty"`
+}
+
+// ProjectStatus defines the observed state of Project
+type ProjectStatus struct {
+	SpinnakerResource SpinnakerProjectResource `json:"spinnakerResource,omitempty"`
+	Conditions        []ProjectCondition       `json:"conditions,omitempty"`
+	Hash              string                   `json:"hash,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="SPINNAKER-PROJECT-NAME",type=string,JSONPath=`.status.spinnakerResource.projectName`
+// +kubebuilder:printcolumn:name="SPINNAKER-PROJECT-ID",type=string,JSONPath=`.status.spinnakerResource.id`
+
+// Project is the schema for Spinnaker Project
+type Project struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Spec   runtime.RawExtension `json:"spec,omitempty"`
+	Status ProjectStatus        `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ProjectList contains a list of Project
+type ProjectList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+	Items           []Project `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Project{}, &ProjectList{})
+}
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SpinnakerServiceAccountSpec defines the desired state of SpinnakerServiceAccount
+type SpinnakerServiceAccountSpec struct {
+	// Name is the name of the service account in Spinnaker, which defaults to metadata.name
+	Name     string   `json:"name,omitempty"`
+	MemberOf []string `json:"memberOf,omitempty"`
+}
+
+// SpinnakerServiceAccountResource defines the resource of Spinnaker
+type SpinnakerServiceAccountResource struct {
+	Name string `json:"name,omitempty"`
+}
+
+// SpinnakerServiceAccountConditionType defines codition type
+type SpinnakerServiceAccountConditionType string
+
+const (
+	// SpinnakerServiceAccountCreationComplete means creation has finished
+	SpinnakerServiceAccountCreationComplete SpinnakerServiceAccountConditionType = "CreationComplete"
+	// SpinnakerServiceAccountUpdateComplete means update has finished
+	SpinnakerServiceAccountUpdateComplete SpinnakerServiceAccountConditionType = "UpdateComplete"
+	// SpinnakerServiceAccountDeletionComplete means deletion has finished
+	SpinnakerServiceAccountDeletionComplete SpinnakerServiceAccountConditionType = "DeletionComplete"
+	// SpinnakerServiceAccountPaused means reconciliation is paused and no change is pushed to Spinnaker
+	SpinnakerServiceAccountPaused SpinnakerServiceAccountConditionType = "Paused"
+	// SpinnakerServiceAccountConflict means the service account exists in Spinnaker without having been created by the
+	// controller, or is owned by another SpinnakerServiceAccount
+	SpinnakerServiceAccountConflict SpinnakerServiceAccountConditionType = "Conflict"
+)
+
+// SpinnakerServiceAccountCondition defines condition struct
+type SpinnakerServiceAccountCondition struct {
+	Type    SpinnakerServiceAccountConditionType `json:"type"`
+	Status  string                               `json:"status"`
+	Message string                               `json:"message,omitempty"`
+}
+
+// SpinnakerServiceAccountStatus defines the observed state of SpinnakerServiceAccount
+type SpinnakerServiceAccountStatus struct {
+	SpinnakerResource SpinnakerServiceAccountResource    `json:"spinnakerResource,omitempty"`
+	Conditions        []SpinnakerServiceAccountCondition `json:"conditions,omitempty"`
+	Hash              string                             `json:"hash,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="SPINNAKER-SERVICE-ACCOUNT-NAME",type=string,JSONPath=`.status.spinnakerResource.name`
+
+// SpinnakerServiceAccount is the schema for Spinnaker Service Account
+type SpinnakerServiceAccount struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SpinnakerServiceAccountSpec   `json:"spec,omitempty"`
+	Status SpinnakerServiceAccountStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SpinnakerServiceAccountList contains a list of SpinnakerServiceAccount
+type SpinnakerServiceAccountList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+	Items           []SpinnakerServiceAccount `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SpinnakerServiceAccount{}, &SpinnakerServiceAccountList{})
+}
@@ -6,6 +6,7 @@
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -83,6 +84,36 @@ func (in *ApplicationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationPermissions) DeepCopyInto(out *ApplicationPermissions) {
+	*out = *in
+	if in.Read != nil {
+		in, out := &in.Read, &out.Read
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Write != nil {
+		in, out := &in.Write, &out.Write
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Execute != nil {
+		in, out := &in.Execute, &out.Execute
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationPermissions.
+func (in *ApplicationPermissions) DeepCopy() *ApplicationPermissions {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationPermissions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationStatus) DeepCopyInto(out *ApplicationStatus) {
 	*out = *in
@@ -92,6 +123,16 @@ func (in *ApplicationStatus) DeepCopyInto(out *ApplicationStatus) {
 		*out = make([]ApplicationCondition, len(*in))
 		copy(*out, *in)
 	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = new(ApplicationPermissions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManagedNotifications != nil {
+		in, out := &in.ManagedNotifications, &out.ManagedNotifications
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationStatus.
@@ -105,7 +146,22 @@ func (in *ApplicationStatus) DeepCopy() *ApplicationStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CanaryConfig) DeepCopyInto(out *CanaryConfig) {
+func (in *Artifact) DeepCopyInto(out *Artifact) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Artifact.
+func (in *Artifact) DeepCopy() *Artifact {
+	if in == nil {
+		return nil
+	}
+	out := new(Artifact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryAnalysis) DeepCopyInto(out *CanaryAnalysis) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -113,18 +169,18 @@ func (in *CanaryConfig) DeepCopyInto(out *CanaryConfig) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfig.
-func (in *CanaryConfig) DeepCopy() *CanaryConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysis.
+func (in *CanaryAnalysis) DeepCopy() *CanaryAnalysis {
 	if in == nil {
 		return nil
 	}
-	out := new(CanaryConfig)
+	out := new(CanaryAnalysis)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *CanaryConfig) DeepCopyObject() runtime.Object {
+func (in *CanaryAnalysis) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -132,46 +188,46 @@ func (in *CanaryConfig) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CanaryConfigCondition) DeepCopyInto(out *CanaryConfigCondition) {
+func (in *CanaryAnalysisCondition) DeepCopyInto(out *CanaryAnalysisCondition) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfigCondition.
-func (in *CanaryConfigCondition) DeepCopy() *CanaryConfigCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysisCondition.
+func (in *CanaryAnalysisCondition) DeepCopy() *CanaryAnalysisCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(CanaryConfigCondition)
+	out := new(CanaryAnalysisCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CanaryConfigList) DeepCopyInto(out *CanaryConfigList) {
+func (in *CanaryAnalysisList) DeepCopyInto(out *CanaryAnalysisList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]CanaryConfig, len(*in))
+		*out = make([]CanaryAnalysis, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfigList.
-func (in *CanaryConfigList) DeepCopy() *CanaryConfigList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysisList.
+func (in *CanaryAnalysisList) DeepCopy() *CanaryAnalysisList {
 	if in == nil {
 		return nil
 	}
-	out := new(CanaryConfigList)
+	out := new(CanaryAnalysisList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *CanaryConfigList) DeepCopyObject() runtime.Object {
+func (in *CanaryAnalysisList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -179,28 +235,105 @@ func (in *CanaryConfigList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *CanaryConfigStatus) DeepCopyInto(out *CanaryConfigStatus) {
+func (in *CanaryAnalysisMetricResult) DeepCopyInto(out *CanaryAnalysisMetricResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysisMetricResult.
+func (in *CanaryAnalysisMetricResult) DeepCopy() *CanaryAnalysisMetricResult {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryAnalysisMetricResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryAnalysisScope) DeepCopyInto(out *CanaryAnalysisScope) {
+	*out = *in
+	in.Control.DeepCopyInto(&out.Control)
+	in.Experiment.DeepCopyInto(&out.Experiment)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysisScope.
+func (in *CanaryAnalysisScope) DeepCopy() *CanaryAnalysisScope {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryAnalysisScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryAnalysisSpec) DeepCopyInto(out *CanaryAnalysisSpec) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]CanaryAnalysisScope, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+	out.Thresholds = in.Thresholds
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysisSpec.
+func (in *CanaryAnalysisSpec) DeepCopy() *CanaryAnalysisSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryAnalysisSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryAnalysisStatus) DeepCopyInto(out *CanaryAnalysisStatus) {
 	*out = *in
 	out.SpinnakerResource = in.SpinnakerResource
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]CanaryAnalysisMetricResult, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]CanaryConfigCondition, len(*in))
+		*out = make([]CanaryAnalysisCondition, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfigStatus.
-func (in *CanaryConfigStatus) DeepCopy() *CanaryConfigStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysisStatus.
+func (in *CanaryAnalysisStatus) DeepCopy() *CanaryAnalysisStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(CanaryConfigStatus)
+	out := new(CanaryAnalysisStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Pipeline) DeepCopyInto(out *Pipeline) {
+func (in *CanaryAnalysisThresholds) DeepCopyInto(out *CanaryAnalysisThresholds) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryAnalysisThresholds.
+func (in *CanaryAnalysisThresholds) DeepCopy() *CanaryAnalysisThresholds {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryAnalysisThresholds)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CanaryConfig) DeepCopyInto(out *CanaryConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -208,18 +341,18 @@ func (in *Pipeline) DeepCopyInto(out *Pipeline) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Pipeline.
-func (in *Pipeline) DeepCopy() *Pipeline {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfig.
+func (in *CanaryConfig) DeepCopy() *CanaryConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(Pipeline)
+	out := new(CanaryConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Pipeline) DeepCopyObject() runtime.Object {
+func (in *CanaryConfig) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -227,46 +360,46 @@ func (in *Pipeline) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PipelineCondition) DeepCopyInto(out *PipelineCondition) {
+func (in *CanaryConfigCondition) DeepCopyInto(out *CanaryConfigCondition) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineCondition.
-func (in *PipelineCondition) DeepCopy() *PipelineCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfigCondition.
+func (in *CanaryConfigCondition) DeepCopy() *CanaryConfigCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(PipelineCondition)
+	out := new(CanaryConfigCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PipelineList) DeepCopyInto(out *PipelineList) {
+func (in *CanaryConfigList) DeepCopyInto(out *CanaryConfigList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Pipeline, len(*in))
+		*out = make([]CanaryConfig, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineList.
-func (in *PipelineList) DeepCopy() *PipelineList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfigList.
+func (in *CanaryConfigList) DeepCopy() *CanaryConfigList {
 	if in == nil {
 		return nil
 	}
-	out := new(PipelineList)
+	out := new(CanaryConfigList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PipelineList) DeepCopyObject() runtime.Object {
+func (in *CanaryConfigList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -274,47 +407,73 @@ func (in *PipelineList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PipelineStatus) DeepCopyInto(out *PipelineStatus) {
+func (in *CanaryConfigStatus) DeepCopyInto(out *CanaryConfigStatus) {
 	*out = *in
 	out.SpinnakerResource = in.SpinnakerResource
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]PipelineCondition, len(*in))
+		*out = make([]CanaryConfigCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.Applications != nil {
+		in, out := &in.Applications, &out.Applications
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineStatus.
-func (in *PipelineStatus) DeepCopy() *PipelineStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryConfigStatus.
+func (in *CanaryConfigStatus) DeepCopy() *CanaryConfigStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PipelineStatus)
+	out := new(CanaryConfigStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PipelineTemplate) DeepCopyInto(out *PipelineTemplate) {
+func (in *CanaryScope) DeepCopyInto(out *CanaryScope) {
+	*out = *in
+	if in.ExtendedScopeParams != nil {
+		in, out := &in.ExtendedScopeParams, &out.ExtendedScopeParams
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CanaryScope.
+func (in *CanaryScope) DeepCopy() *CanaryScope {
+	if in == nil {
+		return nil
+	}
+	out := new(CanaryScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Notification) DeepCopyInto(out *Notification) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplate.
-func (in *PipelineTemplate) DeepCopy() *PipelineTemplate {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notification.
+func (in *Notification) DeepCopy() *Notification {
 	if in == nil {
 		return nil
 	}
-	out := new(PipelineTemplate)
+	out := new(Notification)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PipelineTemplate) DeepCopyObject() runtime.Object {
+func (in *Notification) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -322,46 +481,58 @@ func (in *PipelineTemplate) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PipelineTemplateCondition) DeepCopyInto(out *PipelineTemplateCondition) {
+func (in *NotificationConfig) DeepCopyInto(out *NotificationConfig) {
 	*out = *in
+	if in.When != nil {
+		in, out := &in.When, &out.When
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Message != nil {
+		in, out := &in.Message, &out.Message
+		*out = make(map[string]NotificationMessage, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplateCondition.
-func (in *PipelineTemplateCondition) DeepCopy() *PipelineTemplateCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationConfig.
+func (in *NotificationConfig) DeepCopy() *NotificationConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(PipelineTemplateCondition)
+	out := new(NotificationConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PipelineTemplateList) DeepCopyInto(out *PipelineTemplateList) {
+func (in *NotificationList) DeepCopyInto(out *NotificationList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]PipelineTemplate, len(*in))
+		*out = make([]Notification, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplateList.
-func (in *PipelineTemplateList) DeepCopy() *PipelineTemplateList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationList.
+func (in *NotificationList) DeepCopy() *NotificationList {
 	if in == nil {
 		return nil
 	}
-	out := new(PipelineTemplateList)
+	out := new(NotificationList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PipelineTemplateList) DeepCopyObject() runtime.Object {
+func (in *NotificationList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -369,82 +540,736 @@ func (in *PipelineTemplateList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PipelineTemplateStatus) DeepCopyInto(out *PipelineTemplateStatus) {
+func (in *NotificationMessage) DeepCopyInto(out *NotificationMessage) {
 	*out = *in
-	out.SpinnakerResource = in.SpinnakerResource
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]PipelineTemplateCondition, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationMessage.
+func (in *NotificationMessage) DeepCopy() *NotificationMessage {
+	if in == nil {
+		return nil
 	}
+	out := new(NotificationMessage)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplateStatus.
-func (in *PipelineTemplateStatus) DeepCopy() *PipelineTemplateStatus {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationSpec) DeepCopyInto(out *NotificationSpec) {
+	*out = *in
+	in.Target.DeepCopyInto(&out.Target)
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]NotificationConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationSpec.
+func (in *NotificationSpec) DeepCopy() *NotificationSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PipelineTemplateStatus)
+	out := new(NotificationSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpinnakerApplicationResource) DeepCopyInto(out *SpinnakerApplicationResource) {
+func (in *NotificationTarget) DeepCopyInto(out *NotificationTarget) {
 	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerApplicationResource.
-func (in *SpinnakerApplicationResource) DeepCopy() *SpinnakerApplicationResource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationTarget.
+func (in *NotificationTarget) DeepCopy() *NotificationTarget {
 	if in == nil {
 		return nil
 	}
-	out := new(SpinnakerApplicationResource)
+	out := new(NotificationTarget)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpinnakerCanaryConfigResource) DeepCopyInto(out *SpinnakerCanaryConfigResource) {
+func (in *Pipeline) DeepCopyInto(out *Pipeline) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerCanaryConfigResource.
-func (in *SpinnakerCanaryConfigResource) DeepCopy() *SpinnakerCanaryConfigResource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Pipeline.
+func (in *Pipeline) DeepCopy() *Pipeline {
 	if in == nil {
 		return nil
 	}
-	out := new(SpinnakerCanaryConfigResource)
+	out := new(Pipeline)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Pipeline) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpinnakerPipelineResource) DeepCopyInto(out *SpinnakerPipelineResource) {
+func (in *PipelineCondition) DeepCopyInto(out *PipelineCondition) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerPipelineResource.
-func (in *SpinnakerPipelineResource) DeepCopy() *SpinnakerPipelineResource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineCondition.
+func (in *PipelineCondition) DeepCopy() *PipelineCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(SpinnakerPipelineResource)
+	out := new(PipelineCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SpinnakerPipelineTemplateResource) DeepCopyInto(out *SpinnakerPipelineTemplateResource) {
+func (in *PipelineExecution) DeepCopyInto(out *PipelineExecution) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerPipelineTemplateResource.
-func (in *SpinnakerPipelineTemplateResource) DeepCopy() *SpinnakerPipelineTemplateResource {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineExecution.
+func (in *PipelineExecution) DeepCopy() *PipelineExecution {
 	if in == nil {
 		return nil
 	}
-	out := new(SpinnakerPipelineTemplateResource)
+	out := new(PipelineExecution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PipelineExecution) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineExecutionCondition) DeepCopyInto(out *PipelineExecutionCondition) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineExecutionCondition.
+func (in *PipelineExecutionCondition) DeepCopy() *PipelineExecutionCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineExecutionCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineExecutionList) DeepCopyInto(out *PipelineExecutionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PipelineExecution, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineExecutionList.
+func (in *PipelineExecutionList) DeepCopy() *PipelineExecutionList {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineExecutionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PipelineExecutionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineExecutionSpec) DeepCopyInto(out *PipelineExecutionSpec) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]Artifact, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineExecutionSpec.
+func (in *PipelineExecutionSpec) DeepCopy() *PipelineExecutionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineExecutionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineExecutionStatus) DeepCopyInto(out *PipelineExecutionStatus) {
+	*out = *in
+	out.SpinnakerResource = in.SpinnakerResource
+	in.Execution.DeepCopyInto(&out.Execution)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PipelineExecutionCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineExecutionStatus.
+func (in *PipelineExecutionStatus) DeepCopy() *PipelineExecutionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineExecutionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineList) DeepCopyInto(out *PipelineList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Pipeline, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineList.
+func (in *PipelineList) DeepCopy() *PipelineList {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PipelineList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineStatus) DeepCopyInto(out *PipelineStatus) {
+	*out = *in
+	out.SpinnakerResource = in.SpinnakerResource
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PipelineCondition, len(*in))
+		copy(*out, *in)
+	}
+	in.LastExecution.DeepCopyInto(&out.LastExecution)
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineStatus.
+func (in *PipelineStatus) DeepCopy() *PipelineStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineTemplate) DeepCopyInto(out *PipelineTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplate.
+func (in *PipelineTemplate) DeepCopy() *PipelineTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PipelineTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineTemplateCondition) DeepCopyInto(out *PipelineTemplateCondition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplateCondition.
+func (in *PipelineTemplateCondition) DeepCopy() *PipelineTemplateCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineTemplateCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineTemplateList) DeepCopyInto(out *PipelineTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PipelineTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplateList.
+func (in *PipelineTemplateList) DeepCopy() *PipelineTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PipelineTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineTemplateStatus) DeepCopyInto(out *PipelineTemplateStatus) {
+	*out = *in
+	out.SpinnakerResource = in.SpinnakerResource
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PipelineTemplateCondition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTemplateStatus.
+func (in *PipelineTemplateStatus) DeepCopy() *PipelineTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Project) DeepCopyInto(out *Project) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Project.
+func (in *Project) DeepCopy() *Project {
+	if in == nil {
+		return nil
+	}
+	out := new(Project)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Project) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectCondition) DeepCopyInto(out *ProjectCondition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectCondition.
+func (in *ProjectCondition) DeepCopy() *ProjectCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectList) DeepCopyInto(out *ProjectList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Project, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectList.
+func (in *ProjectList) DeepCopy() *ProjectList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProjectList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectStatus) DeepCopyInto(out *ProjectStatus) {
+	*out = *in
+	out.SpinnakerResource = in.SpinnakerResource
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ProjectCondition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectStatus.
+func (in *ProjectStatus) DeepCopy() *ProjectStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerApplicationResource) DeepCopyInto(out *SpinnakerApplicationResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerApplicationResource.
+func (in *SpinnakerApplicationResource) DeepCopy() *SpinnakerApplicationResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerApplicationResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerCanaryAnalysisResource) DeepCopyInto(out *SpinnakerCanaryAnalysisResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerCanaryAnalysisResource.
+func (in *SpinnakerCanaryAnalysisResource) DeepCopy() *SpinnakerCanaryAnalysisResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerCanaryAnalysisResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerCanaryConfigResource) DeepCopyInto(out *SpinnakerCanaryConfigResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerCanaryConfigResource.
+func (in *SpinnakerCanaryConfigResource) DeepCopy() *SpinnakerCanaryConfigResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerCanaryConfigResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerPipelineExecution) DeepCopyInto(out *SpinnakerPipelineExecution) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerPipelineExecution.
+func (in *SpinnakerPipelineExecution) DeepCopy() *SpinnakerPipelineExecution {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerPipelineExecution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerPipelineExecutionResource) DeepCopyInto(out *SpinnakerPipelineExecutionResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerPipelineExecutionResource.
+func (in *SpinnakerPipelineExecutionResource) DeepCopy() *SpinnakerPipelineExecutionResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerPipelineExecutionResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerPipelineResource) DeepCopyInto(out *SpinnakerPipelineResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerPipelineResource.
+func (in *SpinnakerPipelineResource) DeepCopy() *SpinnakerPipelineResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerPipelineResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerPipelineTemplateResource) DeepCopyInto(out *SpinnakerPipelineTemplateResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerPipelineTemplateResource.
+func (in *SpinnakerPipelineTemplateResource) DeepCopy() *SpinnakerPipelineTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerPipelineTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerProjectResource) DeepCopyInto(out *SpinnakerProjectResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerProjectResource.
+func (in *SpinnakerProjectResource) DeepCopy() *SpinnakerProjectResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerProjectResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerServiceAccount) DeepCopyInto(out *SpinnakerServiceAccount) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerServiceAccount.
+func (in *SpinnakerServiceAccount) DeepCopy() *SpinnakerServiceAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerServiceAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SpinnakerServiceAccount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerServiceAccountCondition) DeepCopyInto(out *SpinnakerServiceAccountCondition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerServiceAccountCondition.
+func (in *SpinnakerServiceAccountCondition) DeepCopy() *SpinnakerServiceAccountCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerServiceAccountCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerServiceAccountList) DeepCopyInto(out *SpinnakerServiceAccountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SpinnakerServiceAccount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerServiceAccountList.
+func (in *SpinnakerServiceAccountList) DeepCopy() *SpinnakerServiceAccountList {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerServiceAccountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SpinnakerServiceAccountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerServiceAccountResource) DeepCopyInto(out *SpinnakerServiceAccountResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerServiceAccountResource.
+func (in *SpinnakerServiceAccountResource) DeepCopy() *SpinnakerServiceAccountResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerServiceAccountResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerServiceAccountSpec) DeepCopyInto(out *SpinnakerServiceAccountSpec) {
+	*out = *in
+	if in.MemberOf != nil {
+		in, out := &in.MemberOf, &out.MemberOf
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerServiceAccountSpec.
+func (in *SpinnakerServiceAccountSpec) DeepCopy() *SpinnakerServiceAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerServiceAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpinnakerServiceAccountStatus) DeepCopyInto(out *SpinnakerServiceAccountStatus) {
+	*out = *in
+	out.SpinnakerResource = in.SpinnakerResource
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SpinnakerServiceAccountCondition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpinnakerServiceAccountStatus.
+func (in *SpinnakerServiceAccountStatus) DeepCopy() *SpinnakerServiceAccountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SpinnakerServiceAccountStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -6,17 +6,24 @@ import (
 	"encoding/json"
 	"fmt"
 	v1 "spinnaker-dcd-controller/api/v1"
-	"time"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
+	"strings"
 
 	"github.com/spinnaker/roer/spinnaker"
 
 	"github.com/go-logr/logr"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
@@ -27,15 +34,25 @@ const (
 
 type ApplicationReconciler struct {
 	client.Client
-	Log             logr.Logger
-	Scheme          *runtime.Scheme
-	Recorder        record.EventRecorder
-	SpinnakerClient spinnaker.Client
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Gateway                 gateway.Client
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Revisions               *Revisions
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
 }
 
-func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
 	application := &v1.Application{}
-	ctx := context.Background()
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "Application", req)
+	defer func() { endReconcileSpan(span, application, err) }()
 	logger := r.Log.WithValues("application", req.NamespacedName)
 	if err := r.Get(ctx, req.NamespacedName, application); err != nil {
 		if errors.IsNotFound(err) {
@@ -43,25 +60,48 @@ func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		}
 		return ctrl.Result{}, err
 	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, application) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, application); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, application, containsString(application.ObjectMeta.Finalizers, myFinalizerName))
+	if rolledBack, err := r.rollback(ctx, application); rolledBack {
+		return ctrl.Result{}, err
+	}
 
 	if application.ObjectMeta.DeletionTimestamp.IsZero() {
 		hash := fmt.Sprintf("%x", sha256.Sum256(application.Spec.Raw))
 		oldHash := application.Status.Hash
 		if oldHash != hash {
 			var taskType string
+			var operation audit.Operation
 			if oldHash == "" {
 				taskType = ApplicationCreateTaskType
+				operation = audit.OperationCreate
 			} else {
 				taskType = ApplicationUpdateTaskType
+				operation = audit.OperationUpdate
+			}
+
+			permissions, err := parsePermissions(application)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if permissions != nil {
+				permissions = effectivePermissions(permissions)
+				r.validatePermissions(ctx, application, permissions, logger)
 			}
 
 			task := r.buildTask(req.Name, application, taskType)
-			response, err := r.submitTask(req.Name, task)
+			response, err := r.submitTask(ctx, application, req.Name, task, operation)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
 			application.Status.SpinnakerResource.ApplicationName = req.Name
 			application.Status.Hash = hash
+			application.Status.Permissions = permissions
 			application.ObjectMeta.Finalizers = append(application.ObjectMeta.Finalizers, myFinalizerName)
 			if response.Status == "TERMINAL" {
 				application.Status.Conditions = append(application.Status.Conditions, v1.ApplicationCondition{
@@ -81,28 +121,49 @@ func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 				return ctrl.Result{}, err
 			}
 		}
-	} else {
-		if containsString(application.ObjectMeta.Finalizers, myFinalizerName) {
-			task := r.buildTask(req.Name, application, ApplicationDeleteTaskType)
-			response, err := r.submitTask(req.Name, task)
+
+		notifications, err := notificationsFor(ctx, r.Client, v1.NotificationTargetApplication, application)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		notificationHash := notificationHash(notifications)
+		if application.Status.Hash != "" && notificationHash != application.Status.NotificationHash {
+			keys, err := r.saveNotifications(ctx, application, req.Name, notifications)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
+			application.Status.NotificationHash = notificationHash
+			application.Status.ManagedNotifications = keys
+			r.Recorder.Eventf(application, coreV1.EventTypeNormal, "SuccessfulUpdatedNotifications", "Updated notifications of application: %q", req.Name)
+			logger.V(1).Info("update notifications", "application", application)
+			if err := r.Update(ctx, application); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	} else {
+		if containsString(application.ObjectMeta.Finalizers, myFinalizerName) {
+			if !r.Settings.retain(r.Recorder, application) {
+				task := r.buildTask(req.Name, application, ApplicationDeleteTaskType)
+				response, err := r.submitTask(ctx, application, req.Name, task, audit.OperationDelete)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if response.Status == "TERMINAL" {
+					application.Status.Conditions = append(application.Status.Conditions, v1.ApplicationCondition{
+						Type:   v1.ApplicationDeletionComplete,
+						Status: "False",
+					})
+				} else {
+					application.Status.Conditions = append(application.Status.Conditions, v1.ApplicationCondition{
+						Type:   v1.ApplicationDeletionComplete,
+						Status: "True",
+					})
+					r.Recorder.Eventf(application, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted application: %q", req.Name)
+					logger.V(1).Info("delete", "application", application)
+				}
+			}
 
 			application.ObjectMeta.Finalizers = removeString(application.ObjectMeta.Finalizers, myFinalizerName)
-			if response.Status == "TERMINAL" {
-				application.Status.Conditions = append(application.Status.Conditions, v1.ApplicationCondition{
-					Type:   v1.ApplicationDeletionComplete,
-					Status: "False",
-				})
-			} else {
-				application.Status.Conditions = append(application.Status.Conditions, v1.ApplicationCondition{
-					Type:   v1.ApplicationDeletionComplete,
-					Status: "True",
-				})
-				r.Recorder.Eventf(application, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted application: %q", req.Name)
-				logger.V(1).Info("delete", "application", application)
-			}
 			if err := r.Update(ctx, application); err != nil {
 				return ctrl.Result{}, err
 			}
@@ -112,37 +173,128 @@ func (r *ApplicationReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 	return ctrl.Result{}, nil
 }
 
-func (r *ApplicationReconciler) submitTask(applicationName string, task spinnaker.Task) (*spinnaker.ExecutionResponse, error) {
-	ref, err := r.SpinnakerClient.ApplicationSubmitTask(applicationName, task)
+func (r *ApplicationReconciler) validatePermissions(ctx context.Context, application *v1.Application, permissions *v1.ApplicationPermissions, logger logr.Logger) {
+	user, knownRoles, err := getKnownRoles(r.Gateway.Gate(ctx))
 	if err != nil {
+		logger.Info("skip validating permissions", "error", err.Error())
+		return
+	}
+
+	// Gate does not list the roles of Fiat, so that a role that the known roles lack may still be a valid role of a
+	// team that neither the controller nor a service account is member of, and cannot be reported as invalid.
+	if unknown := unknownRoles(permissions, knownRoles); len(unknown) > 0 {
+		message := fmt.Sprintf("Roles not verifiable through Gate: %s", strings.Join(unknown, ", "))
+		r.setCondition(application, v1.ApplicationPermissionsValid, "Unknown", message)
+		r.Recorder.Eventf(application, coreV1.EventTypeNormal, "UnverifiedRoles", message)
+	} else {
+		r.setCondition(application, v1.ApplicationPermissionsValid, "True", "")
+	}
+
+	if isLockedOut(permissions, user) {
+		message := fmt.Sprintf("WRITE permission does not include any role of %s", user.Username)
+		r.setCondition(application, v1.ApplicationControllerLockedOut, "True", message)
+		r.Recorder.Eventf(application, coreV1.EventTypeWarning, "ControllerLockedOut", message)
+	} else {
+		r.setCondition(application, v1.ApplicationControllerLockedOut, "False", "")
+	}
+}
+
+func (r *ApplicationReconciler) setCondition(application *v1.Application, conditionType v1.ApplicationConditionType, status string, message string) {
+	for i, condition := range application.Status.Conditions {
+		if condition.Type == conditionType {
+			application.Status.Conditions[i].Status = status
+			application.Status.Conditions[i].Message = message
+			return
+		}
+	}
+	application.Status.Conditions = append(application.Status.Conditions, v1.ApplicationCondition{
+		Type:    conditionType,
+		Status:  status,
+		Message: message,
+	})
+}
+
+func (r *ApplicationReconciler) submitTask(ctx context.Context, application *v1.Application, applicationName string, task spinnaker.Task, operation audit.Operation) (*spinnaker.ExecutionResponse, error) {
+	change := audit.Change{
+		Operation: operation,
+		Object:    "application",
+		ID:        applicationName,
+		Before: auditBefore(r.Audit, func() (interface{}, error) {
+			return getSpinnakerApplication(r.Gateway.Gate(ctx), applicationName)
+		}),
+	}
+	if operation != audit.OperationDelete {
+		change.After = task.Job[0].(spinnaker.ApplicationJob).Application
+	}
+
+	ref, err := r.Gateway.Roer(ctx).ApplicationSubmitTask(applicationName, task)
+	if err != nil {
+		r.Audit.Log(application, change, err)
 		return nil, err
 	}
-	response, err := r.SpinnakerClient.PollTaskStatus(ref.Ref, 30*time.Second)
+	response, err := pollTask(ctx, r.Gateway, ref.Ref, r.Settings.get().TaskPollTimeout)
 	if err != nil {
+		r.Audit.Log(application, change, err)
 		return nil, err
 	}
+	r.Audit.Log(application, change, taskError(ref.Ref, response.Status))
+	if operation != audit.OperationDelete && response.Status != executionStatusTerminal {
+		if err := r.Revisions.record(ctx, application, change.After, response.ID); err != nil {
+			r.Log.Error(err, "unable to record revision", "application", application.Name)
+		}
+	}
 
 	return response, nil
 }
 
+func (r *ApplicationReconciler) rollback(ctx context.Context, application *v1.Application) (bool, error) {
+	return r.Revisions.rollback(ctx, r.Client, r.Recorder, application, func(ctx context.Context, payload json.RawMessage) error {
+		var attributes map[string]interface{}
+		if err := json.Unmarshal(payload, &attributes); err != nil {
+			return err
+		}
+		applicationName := application.Status.SpinnakerResource.ApplicationName
+		response, err := r.submitTask(ctx, application, applicationName, newApplicationTask(applicationName, attributes, ApplicationUpdateTaskType), audit.OperationUpdate)
+		if err != nil {
+			return err
+		}
+		return taskError(response.ID, response.Status)
+	})
+}
+
 func (r *ApplicationReconciler) buildTask(applicationName string, application *v1.Application, taskType string) spinnaker.Task {
+	var m map[string]interface{}
+	_ = json.Unmarshal(application.Spec.Raw, &m)
+	m["name"] = applicationName
+	if permissions, err := parsePermissions(application); err == nil && permissions != nil {
+		m["permissions"] = permissions
+	}
+	return newApplicationTask(applicationName, m, taskType)
+}
+
+// newApplicationTask returns the task that applies attributes, which are either built from the spec or the payload of a
+// revision.
+func newApplicationTask(applicationName string, attributes map[string]interface{}, taskType string) spinnaker.Task {
 	return spinnaker.Task{
 		Application: applicationName,
 		Description: fmt.Sprintf("Execute %s task: %s", taskType, applicationName),
 		Job: []interface{}{
 			spinnaker.ApplicationJob{
-				Application: func() map[string]interface{} {
-					var m map[string]interface{}
-					_ = json.Unmarshal(application.Spec.Raw, &m)
-					m["name"] = applicationName
-					return m
-				}(),
-				Type: taskType,
+				Application: attributes,
+				Type:        taskType,
 			},
 		},
 	}
 }
 
 func (r *ApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&v1.Application{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Application{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.Application{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.ApplicationList{}), &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &v1.Notification{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: mapNotification(r.Client, r.Log, v1.NotificationTargetApplication, &v1.ApplicationList{}),
+		}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(newRequeueOnCancel(r, r.Log))
 }
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"testing"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestApplicationLifecycle(t *testing.T) {
+	requireEnvironment(t)
+
+	key := client.ObjectKey{Name: "envtest-application"}
+	application := &v1.Application{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name},
+		Spec:       rawSpec("email: before@example.com"),
+	}
+	create(t, application)
+
+	eventually(t, func() error {
+		saved, ok := testGate.Application(key.Name)
+		if !ok {
+			return xerrors.New("application is not created")
+		}
+		if saved["email"] != "before@example.com" {
+			return xerrors.Errorf("unexpected email: %v", saved["email"])
+		}
+		return nil
+	})
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, application); err != nil {
+			return err
+		}
+		if application.Status.SpinnakerResource.ApplicationName != key.Name {
+			return xerrors.Errorf("unexpected status: %+v", application.Status)
+		}
+		if !containsString(application.Finalizers, myFinalizerName) {
+			return xerrors.New("finalizer is not added")
+		}
+		return nil
+	})
+
+	update(t, key, application, func() {
+		application.Spec = rawSpec("email: after@example.com")
+	})
+	eventually(t, func() error {
+		saved, _ := testGate.Application(key.Name)
+		if saved["email"] != "after@example.com" {
+			return xerrors.Errorf("unexpected email: %v", saved["email"])
+		}
+		return nil
+	})
+
+	remove(t, application)
+	waitForDeletion(t, key, &v1.Application{})
+	if _, ok := testGate.Application(key.Name); ok {
+		t.Fatal("application is not deleted from Spinnaker")
+	}
+}
+
+func TestApplicationRetriesFailedTask(t *testing.T) {
+	requireEnvironment(t)
+	defer testGate.ClearFailures()
+
+	key := client.ObjectKey{Name: "envtest-application-retry"}
+	path := "/applications/" + key.Name + "/tasks"
+	// Submitting a task is not idempotent, so that the transport never retries it and the reconciler has to.
+	testGate.Fail(http.MethodPost, path, http.StatusInternalServerError, 1)
+
+	application := &v1.Application{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name},
+		Spec:       rawSpec("email: retry@example.com"),
+	}
+	create(t, application)
+	eventually(t, func() error {
+		if _, ok := testGate.Application(key.Name); !ok {
+			return xerrors.New("application is not created")
+		}
+		return nil
+	})
+	if count := testGate.CountRequests(http.MethodPost, path); count != 2 {
+		t.Fatalf("expected one failed and one successful submission, got %d", count)
+	}
+
+	remove(t, application)
+	waitForDeletion(t, key, &v1.Application{})
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+
+	"golang.org/x/xerrors"
+)
+
+// saveNotifications merges notifications into the application notifications of Spinnaker, which are grouped by type,
+// in place of the ones applied last time, and returns the keys of the ones applied. Notifications set by hand are left
+// alone unless a Notification has the same type and address.
+func (r *ApplicationReconciler) saveNotifications(ctx context.Context, application *v1.Application, applicationName string, notifications []v1.Notification) ([]string, error) {
+	existing, err := r.getNotifications(ctx, applicationName)
+	if err != nil {
+		return nil, err
+	}
+	keys, entries := notificationEntries(notifications, "application")
+
+	config := map[string]interface{}{}
+	for key, value := range existing {
+		config[key] = value
+	}
+	for _, field := range serverManagedFields {
+		delete(config, field)
+	}
+	keysByType := map[string][]string{}
+	for _, key := range keys {
+		notificationType := entries[key]["type"].(string)
+		keysByType[notificationType] = append(keysByType[notificationType], key)
+	}
+	for notificationType, value := range existing {
+		if _, ok := value.([]interface{}); ok {
+			if _, ok := keysByType[notificationType]; !ok {
+				keysByType[notificationType] = nil
+			}
+		}
+	}
+	for notificationType, typeKeys := range keysByType {
+		group, _ := config[notificationType].([]interface{})
+		config[notificationType] = mergeNotifications(group, application.Status.ManagedNotifications, typeKeys, entries)
+	}
+	config["application"] = applicationName
+
+	change := audit.Change{
+		Operation: audit.OperationUpdate,
+		Object:    "notification",
+		ID:        applicationName,
+		Before:    auditBefore(r.Audit, func() (interface{}, error) { return existing, nil }),
+		After:     config,
+	}
+	body, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/notifications/application/"+url.PathEscape(applicationName), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.Gateway.Do(req)
+	if err == nil {
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = xerrors.Errorf("encountered an error saving notifications of application %s, status code: %d", applicationName, resp.StatusCode)
+		}
+	}
+	r.Audit.Log(application, change, err)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *ApplicationReconciler) getNotifications(ctx context.Context, applicationName string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/notifications/application/"+url.PathEscape(applicationName), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Gateway.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]interface{}{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("encountered an error getting notifications of application %s, status code: %d", applicationName, resp.StatusCode)
+	}
+	var config map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	return config, nil
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"encoding/json"
+	"sort"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spinnaker/spin/cmd/gateclient"
+	gate "github.com/spinnaker/spin/gateapi"
+	"golang.org/x/xerrors"
+)
+
+func parsePermissions(application *v1.Application) (*v1.ApplicationPermissions, error) {
+	var spec struct {
+		Permissions map[string]interface{} `json:"permissions"`
+	}
+	_ = json.Unmarshal(application.Spec.Raw, &spec)
+	if spec.Permissions == nil {
+		return nil, nil
+	}
+
+	var permissions v1.ApplicationPermissions
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Result:      &permissions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(spec.Permissions); err != nil {
+		return nil, xerrors.Errorf("invalid permissions: %w", err)
+	}
+	return &permissions, nil
+}
+
+// effectivePermissions normalizes roles in the same way as Fiat, which lowercases roles and grants EXECUTE to READ
+// roles when EXECUTE is not given.
+func effectivePermissions(permissions *v1.ApplicationPermissions) *v1.ApplicationPermissions {
+	effective := &v1.ApplicationPermissions{
+		Read:    normalizeRoles(permissions.Read),
+		Write:   normalizeRoles(permissions.Write),
+		Execute: normalizeRoles(permissions.Execute),
+	}
+	if len(effective.Execute) == 0 {
+		effective.Execute = effective.Read
+	}
+	return effective
+}
+
+func normalizeRoles(roles []string) []string {
+	var result []string
+	for _, role := range roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if role != "" && !containsString(result, role) {
+			result = append(result, role)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func allRoles(permissions *v1.ApplicationPermissions) []string {
+	var result []string
+	for _, roles := range [][]string{permissions.Read, permissions.Write, permissions.Execute} {
+		for _, role := range roles {
+			if !containsString(result, role) {
+				result = append(result, role)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// getKnownRoles collects the roles visible through Gate, which are the roles of the controller's own user and the
+// roles that service accounts are member of. It is a subset of the roles of Fiat, since Gate does not list them.
+func getKnownRoles(gateClient gateclient.GatewayClient) (user gate.User, roles []string, err error) {
+	user, _, err = gateClient.AuthControllerApi.UserUsingGET(gateClient.Context)
+	if err != nil {
+		return gate.User{}, nil, err
+	}
+	roles = normalizeRoles(user.Roles)
+
+	serviceAccounts, _, err := gateClient.AuthControllerApi.GetServiceAccountsUsingGET(gateClient.Context, &gate.AuthControllerApiGetServiceAccountsUsingGETOpts{})
+	if err != nil {
+		return gate.User{}, nil, err
+	}
+	for _, serviceAccount := range serviceAccounts {
+		var account struct {
+			MemberOf []string
+		}
+		if err := mapstructure.Decode(serviceAccount, &account); err != nil {
+			continue
+		}
+		for _, role := range normalizeRoles(account.MemberOf) {
+			if !containsString(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	sort.Strings(roles)
+	return user, roles, nil
+}
+
+func unknownRoles(permissions *v1.ApplicationPermissions, knownRoles []string) []string {
+	var result []string
+	for _, role := range allRoles(permissions) {
+		if !containsString(knownRoles, role) {
+			result = append(result, role)
+		}
+	}
+	return result
+}
+
+func isLockedOut(permissions *v1.ApplicationPermissions, user gate.User) bool {
+	if len(permissions.Read) == 0 && len(permissions.Write) == 0 && len(permissions.Execute) == 0 {
+		return false
+	}
+	for _, role := range normalizeRoles(user.Roles) {
+		if containsString(permissions.Write, role) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"spinnaker-dcd-controller/internal/audit"
+
+	"github.com/spinnaker/spin/cmd/gateclient"
+	gate "github.com/spinnaker/spin/gateapi"
+	"golang.org/x/xerrors"
+)
+
+// serverManagedFields are set by Spinnaker on every save, so that they are left out of the diff of audit records.
+var serverManagedFields = []string{"createTs", "updateTs", "lastModified", "lastModifiedBy"}
+
+// auditBefore returns the Spinnaker object that a change is about to overwrite, or nil when auditing is disabled or the
+// object cannot be read, which only makes the diff of the audit record coarser.
+func auditBefore(auditor *audit.Logger, get func() (interface{}, error)) interface{} {
+	if auditor == nil {
+		return nil
+	}
+	before, err := get()
+	if err != nil || before == nil {
+		return nil
+	}
+	var m map[string]interface{}
+	data, err := json.Marshal(before)
+	if err != nil || json.Unmarshal(data, &m) != nil || m == nil {
+		return nil
+	}
+	for _, field := range serverManagedFields {
+		delete(m, field)
+	}
+	return m
+}
+
+// taskError returns an error when a task of Spinnaker did not succeed, so that its audit record is marked as failed.
+func taskError(ref string, status string) error {
+	if status == executionStatusTerminal {
+		return xerrors.Errorf("task %s is %s", ref, status)
+	}
+	return nil
+}
+
+func getSpinnakerApplication(gateClient gateclient.GatewayClient, name string) (interface{}, error) {
+	application, resp, err := gateClient.ApplicationControllerApi.GetApplicationUsingGET(
+		gateClient.Context, name, &gate.ApplicationControllerApiGetApplicationUsingGETOpts{})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return application["attributes"], nil
+}
+
+func getSpinnakerPipeline(gateClient gateclient.GatewayClient, application string, name string) (interface{}, error) {
+	config, resp, err := gateClient.ApplicationControllerApi.GetPipelineConfigUsingGET(gateClient.Context, application, name)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
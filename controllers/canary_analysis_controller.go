@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/go-logr/logr"
+	"github.com/mitchellh/mapstructure"
+	gate "github.com/spinnaker/spin/gateapi"
+	"golang.org/x/xerrors"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	defaultCanaryScopeName = "default"
+	defaultCanaryStep      = 60
+
+	canaryVerdictPass = "Pass"
+
+	// canaryAnalysisInitiating is the reason of the Started condition saved before an analysis is initiated, so that
+	// an analysis whose ID was not recorded is looked up instead of being initiated again.
+	canaryAnalysisInitiating = "Initiating"
+	// canaryExecutionLookupLimit is the number of the latest analyses of the application looked up
+	canaryExecutionLookupLimit = 100
+)
+
+type CanaryAnalysisReconciler struct {
+	client.Client
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Gateway                 gateway.Client
+	DeckEndpoint            string
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
+}
+
+type canaryExecutionStatus struct {
+	PipelineID                string
+	ParentPipelineExecutionID string
+}
+
+type canaryExecutionResult struct {
+	Complete bool
+	Status   string
+	Result   struct {
+		JudgeResult struct {
+			Score struct {
+				Score          float64
+				Classification string
+			}
+			Results []struct {
+				Name                 string
+				Classification       string
+				ClassificationReason string
+			}
+		}
+	}
+}
+
+func (r *CanaryAnalysisReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
+	canaryAnalysis := &v1.CanaryAnalysis{}
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "CanaryAnalysis", req)
+	defer func() { endReconcileSpan(span, canaryAnalysis, err) }()
+	logger := r.Log.WithValues("canaryAnalysis", req.NamespacedName)
+	if err := r.Get(ctx, req.NamespacedName, canaryAnalysis); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, canaryAnalysis) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, canaryAnalysis); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, canaryAnalysis, canaryAnalysis.Status.SpinnakerResource.ID != "")
+
+	if !canaryAnalysis.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if canaryAnalysis.Status.SpinnakerResource.ID == "" {
+		return r.initiate(ctx, canaryAnalysis, logger)
+	}
+
+	if r.isComplete(canaryAnalysis) {
+		return ctrl.Result{}, nil
+	}
+
+	result, err := r.getResult(ctx, canaryAnalysis)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := canaryAnalysis.Status.DeepCopy()
+	status.Status = result.Status
+	if result.Complete {
+		judgeResult := result.Result.JudgeResult
+		status.Verdict = judgeResult.Score.Classification
+		status.Score = strconv.FormatFloat(judgeResult.Score.Score, 'f', -1, 64)
+		status.Metrics = nil
+		for _, metric := range judgeResult.Results {
+			status.Metrics = append(status.Metrics, v1.CanaryAnalysisMetricResult{
+				Name:                 metric.Name,
+				Classification:       metric.Classification,
+				ClassificationReason: metric.ClassificationReason,
+			})
+		}
+		verdict := status.Verdict
+		if verdict == "" {
+			verdict = strings.ToUpper(result.Status)
+		}
+		conditionStatus := "False"
+		if strings.EqualFold(verdict, canaryVerdictPass) {
+			conditionStatus = "True"
+		}
+		r.setCondition(status, v1.CanaryAnalysisComplete, conditionStatus, verdict)
+	}
+
+	if !reflect.DeepEqual(*status, canaryAnalysis.Status) {
+		if result.Complete {
+			if strings.EqualFold(status.Verdict, canaryVerdictPass) {
+				r.Recorder.Eventf(canaryAnalysis, coreV1.EventTypeNormal, "SuccessfulAnalysis", "Canary analysis %s passed with score %s", status.SpinnakerResource.ID, status.Score)
+			} else {
+				r.Recorder.Eventf(canaryAnalysis, coreV1.EventTypeWarning, "FailedAnalysis", "Canary analysis %s finished with %s, verdict %q and score %s", status.SpinnakerResource.ID, status.Status, status.Verdict, status.Score)
+			}
+		}
+		canaryAnalysis.Status = *status
+		logger.V(1).Info("update", "canary analysis", canaryAnalysis)
+		if err := r.Update(ctx, canaryAnalysis); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !result.Complete {
+		return ctrl.Result{RequeueAfter: r.Settings.get().ExecutionPollInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *CanaryAnalysisReconciler) initiate(ctx context.Context, canaryAnalysis *v1.CanaryAnalysis, logger logr.Logger) (ctrl.Result, error) {
+	canaryConfig := &v1.CanaryConfig{}
+	if err := r.Get(ctx, client.ObjectKey{Name: canaryAnalysis.Spec.CanaryConfigName}, canaryConfig); err != nil {
+		if errors.IsNotFound(err) {
+			logger.V(1).Info("wait for canary config to be created")
+			return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	canaryConfigID := canaryConfig.Status.SpinnakerResource.ID
+	if canaryConfigID == "" {
+		logger.V(1).Info("wait for canary config to be saved")
+		return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+	}
+
+	// Kayenta analyzes the window as soon as it starts, so that the window has to be over before starting.
+	if wait := time.Until(canaryAnalysis.Spec.EndTime.Time); wait > 0 {
+		logger.V(1).Info("wait for the end of the analysis window", "endTime", canaryAnalysis.Spec.EndTime)
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	// The analysis may have been initiated without its ID being recorded, e.g. when the update of status failed.
+	if r.isInitiating(canaryAnalysis) {
+		if canaryAnalysis.Spec.Application == "" {
+			r.Recorder.Eventf(canaryAnalysis, coreV1.EventTypeWarning, "StartUnknown", "Canary analysis may have been started without recording its ID, set spec.application to look it up")
+			return ctrl.Result{}, nil
+		}
+		id, err := r.findExecution(ctx, canaryAnalysis)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if id != "" {
+			return r.recordStarted(ctx, canaryAnalysis, canaryConfigID, id, logger)
+		}
+	} else {
+		r.setCondition(&canaryAnalysis.Status, v1.CanaryAnalysisStarted, "Unknown", canaryAnalysisInitiating)
+		if err := r.Update(ctx, canaryAnalysis); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	gateClient := r.Gateway.Gate(ctx)
+	request := r.buildExecutionRequest(canaryAnalysis)
+	raw, resp, err := gateClient.V2CanaryControllerApi.InitiateCanaryUsingPOST(
+		gateClient.Context, canaryConfigID, request,
+		&gate.V2CanaryControllerApiInitiateCanaryUsingPOSTOpts{
+			Application:               optionalString(canaryAnalysis.Spec.Application),
+			MetricsAccountName:        optionalString(canaryAnalysis.Spec.MetricsAccountName),
+			StorageAccountName:        optionalString(canaryAnalysis.Spec.StorageAccountName),
+			ParentPipelineExecutionId: optional.NewString(string(canaryAnalysis.UID)),
+		})
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = xerrors.Errorf("encountered an error starting canary analysis, status code: %d", resp.StatusCode)
+	}
+	r.Audit.Log(canaryAnalysis, audit.Change{
+		Operation: audit.OperationExecute,
+		Object:    "canaryConfig",
+		ID:        canaryConfigID,
+		After:     request,
+	}, err)
+	if err != nil {
+		r.Recorder.Eventf(canaryAnalysis, coreV1.EventTypeWarning, "StartFailed", "Failed to start canary analysis with canary config: %q", canaryConfigID)
+		return ctrl.Result{}, err
+	}
+	response, _ := raw.(map[string]interface{})
+	id, _ := response["canaryExecutionId"].(string)
+	if id == "" {
+		return ctrl.Result{}, xerrors.Errorf("canary execution ID missing in response: %v", raw)
+	}
+	return r.recordStarted(ctx, canaryAnalysis, canaryConfigID, id, logger)
+}
+
+func (r *CanaryAnalysisReconciler) recordStarted(ctx context.Context, canaryAnalysis *v1.CanaryAnalysis, canaryConfigID string, id string, logger logr.Logger) (ctrl.Result, error) {
+	canaryAnalysis.Status.SpinnakerResource.CanaryConfigID = canaryConfigID
+	canaryAnalysis.Status.SpinnakerResource.ID = id
+	canaryAnalysis.Status.ReportURL = r.buildReportURL(canaryAnalysis)
+	r.setCondition(&canaryAnalysis.Status, v1.CanaryAnalysisStarted, "True", "")
+	r.Recorder.Eventf(canaryAnalysis, coreV1.EventTypeNormal, "SuccessfulStarted", "Started canary analysis: %q", id)
+	logger.V(1).Info("start", "canary analysis", canaryAnalysis)
+	if err := r.Update(ctx, canaryAnalysis); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.Settings.get().ExecutionPollInterval}, nil
+}
+
+// findExecution returns the ID of the analysis initiated for the CanaryAnalysis, which Kayenta records as the parent
+// pipeline execution, or an empty string when it has not been initiated.
+func (r *CanaryAnalysisReconciler) findExecution(ctx context.Context, canaryAnalysis *v1.CanaryAnalysis) (string, error) {
+	gateClient := r.Gateway.Gate(ctx)
+	executions, _, err := gateClient.V2CanaryControllerApi.GetCanaryResultsByApplicationUsingGET(
+		gateClient.Context, canaryAnalysis.Spec.Application, canaryExecutionLookupLimit,
+		&gate.V2CanaryControllerApiGetCanaryResultsByApplicationUsingGETOpts{
+			StorageAccountName: optionalString(canaryAnalysis.Spec.StorageAccountName),
+		})
+	if err != nil {
+		return "", xerrors.Errorf("failed to list canary analyses of %s: %w", canaryAnalysis.Spec.Application, err)
+	}
+	for _, raw := range executions {
+		var execution canaryExecutionStatus
+		if err := mapstructure.WeakDecode(raw, &execution); err != nil {
+			return "", xerrors.Errorf("failed to decode canary analysis: %w", err)
+		}
+		if execution.ParentPipelineExecutionID == string(canaryAnalysis.UID) {
+			return execution.PipelineID, nil
+		}
+	}
+	return "", nil
+}
+
+func (r *CanaryAnalysisReconciler) getResult(ctx context.Context, canaryAnalysis *v1.CanaryAnalysis) (*canaryExecutionResult, error) {
+	gateClient := r.Gateway.Gate(ctx)
+	raw, _, err := gateClient.V2CanaryControllerApi.GetCanaryResultUsingGET1(
+		gateClient.Context, canaryAnalysis.Status.SpinnakerResource.ID,
+		&gate.V2CanaryControllerApiGetCanaryResultUsingGET1Opts{
+			StorageAccountName: optionalString(canaryAnalysis.Spec.StorageAccountName),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var result canaryExecutionResult
+	if err := mapstructure.WeakDecode(raw, &result); err != nil {
+		return nil, xerrors.Errorf("failed to decode canary result: %w", err)
+	}
+	return &result, nil
+}
+
+func (r *CanaryAnalysisReconciler) isInitiating(canaryAnalysis *v1.CanaryAnalysis) bool {
+	for _, condition := range canaryAnalysis.Status.Conditions {
+		if condition.Type == v1.CanaryAnalysisStarted && condition.Reason == canaryAnalysisInitiating {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CanaryAnalysisReconciler) setCondition(status *v1.CanaryAnalysisStatus, conditionType v1.CanaryAnalysisConditionType, conditionStatus string, reason string) {
+	for i, condition := range status.Conditions {
+		if condition.Type == conditionType {
+			status.Conditions[i].Status = conditionStatus
+			status.Conditions[i].Reason = reason
+			return
+		}
+	}
+	status.Conditions = append(status.Conditions, v1.CanaryAnalysisCondition{
+		Type:   conditionType,
+		Status: conditionStatus,
+		Reason: reason,
+	})
+}
+
+func (r *CanaryAnalysisReconciler) isComplete(canaryAnalysis *v1.CanaryAnalysis) bool {
+	for _, condition := range canaryAnalysis.Status.Conditions {
+		if condition.Type == v1.CanaryAnalysisComplete {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *CanaryAnalysisReconciler) buildExecutionRequest(canaryAnalysis *v1.CanaryAnalysis) map[string]interface{} {
+	step := canaryAnalysis.Spec.StepSeconds
+	if step == 0 {
+		step = defaultCanaryStep
+	}
+	start := canaryAnalysis.Spec.StartTime.UTC().Format(time.RFC3339)
+	end := canaryAnalysis.Spec.EndTime.UTC().Format(time.RFC3339)
+	buildScope := func(scope v1.CanaryScope) map[string]interface{} {
+		return map[string]interface{}{
+			"scope":               scope.Scope,
+			"location":            scope.Location,
+			"start":               start,
+			"end":                 end,
+			"step":                step,
+			"extendedScopeParams": scope.ExtendedScopeParams,
+		}
+	}
+
+	scopes := map[string]interface{}{}
+	for _, scope := range canaryAnalysis.Spec.Scopes {
+		name := scope.Name
+		if name == "" {
+			name = defaultCanaryScopeName
+		}
+		scopes[name] = map[string]interface{}{
+			"controlScope":    buildScope(scope.Control),
+			"experimentScope": buildScope(scope.Experiment),
+		}
+	}
+
+	return map[string]interface{}{
+		"scopes": scopes,
+		"thresholds": map[string]interface{}{
+			"pass":     canaryAnalysis.Spec.Thresholds.Pass,
+			"marginal": canaryAnalysis.Spec.Thresholds.Marginal,
+		},
+	}
+}
+
+func (r *CanaryAnalysisReconciler) buildReportURL(canaryAnalysis *v1.CanaryAnalysis) string {
+	if r.DeckEndpoint == "" || canaryAnalysis.Spec.Application == "" {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%s/#/applications/%s/canary/report/%s/%s",
+		strings.TrimSuffix(r.DeckEndpoint, "/"),
+		canaryAnalysis.Spec.Application,
+		canaryAnalysis.Status.SpinnakerResource.CanaryConfigID,
+		canaryAnalysis.Status.SpinnakerResource.ID,
+	)
+}
+
+func optionalString(s string) optional.String {
+	if s == "" {
+		return optional.EmptyString()
+	}
+	return optional.NewString(s)
+}
+
+func (r *CanaryAnalysisReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.CanaryAnalysis{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.CanaryAnalysis{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.CanaryAnalysisList{}), &handler.EnqueueRequestForObject{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(newRequeueOnCancel(r, r.Log))
+}
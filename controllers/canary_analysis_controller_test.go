@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/gateway"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// failingUpdateClient fails the update numbered failUpdate, counted from 1, as if the API server had refused it.
+type failingUpdateClient struct {
+	client.Client
+	updates    int
+	failUpdate int
+}
+
+func (c *failingUpdateClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	c.updates++
+	if c.updates == c.failUpdate {
+		return xerrors.New("injected update failure")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func newCanaryAnalysisReconciler(gatewayClient gateway.Client, canaryAnalysis *v1.CanaryAnalysis) *CanaryAnalysisReconciler {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	canaryConfig := &v1.CanaryConfig{ObjectMeta: metaV1.ObjectMeta{Name: "sample"}}
+	canaryConfig.Status.SpinnakerResource.ID = "sample-config"
+	return &CanaryAnalysisReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme, canaryConfig, canaryAnalysis),
+		Log:      ctrl.Log.WithName("controllers").WithName("CanaryAnalysis"),
+		Recorder: record.NewFakeRecorder(100),
+		Gateway:  gatewayClient,
+	}
+}
+
+func newCanaryAnalysis() *v1.CanaryAnalysis {
+	return &v1.CanaryAnalysis{
+		ObjectMeta: metaV1.ObjectMeta{Name: "sample", UID: "sample-uid"},
+		Spec: v1.CanaryAnalysisSpec{
+			CanaryConfigName: "sample",
+			Application:      "sample",
+			Scopes: []v1.CanaryAnalysisScope{{
+				Control:    v1.CanaryScope{Scope: "baseline"},
+				Experiment: v1.CanaryScope{Scope: "canary"},
+			}},
+			StartTime: metaV1.NewTime(time.Now().Add(-time.Hour)),
+			EndTime:   metaV1.NewTime(time.Now().Add(-time.Minute)),
+		},
+	}
+}
+
+func reconcileCanaryAnalysis(r *CanaryAnalysisReconciler) (*v1.CanaryAnalysis, error) {
+	key := client.ObjectKey{Name: "sample"}
+	_, reconcileErr := r.Reconcile(ctrl.Request{NamespacedName: key})
+	canaryAnalysis := &v1.CanaryAnalysis{}
+	if err := r.Get(context.Background(), key, canaryAnalysis); err != nil {
+		return nil, err
+	}
+	return canaryAnalysis, reconcileErr
+}
+
+func TestCanaryAnalysisAdoptsAnalysisWhoseIDWasNotRecorded(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	server.AddCanaryConfig("sample-config", map[string]interface{}{"name": "sample"})
+	r := newCanaryAnalysisReconciler(gatewayClient, newCanaryAnalysis())
+	// The update recording the ID fails after the analysis has been initiated.
+	r.Client = &failingUpdateClient{Client: r.Client, failUpdate: 2}
+
+	if _, err := reconcileCanaryAnalysis(r); err == nil {
+		t.Fatal("expected the failed update to be returned")
+	}
+	canaryAnalysis, err := reconcileCanaryAnalysis(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canaryAnalysis.Status.SpinnakerResource.ID == "" || !hasCanaryAnalysisCondition(canaryAnalysis, v1.CanaryAnalysisStarted, "True") {
+		t.Fatalf("expected the initiated analysis to be adopted, got %+v", canaryAnalysis.Status)
+	}
+	if count := server.CountRequests(http.MethodPost, "/v2/canaries/canary/sample-config"); count != 1 {
+		t.Fatalf("expected the analysis to be initiated once, got %d", count)
+	}
+}
+
+func TestCanaryAnalysisWaitsWithoutApplicationToLookUp(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	server.AddCanaryConfig("sample-config", map[string]interface{}{"name": "sample"})
+	canaryAnalysis := newCanaryAnalysis()
+	canaryAnalysis.Spec.Application = ""
+	canaryAnalysis.Status.Conditions = []v1.CanaryAnalysisCondition{{
+		Type:   v1.CanaryAnalysisStarted,
+		Status: "Unknown",
+		Reason: canaryAnalysisInitiating,
+	}}
+	r := newCanaryAnalysisReconciler(gatewayClient, canaryAnalysis)
+
+	canaryAnalysis, err := reconcileCanaryAnalysis(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canaryAnalysis.Status.SpinnakerResource.ID != "" || server.CountRequests(http.MethodPost, "/v2/canaries/canary/sample-config") != 0 {
+		t.Fatalf("expected the analysis not to be initiated again, got %+v", canaryAnalysis.Status)
+	}
+}
+
+func TestCanaryAnalysisComparesVerdictCaseInsensitively(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	server.AddCanaryConfig("sample-config", map[string]interface{}{"name": "sample"})
+	server.SetCanaryVerdict("pass", 95)
+	r := newCanaryAnalysisReconciler(gatewayClient, newCanaryAnalysis())
+
+	if _, err := reconcileCanaryAnalysis(r); err != nil {
+		t.Fatal(err)
+	}
+	canaryAnalysis, err := reconcileCanaryAnalysis(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canaryAnalysis.Status.Verdict != "pass" || canaryAnalysis.Status.Score != "95" {
+		t.Fatalf("expected the result to be recorded, got %+v", canaryAnalysis.Status)
+	}
+	if !hasCanaryAnalysisCondition(canaryAnalysis, v1.CanaryAnalysisComplete, "True") {
+		t.Fatalf("expected the analysis to pass, got %+v", canaryAnalysis.Status.Conditions)
+	}
+}
+
+func hasCanaryAnalysisCondition(canaryAnalysis *v1.CanaryAnalysis, conditionType v1.CanaryAnalysisConditionType, status string) bool {
+	for _, condition := range canaryAnalysis.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == status
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+
+	gate "github.com/spinnaker/spin/gateapi"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	canaryConfigApplicationsField = "spec.applications"
+
+	tenantAnnotation = "spinnaker.kaidotdev.github.io/tenant"
+)
+
+type canaryConfigScope struct {
+	// Applications are the applications that still exist, in the order of the spec
+	Applications []string
+	// Waiting is true while any application is an Application that has not been created yet
+	Waiting bool
+	// Problems are the applications that the tenant of the canary config cannot use
+	Problems []string
+}
+
+func parseCanaryConfigApplications(canaryConfig *v1.CanaryConfig) []string {
+	var spec struct {
+		Applications []string `json:"applications"`
+	}
+	_ = json.Unmarshal(canaryConfig.Spec.Raw, &spec)
+	return spec.Applications
+}
+
+// scopeApplications resolves the applications of the canary config against Application CRs in the same way as
+// PipelineReconciler.isApplicationCreated, and drops the ones that have been deleted so that the config can be
+// re-scoped to the rest.
+func (r *CanaryConfigReconciler) scopeApplications(ctx context.Context, canaryConfig *v1.CanaryConfig, applicationNames []string) (*canaryConfigScope, error) {
+	tenant := canaryConfig.Annotations[tenantAnnotation]
+	scope := &canaryConfigScope{}
+	for _, applicationName := range applicationNames {
+		application := &v1.Application{}
+		if err := r.Get(ctx, client.ObjectKey{Name: applicationName}, application); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, err
+			}
+			if tenant != "" {
+				scope.Problems = append(scope.Problems, fmt.Sprintf("application %q is not an Application of tenant %q", applicationName, tenant))
+				continue
+			}
+			exists, err := r.isApplicationExisting(ctx, applicationName)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				scope.Applications = append(scope.Applications, applicationName)
+			}
+			continue
+		}
+
+		if !application.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if applicationTenant := application.Annotations[tenantAnnotation]; applicationTenant != tenant {
+			if tenant == "" {
+				scope.Problems = append(scope.Problems, fmt.Sprintf("application %q belongs to tenant %q", applicationName, applicationTenant))
+			} else {
+				scope.Problems = append(scope.Problems, fmt.Sprintf("application %q does not belong to tenant %q", applicationName, tenant))
+			}
+			continue
+		}
+		if application.Status.SpinnakerResource.ApplicationName == "" {
+			scope.Waiting = true
+		}
+		scope.Applications = append(scope.Applications, applicationName)
+	}
+	return scope, nil
+}
+
+func (r *CanaryConfigReconciler) isApplicationExisting(ctx context.Context, applicationName string) (bool, error) {
+	gateClient := r.Gateway.Gate(ctx)
+	_, resp, err := gateClient.ApplicationControllerApi.GetApplicationUsingGET(
+		gateClient.Context, applicationName, &gate.ApplicationControllerApiGetApplicationUsingGETOpts{})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *CanaryConfigReconciler) mapApplication(object handler.MapObject) []ctrl.Request {
+	canaryConfigList := &v1.CanaryConfigList{}
+	if err := r.List(context.Background(), canaryConfigList, client.MatchingFields{canaryConfigApplicationsField: object.Meta.GetName()}); err != nil {
+		r.Log.Error(err, "failed to list canary configs", "application", object.Meta.GetName())
+		return nil
+	}
+	var requests []ctrl.Request
+	for _, canaryConfig := range canaryConfigList.Items {
+		requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: canaryConfig.Name}})
+	}
+	return requests
+}
+
+func indexCanaryConfigApplications(object runtime.Object) []string {
+	return parseCanaryConfigApplications(object.(*v1.CanaryConfig))
+}
+
+func stringSliceEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
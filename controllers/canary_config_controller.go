@@ -7,30 +7,47 @@ import (
 	"fmt"
 	"net/http"
 	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
+	"strings"
 
 	"github.com/go-logr/logr"
-	"github.com/spinnaker/spin/cmd/gateclient"
 	gate "github.com/spinnaker/spin/gateapi"
 	"golang.org/x/xerrors"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type CanaryConfigReconciler struct {
 	client.Client
-	Log             logr.Logger
-	Scheme          *runtime.Scheme
-	Recorder        record.EventRecorder
-	SpinnakerClient gateclient.GatewayClient
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Gateway                 gateway.Client
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Revisions               *Revisions
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
 }
 
-func (r *CanaryConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *CanaryConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
 	canaryConfig := &v1.CanaryConfig{}
-	ctx := context.Background()
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "CanaryConfig", req)
+	defer func() { endReconcileSpan(span, canaryConfig, err) }()
 	logger := r.Log.WithValues("canaryConfig", req.NamespacedName)
 	if err := r.Get(ctx, req.NamespacedName, canaryConfig); err != nil {
 		if errors.IsNotFound(err) {
@@ -38,25 +55,88 @@ func (r *CanaryConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		}
 		return ctrl.Result{}, err
 	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, canaryConfig) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, canaryConfig); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, canaryConfig, containsString(canaryConfig.ObjectMeta.Finalizers, myFinalizerName))
+	if rolledBack, err := r.rollback(ctx, canaryConfig); rolledBack {
+		return ctrl.Result{}, err
+	}
 
 	if canaryConfig.ObjectMeta.DeletionTimestamp.IsZero() {
 		hash := fmt.Sprintf("%x", sha256.Sum256(canaryConfig.Spec.Raw))
 		oldHash := canaryConfig.Status.Hash
-		if hash != oldHash {
+		applicationNames := parseCanaryConfigApplications(canaryConfig)
+		scope, err := r.scopeApplications(ctx, canaryConfig, applicationNames)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if hash != oldHash || !stringSliceEqual(scope.Applications, canaryConfig.Status.Applications) {
+			if scope.Waiting {
+				logger.V(1).Info("wait for applications to be created")
+				return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+			}
+			if len(applicationNames) > 0 && len(scope.Applications) == 0 && len(scope.Problems) == 0 {
+				return r.cleanUp(ctx, canaryConfig, hash, logger)
+			}
+
 			var configJSON map[string]interface{}
 			_ = json.Unmarshal(canaryConfig.Spec.Raw, &configJSON)
 
-			if _, exists := configJSON["id"]; !exists {
-				return ctrl.Result{}, xerrors.New("required canary config key 'id' missing...")
+			if configJSON == nil {
+				configJSON = map[string]interface{}{}
+			}
+			if len(applicationNames) > 0 {
+				configJSON["applications"] = scope.Applications
+			}
+			id, name, err := r.identify(canaryConfig, configJSON)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			configJSON["id"] = id
+			configJSON["name"] = name
+
+			conflict, err := r.findConflict(ctx, canaryConfig, id)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if conflict != "" {
+				r.setCondition(canaryConfig, v1.CanaryConfigConflict, "True", conflict)
+				r.Recorder.Eventf(canaryConfig, coreV1.EventTypeWarning, "ConflictingCanaryConfig", conflict)
+				if err := r.Update(ctx, canaryConfig); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{RequeueAfter: r.Settings.get().InvalidResyncInterval}, nil
+			}
+			r.setCondition(canaryConfig, v1.CanaryConfigConflict, "False", "")
+
+			problems, err := validateCanaryConfig(r.Gateway.Gate(ctx), configJSON)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			problems = append(scope.Problems, problems...)
+			if len(problems) > 0 {
+				message := strings.Join(problems, "; ")
+				r.setCondition(canaryConfig, v1.CanaryConfigValid, "False", message)
+				r.Recorder.Eventf(canaryConfig, coreV1.EventTypeWarning, "InvalidCanaryConfig", message)
+				if err := r.Update(ctx, canaryConfig); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{RequeueAfter: r.Settings.get().InvalidResyncInterval}, nil
 			}
+			r.setCondition(canaryConfig, v1.CanaryConfigValid, "True", "")
 
-			if err := r.saveCanaryConfig(configJSON); err != nil {
+			if err := r.saveCanaryConfig(ctx, canaryConfig, configJSON); err != nil {
 				return ctrl.Result{}, err
 			}
 
-			canaryConfig.Status.SpinnakerResource.Name = configJSON["name"].(string)
-			canaryConfig.Status.SpinnakerResource.ID = configJSON["id"].(string)
+			canaryConfig.Status.SpinnakerResource.Name = name
+			canaryConfig.Status.SpinnakerResource.ID = id
 			canaryConfig.Status.Hash = hash
+			canaryConfig.Status.Applications = scope.Applications
 			if !containsString(canaryConfig.ObjectMeta.Finalizers, myFinalizerName) {
 				canaryConfig.ObjectMeta.Finalizers = append(canaryConfig.ObjectMeta.Finalizers, myFinalizerName)
 			}
@@ -72,15 +152,17 @@ func (r *CanaryConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		}
 	} else {
 		if containsString(canaryConfig.ObjectMeta.Finalizers, myFinalizerName) {
-			if err := r.deleteCanaryConfig(canaryConfig.Status.SpinnakerResource.ID); err != nil {
-				return ctrl.Result{}, err
+			if !r.Settings.retain(r.Recorder, canaryConfig) {
+				if err := r.deleteCanaryConfig(ctx, canaryConfig, canaryConfig.Status.SpinnakerResource.ID); err != nil {
+					return ctrl.Result{}, err
+				}
+				canaryConfig.Status.Conditions = append(canaryConfig.Status.Conditions, v1.CanaryConfigCondition{
+					Type:   v1.CanaryConfigDeletionComplete,
+					Status: "True",
+				})
+				r.Recorder.Eventf(canaryConfig, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted canary config: %q", req.Name)
+				logger.V(1).Info("delete", "canary config", canaryConfig)
 			}
-			canaryConfig.Status.Conditions = append(canaryConfig.Status.Conditions, v1.CanaryConfigCondition{
-				Type:   v1.CanaryConfigDeletionComplete,
-				Status: "True",
-			})
-			r.Recorder.Eventf(canaryConfig, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted canary config: %q", req.Name)
-			logger.V(1).Info("delete", "canary config", canaryConfig)
 
 			canaryConfig.ObjectMeta.Finalizers = removeString(canaryConfig.ObjectMeta.Finalizers, myFinalizerName)
 			if err := r.Update(ctx, canaryConfig); err != nil {
@@ -92,20 +174,121 @@ func (r *CanaryConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 	return ctrl.Result{}, nil
 }
 
-func (r *CanaryConfigReconciler) saveCanaryConfig(configJSON map[string]interface{}) error {
+// identify returns the ID and name of the canary config. The ID defaults to the UID, which is stable and unique
+// unlike the IDs that are copied along with manifests, and the name defaults to metadata.name.
+func (r *CanaryConfigReconciler) identify(canaryConfig *v1.CanaryConfig, configJSON map[string]interface{}) (string, string, error) {
+	id := string(canaryConfig.UID)
+	if value, exists := configJSON["id"]; exists {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return "", "", xerrors.Errorf("canary config key 'id' must be a non-empty string: %v", value)
+		}
+		id = s
+	}
+	if oldID := canaryConfig.Status.SpinnakerResource.ID; oldID != "" && oldID != id {
+		return "", "", xerrors.Errorf("canary config key 'id' cannot be changed from %s to %s", oldID, id)
+	}
+
+	name := canaryConfig.Name
+	if value, exists := configJSON["name"]; exists {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return "", "", xerrors.Errorf("canary config key 'name' must be a non-empty string: %v", value)
+		}
+		name = s
+	}
+	return id, name, nil
+}
+
+// findConflict describes why the canary config of the ID belongs to someone else, or returns an empty string.
+// The controller owns the canary configs it has recorded in status and the ones with the ID derived from the UID,
+// so that it never takes over a config created in Deck or by another CanaryConfig.
+func (r *CanaryConfigReconciler) findConflict(ctx context.Context, canaryConfig *v1.CanaryConfig, id string) (string, error) {
+	canaryConfigList := &v1.CanaryConfigList{}
+	if err := r.List(ctx, canaryConfigList); err != nil {
+		return "", err
+	}
+	for _, other := range canaryConfigList.Items {
+		if other.UID != canaryConfig.UID && other.Status.SpinnakerResource.ID == id {
+			return fmt.Sprintf("canary config %s is owned by CanaryConfig %q", id, other.Name), nil
+		}
+	}
+
+	if canaryConfig.Status.SpinnakerResource.ID == id || string(canaryConfig.UID) == id {
+		return "", nil
+	}
+	gateClient := r.Gateway.Gate(ctx)
+	_, resp, err := gateClient.V2CanaryConfigControllerApi.GetCanaryConfigUsingGET(
+		gateClient.Context, id, &gate.V2CanaryConfigControllerApiGetCanaryConfigUsingGETOpts{})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("canary config %s already exists and was not created by this controller", id), nil
+}
+
+// cleanUp deletes the canary config from Spinnaker once all of its applications have been deleted, and keeps the ID
+// in status so that the config is saved again when one of them comes back.
+func (r *CanaryConfigReconciler) cleanUp(ctx context.Context, canaryConfig *v1.CanaryConfig, hash string, logger logr.Logger) (ctrl.Result, error) {
+	if id := canaryConfig.Status.SpinnakerResource.ID; id != "" {
+		if err := r.deleteCanaryConfig(ctx, canaryConfig, id); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(canaryConfig, coreV1.EventTypeNormal, "SuccessfulCleanedUp", "Deleted canary config %q since all of its applications have been deleted", id)
+		logger.V(1).Info("clean up", "canary config", canaryConfig)
+	}
+	canaryConfig.Status.Applications = nil
+	canaryConfig.Status.Hash = hash
+	if err := r.Update(ctx, canaryConfig); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *CanaryConfigReconciler) setCondition(canaryConfig *v1.CanaryConfig, conditionType v1.CanaryConfigConditionType, status string, message string) {
+	for i, condition := range canaryConfig.Status.Conditions {
+		if condition.Type == conditionType {
+			canaryConfig.Status.Conditions[i].Status = status
+			canaryConfig.Status.Conditions[i].Message = message
+			return
+		}
+	}
+	canaryConfig.Status.Conditions = append(canaryConfig.Status.Conditions, v1.CanaryConfigCondition{
+		Type:    conditionType,
+		Status:  status,
+		Message: message,
+	})
+}
+
+func (r *CanaryConfigReconciler) saveCanaryConfig(ctx context.Context, canaryConfig *v1.CanaryConfig, configJSON map[string]interface{}) error {
+	gateClient := r.Gateway.Gate(ctx)
 	configID := configJSON["id"].(string)
 
-	_, resp, getErr := r.SpinnakerClient.V2CanaryConfigControllerApi.GetCanaryConfigUsingGET(
-		r.SpinnakerClient.Context, configID, &gate.V2CanaryConfigControllerApiGetCanaryConfigUsingGETOpts{})
+	existing, resp, getErr := gateClient.V2CanaryConfigControllerApi.GetCanaryConfigUsingGET(
+		gateClient.Context, configID, &gate.V2CanaryConfigControllerApiGetCanaryConfigUsingGETOpts{})
 
+	if resp == nil {
+		return getErr
+	}
+
+	change := audit.Change{
+		Object: "canaryConfig",
+		ID:     configID,
+		After:  configJSON,
+	}
 	var saveResp *http.Response
 	var saveErr error
 	if resp.StatusCode == http.StatusOK {
-		_, saveResp, saveErr = r.SpinnakerClient.V2CanaryConfigControllerApi.UpdateCanaryConfigUsingPUT(
-			r.SpinnakerClient.Context, configJSON, configID, &gate.V2CanaryConfigControllerApiUpdateCanaryConfigUsingPUTOpts{})
+		change.Operation = audit.OperationUpdate
+		change.Before = auditBefore(r.Audit, func() (interface{}, error) { return existing, nil })
+		_, saveResp, saveErr = gateClient.V2CanaryConfigControllerApi.UpdateCanaryConfigUsingPUT(
+			gateClient.Context, configJSON, configID, &gate.V2CanaryConfigControllerApiUpdateCanaryConfigUsingPUTOpts{})
 	} else if resp.StatusCode == http.StatusNotFound {
-		_, saveResp, saveErr = r.SpinnakerClient.V2CanaryConfigControllerApi.CreateCanaryConfigUsingPOST(
-			r.SpinnakerClient.Context, configJSON, &gate.V2CanaryConfigControllerApiCreateCanaryConfigUsingPOSTOpts{})
+		change.Operation = audit.OperationCreate
+		_, saveResp, saveErr = gateClient.V2CanaryConfigControllerApi.CreateCanaryConfigUsingPOST(
+			gateClient.Context, configJSON, &gate.V2CanaryConfigControllerApiCreateCanaryConfigUsingPOSTOpts{})
 	} else {
 		if getErr != nil {
 			return getErr
@@ -116,34 +299,72 @@ func (r *CanaryConfigReconciler) saveCanaryConfig(configJSON map[string]interfac
 			resp.StatusCode, configID)
 	}
 
+	if saveErr == nil && saveResp.StatusCode != http.StatusOK {
+		saveErr = xerrors.Errorf(
+			"encountered an error saving canary config %v, status code: %d",
+			configJSON, saveResp.StatusCode)
+	}
+	r.Audit.Log(canaryConfig, change, saveErr)
 	if saveErr != nil {
 		return saveErr
 	}
-
-	if saveResp.StatusCode != http.StatusOK {
-		return xerrors.Errorf(
-			"encountered an error saving canary config %v, status code: %d",
-			configJSON, saveResp.StatusCode)
+	if err := r.Revisions.record(ctx, canaryConfig, configJSON, ""); err != nil {
+		r.Log.Error(err, "unable to record revision", "canaryConfig", canaryConfig.Name)
 	}
 
 	return nil
 }
 
-func (r *CanaryConfigReconciler) deleteCanaryConfig(id string) error {
-	resp, err := r.SpinnakerClient.V2CanaryConfigControllerApi.DeleteCanaryConfigUsingDELETE(
-		r.SpinnakerClient.Context, id, &gate.V2CanaryConfigControllerApiDeleteCanaryConfigUsingDELETEOpts{})
-	if err != nil {
-		return err
-	}
+func (r *CanaryConfigReconciler) rollback(ctx context.Context, canaryConfig *v1.CanaryConfig) (bool, error) {
+	return r.Revisions.rollback(ctx, r.Client, r.Recorder, canaryConfig, func(ctx context.Context, payload json.RawMessage) error {
+		var configJSON map[string]interface{}
+		if err := json.Unmarshal(payload, &configJSON); err != nil {
+			return err
+		}
+		return r.saveCanaryConfig(ctx, canaryConfig, configJSON)
+	})
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return xerrors.Errorf(
+func (r *CanaryConfigReconciler) deleteCanaryConfig(ctx context.Context, canaryConfig *v1.CanaryConfig, id string) error {
+	gateClient := r.Gateway.Gate(ctx)
+	change := audit.Change{
+		Operation: audit.OperationDelete,
+		Object:    "canaryConfig",
+		ID:        id,
+		Before: auditBefore(r.Audit, func() (interface{}, error) {
+			existing, resp, err := gateClient.V2CanaryConfigControllerApi.GetCanaryConfigUsingGET(
+				gateClient.Context, id, &gate.V2CanaryConfigControllerApiGetCanaryConfigUsingGETOpts{})
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, nil
+			}
+			return existing, err
+		}),
+	}
+	resp, err := gateClient.V2CanaryConfigControllerApi.DeleteCanaryConfigUsingDELETE(
+		gateClient.Context, id, &gate.V2CanaryConfigControllerApiDeleteCanaryConfigUsingDELETEOpts{})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = xerrors.Errorf(
 			"encountered an error deleting canary config, status code: %d", resp.StatusCode)
 	}
+	r.Audit.Log(canaryConfig, change, err)
 
-	return nil
+	return err
 }
 
 func (r *CanaryConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&v1.CanaryConfig{}).Complete(r)
+	if err := mgr.GetFieldIndexer().IndexField(&v1.CanaryConfig{}, canaryConfigApplicationsField, indexCanaryConfigApplications); err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.CanaryConfig{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.CanaryConfig{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.CanaryConfigList{}), &handler.EnqueueRequestForObject{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Watches(&source.Kind{Type: &v1.Application{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.mapApplication),
+		}).
+		Complete(newRequeueOnCancel(r, r.Log))
 }
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const envtestCanaryConfigSpec = `
+description: %s
+metrics:
+  - name: cpu
+    query:
+      type: prometheus
+      metricName: container_cpu_usage_seconds_total
+    groups:
+      - system
+classifier:
+  groupWeights:
+    system: 100
+`
+
+func TestCanaryConfigLifecycle(t *testing.T) {
+	requireEnvironment(t)
+	defer testGate.ClearFailures()
+
+	applicationKey := client.ObjectKey{Name: "envtest-canary-app"}
+	key := client.ObjectKey{Name: "envtest-canary-config"}
+
+	// The application cannot be created until the failure is cleared, so that the canary config has to wait for it.
+	testGate.Fail(http.MethodPost, "/applications/"+applicationKey.Name+"/tasks", http.StatusInternalServerError, 0)
+	application := &v1.Application{
+		ObjectMeta: metaV1.ObjectMeta{Name: applicationKey.Name},
+		Spec:       rawSpec("email: canary@example.com"),
+	}
+	create(t, application)
+	canaryConfig := &v1.CanaryConfig{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name},
+		Spec:       rawSpec("applications: [envtest-canary-app]\n" + canaryConfigManifest("before")),
+	}
+	create(t, canaryConfig)
+	if err := testClient.Get(context.Background(), key, canaryConfig); err != nil {
+		t.Fatal(err)
+	}
+	id := string(canaryConfig.UID)
+
+	consistently(t, 3*time.Second, func() error {
+		if _, ok := testGate.CanaryConfig(id); ok {
+			return xerrors.New("canary config is saved before its application is created")
+		}
+		return nil
+	})
+
+	testGate.ClearFailures()
+	eventually(t, func() error {
+		saved, ok := testGate.CanaryConfig(id)
+		if !ok {
+			return xerrors.New("canary config is not saved")
+		}
+		if saved["name"] != key.Name || saved["description"] != "before" {
+			return xerrors.Errorf("unexpected canary config: %v", saved)
+		}
+		return nil
+	})
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, canaryConfig); err != nil {
+			return err
+		}
+		if canaryConfig.Status.SpinnakerResource.ID != id {
+			return xerrors.Errorf("unexpected status: %+v", canaryConfig.Status)
+		}
+		return nil
+	})
+
+	update(t, key, canaryConfig, func() {
+		canaryConfig.Spec = rawSpec("applications: [envtest-canary-app]\n" + canaryConfigManifest("after"))
+	})
+	eventually(t, func() error {
+		saved, _ := testGate.CanaryConfig(id)
+		if saved["description"] != "after" {
+			return xerrors.Errorf("canary config is not updated: %v", saved)
+		}
+		return nil
+	})
+
+	remove(t, canaryConfig)
+	waitForDeletion(t, key, &v1.CanaryConfig{})
+	if _, ok := testGate.CanaryConfig(id); ok {
+		t.Fatal("canary config is not deleted from Spinnaker")
+	}
+
+	remove(t, application)
+	waitForDeletion(t, applicationKey, &v1.Application{})
+}
+
+func TestCanaryConfigRefusesForeignConfig(t *testing.T) {
+	requireEnvironment(t)
+
+	testGate.AddCanaryConfig("envtest-foreign", map[string]interface{}{"name": "created-in-deck"})
+	key := client.ObjectKey{Name: "envtest-canary-config-foreign"}
+
+	canaryConfig := &v1.CanaryConfig{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name},
+		Spec:       rawSpec("id: envtest-foreign\n" + canaryConfigManifest("foreign")),
+	}
+	create(t, canaryConfig)
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, canaryConfig); err != nil {
+			return err
+		}
+		for _, condition := range canaryConfig.Status.Conditions {
+			if condition.Type == v1.CanaryConfigConflict && condition.Status == "True" {
+				return nil
+			}
+		}
+		return xerrors.Errorf("conflict is not reported: %+v", canaryConfig.Status.Conditions)
+	})
+	if saved, _ := testGate.CanaryConfig("envtest-foreign"); saved["name"] != "created-in-deck" {
+		t.Fatalf("foreign canary config is overwritten: %v", saved)
+	}
+
+	remove(t, canaryConfig)
+	waitForDeletion(t, key, &v1.CanaryConfig{})
+}
+
+func TestValidateCanaryConfigReturnsGateErrors(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	server.AddMetricsAccount("prometheus", "prometheus")
+	server.AddMetricDescriptor("prometheus", "container_cpu_usage_seconds_total")
+	gateClient := gatewayClient.Gate(context.Background())
+	configJSON := func(metricName string) map[string]interface{} {
+		return map[string]interface{}{
+			"metrics": []interface{}{map[string]interface{}{
+				"name":   "cpu",
+				"query":  map[string]interface{}{"type": "prometheus", "metricName": metricName},
+				"groups": []interface{}{"system"},
+			}},
+			"classifier": map[string]interface{}{"groupWeights": map[string]interface{}{"system": 100}},
+		}
+	}
+
+	server.Fail(http.MethodGet, "/v2/canaries/metadata/metricsService", http.StatusInternalServerError, 0)
+	if problems, err := validateCanaryConfig(gateClient, configJSON("container_cpu_usage_seconds_total")); err == nil {
+		t.Fatalf("expected an error of Gate to be returned, got %v", problems)
+	}
+	server.ClearFailures()
+
+	for metricName, valid := range map[string]bool{
+		"container_cpu_usage_seconds_total": true,
+		"missing":                           false,
+	} {
+		problems, err := validateCanaryConfig(gateClient, configJSON(metricName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (len(problems) == 0) != valid {
+			t.Errorf("expected %s to be valid: %v, got %v", metricName, valid, problems)
+		}
+	}
+}
+
+func canaryConfigManifest(description string) string {
+	return fmt.Sprintf(envtestCanaryConfigSpec, description)
+}
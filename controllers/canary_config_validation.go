@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spinnaker/spin/cmd/gateclient"
+	gate "github.com/spinnaker/spin/gateapi"
+	"golang.org/x/xerrors"
+)
+
+const (
+	invalidCanaryConfigResyncInterval = 60 * time.Second
+
+	metricsStoreType   = "METRICS_STORE"
+	groupWeightsTotal  = 100
+	groupWeightEpsilon = 1e-9
+)
+
+type canaryAccount struct {
+	Name           string
+	Type           string
+	SupportedTypes []string
+}
+
+type canaryConfigSpec struct {
+	Metrics []struct {
+		Name   string
+		Query  map[string]interface{}
+		Groups []string
+	}
+	Classifier struct {
+		GroupWeights map[string]float64
+	}
+}
+
+// validateCanaryConfig returns the problems of the canary config that Kayenta would only report when an analysis runs.
+func validateCanaryConfig(gateClient gateclient.GatewayClient, configJSON map[string]interface{}) ([]string, error) {
+	var spec canaryConfigSpec
+	if err := mapstructure.WeakDecode(configJSON, &spec); err != nil {
+		return []string{fmt.Sprintf("invalid canary config: %s", err)}, nil
+	}
+
+	metricsAccounts, err := getMetricsAccounts(gateClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, metric := range spec.Metrics {
+		queryType, _ := metric.Query["type"].(string)
+		accounts := metricsAccounts[queryType]
+		if len(accounts) == 0 {
+			problems = append(problems, fmt.Sprintf("metric %q: no metrics account of type %q", metric.Name, queryType))
+		} else if metricName, ok := metric.Query["metricName"].(string); ok && metricName != "" {
+			found, err := hasMetricDescriptor(gateClient, accounts[0], metricName)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("metric %q: %q not found in metrics account %q", metric.Name, metricName, accounts[0]))
+			}
+		}
+
+		if len(metric.Groups) == 0 {
+			problems = append(problems, fmt.Sprintf("metric %q: no group", metric.Name))
+		}
+		for _, group := range metric.Groups {
+			if _, ok := spec.Classifier.GroupWeights[group]; !ok {
+				problems = append(problems, fmt.Sprintf("metric %q: group %q is not in classifier.groupWeights", metric.Name, group))
+			}
+		}
+	}
+
+	total := 0.0
+	for _, weight := range spec.Classifier.GroupWeights {
+		total += weight
+	}
+	if total < groupWeightsTotal-groupWeightEpsilon || total > groupWeightsTotal+groupWeightEpsilon {
+		problems = append(problems, fmt.Sprintf("classifier.groupWeights sum to %v, not %d", total, groupWeightsTotal))
+	}
+
+	return problems, nil
+}
+
+// getMetricsAccounts returns the names of the metrics accounts configured in Kayenta by their type.
+func getMetricsAccounts(gateClient gateclient.GatewayClient) (map[string][]string, error) {
+	credentials, _, err := gateClient.V2CanaryControllerApi.ListCredentialsUsingGET(gateClient.Context)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to list canary credentials: %w", err)
+	}
+
+	metricsAccounts := map[string][]string{}
+	for _, raw := range credentials {
+		var account canaryAccount
+		if err := mapstructure.WeakDecode(raw, &account); err != nil {
+			return nil, xerrors.Errorf("failed to decode canary credentials: %w", err)
+		}
+		if containsString(account.SupportedTypes, metricsStoreType) {
+			metricsAccounts[account.Type] = append(metricsAccounts[account.Type], account.Name)
+		}
+	}
+	for _, accounts := range metricsAccounts {
+		sort.Strings(accounts)
+	}
+	return metricsAccounts, nil
+}
+
+// hasMetricDescriptor returns false only when the metrics service lists metadata and none of it matches,
+// since not every metrics store supports listing metadata. Errors of Gate are returned, so that the config is validated
+// again instead of being saved unchecked.
+func hasMetricDescriptor(gateClient gateclient.GatewayClient, metricsAccountName string, metricName string) (bool, error) {
+	descriptors, _, err := gateClient.V2CanaryControllerApi.ListMetricsServiceMetadataUsingGET(
+		gateClient.Context, &gate.V2CanaryControllerApiListMetricsServiceMetadataUsingGETOpts{
+			MetricsAccountName: optional.NewString(metricsAccountName),
+			Filter:             optional.NewString(metricName),
+		})
+	if err != nil {
+		return false, xerrors.Errorf("failed to list metadata of metrics account %s: %w", metricsAccountName, err)
+	}
+	if descriptors == nil {
+		return true, nil
+	}
+	for _, raw := range descriptors {
+		descriptor, ok := raw.(map[string]interface{})
+		if !ok {
+			return true, nil
+		}
+		if name, _ := descriptor["name"].(string); strings.EqualFold(name, metricName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
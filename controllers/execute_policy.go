@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	v1 "spinnaker-dcd-controller/api/v1"
+
+	"github.com/robfig/cron/v3"
+	"golang.org/x/xerrors"
+)
+
+const (
+	executeImmediatelyAnnotation = "spinnaker.kaidotdev.github.io/execute-immediately"
+	executePolicyAnnotation      = "spinnaker.kaidotdev.github.io/execute-policy"
+	executeScheduleAnnotation    = "spinnaker.kaidotdev.github.io/execute-schedule"
+	executeConcurrencyAnnotation = "spinnaker.kaidotdev.github.io/execute-concurrency"
+)
+
+type executePolicy string
+
+const (
+	executePolicyNever      executePolicy = "Never"
+	executePolicyOnCreate   executePolicy = "OnCreate"
+	executePolicyOnChange   executePolicy = "OnChange"
+	executePolicyOnSchedule executePolicy = "OnSchedule"
+)
+
+type executeConcurrency string
+
+const (
+	executeConcurrencySkip   executeConcurrency = "Skip"
+	executeConcurrencyCancel executeConcurrency = "Cancel"
+)
+
+func getExecutePolicy(pipeline *v1.Pipeline) executePolicy {
+	if policy, ok := pipeline.Annotations[executePolicyAnnotation]; ok {
+		return executePolicy(policy)
+	}
+	if pipeline.Annotations[executeImmediatelyAnnotation] == "true" {
+		return executePolicyOnCreate
+	}
+	return executePolicyNever
+}
+
+func getExecuteConcurrency(pipeline *v1.Pipeline) executeConcurrency {
+	if concurrency, ok := pipeline.Annotations[executeConcurrencyAnnotation]; ok {
+		return executeConcurrency(concurrency)
+	}
+	return executeConcurrencySkip
+}
+
+// validateExecutePolicy returns why the execute annotations of pipeline are invalid, or nil when they are valid.
+func validateExecutePolicy(pipeline *v1.Pipeline) error {
+	switch policy := getExecutePolicy(pipeline); policy {
+	case executePolicyNever, executePolicyOnCreate, executePolicyOnChange:
+	case executePolicyOnSchedule:
+		if _, err := getExecuteSchedule(pipeline); err != nil {
+			return err
+		}
+	default:
+		return xerrors.Errorf("unknown %s annotation: %q", executePolicyAnnotation, policy)
+	}
+	switch concurrency := getExecuteConcurrency(pipeline); concurrency {
+	case executeConcurrencySkip, executeConcurrencyCancel:
+	default:
+		return xerrors.Errorf("unknown %s annotation: %q", executeConcurrencyAnnotation, concurrency)
+	}
+	return nil
+}
+
+func getExecuteSchedule(pipeline *v1.Pipeline) (cron.Schedule, error) {
+	spec, ok := pipeline.Annotations[executeScheduleAnnotation]
+	if !ok {
+		return nil, xerrors.Errorf("%s annotation is required by %s policy", executeScheduleAnnotation, executePolicyOnSchedule)
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to parse %s annotation: %w", executeScheduleAnnotation, err)
+	}
+	return schedule, nil
+}
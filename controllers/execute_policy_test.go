@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	v1 "spinnaker-dcd-controller/api/v1"
+	"testing"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateExecutePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		valid       bool
+	}{
+		{name: "default", valid: true},
+		{name: "on change", annotations: map[string]string{executePolicyAnnotation: "OnChange"}, valid: true},
+		{name: "misspelled policy", annotations: map[string]string{executePolicyAnnotation: "Onchange"}},
+		{
+			name:        "on schedule",
+			annotations: map[string]string{executePolicyAnnotation: "OnSchedule", executeScheduleAnnotation: "0 9 * * 1-5"},
+			valid:       true,
+		},
+		{name: "on schedule without schedule", annotations: map[string]string{executePolicyAnnotation: "OnSchedule"}},
+		{
+			name:        "on schedule with invalid schedule",
+			annotations: map[string]string{executePolicyAnnotation: "OnSchedule", executeScheduleAnnotation: "every day"},
+		},
+		{name: "misspelled concurrency", annotations: map[string]string{executeConcurrencyAnnotation: "cancel"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pipeline := &v1.Pipeline{ObjectMeta: metaV1.ObjectMeta{Annotations: test.annotations}}
+			if err := validateExecutePolicy(pipeline); (err == nil) != test.valid {
+				t.Errorf("expected valid to be %v, got %v", test.valid, err)
+			}
+		})
+	}
+
+	r := &PipelineReconciler{}
+	pipeline := &v1.Pipeline{}
+	if r.setExecutePolicyCondition(pipeline, nil) || len(pipeline.Status.Conditions) != 0 {
+		t.Fatal("expected no condition while the annotations have always been valid")
+	}
+	pipeline.Annotations = map[string]string{executePolicyAnnotation: "Onchange"}
+	if !r.setExecutePolicyCondition(pipeline, validateExecutePolicy(pipeline)) {
+		t.Fatal("expected the condition to change")
+	}
+	if r.setExecutePolicyCondition(pipeline, validateExecutePolicy(pipeline)) {
+		t.Fatal("expected the condition not to change again")
+	}
+	if !r.setExecutePolicyCondition(pipeline, nil) || pipeline.Status.Conditions[0].Status != "True" || len(pipeline.Status.Conditions) != 1 {
+		t.Fatalf("unexpected conditions %v", pipeline.Status.Conditions)
+	}
+}
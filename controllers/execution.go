@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"time"
+
+	"github.com/antihax/optional"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spinnaker/spin/cmd/gateclient"
+	gate "github.com/spinnaker/spin/gateapi"
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	executionPollInterval   = 10 * time.Second
+	executionResyncInterval = 60 * time.Second
+	executionTriggerTimeout = 10 * time.Minute
+
+	executionStatusTerminal = "TERMINAL"
+)
+
+type pipelineExecution struct {
+	ID        string
+	Name      string
+	Status    string
+	BuildTime int64
+	StartTime int64
+	EndTime   int64
+	Stages    []pipelineExecutionStage
+}
+
+type pipelineExecutionStage struct {
+	ID        string
+	RefID     string
+	Name      string
+	Type      string
+	Status    string
+	StartTime int64
+	EndTime   int64
+}
+
+func isExecutionRunning(status string) bool {
+	switch status {
+	case "NOT_STARTED", "RUNNING", "PAUSED", "SUSPENDED", "BUFFERED":
+		return true
+	}
+	return false
+}
+
+func decodeExecution(raw interface{}) (*pipelineExecution, error) {
+	var execution pipelineExecution
+	if err := mapstructure.WeakDecode(raw, &execution); err != nil {
+		return nil, xerrors.Errorf("failed to decode execution: %w", err)
+	}
+	return &execution, nil
+}
+
+func getPipelineConfigID(gateClient gateclient.GatewayClient, application string, name string) (string, error) {
+	config, resp, err := gateClient.ApplicationControllerApi.GetPipelineConfigUsingGET(gateClient.Context, application, name)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	id, _ := config["id"].(string)
+	return id, nil
+}
+
+func getLastExecution(gateClient gateclient.GatewayClient, pipelineConfigID string) (*pipelineExecution, error) {
+	executions, _, err := gateClient.ExecutionsControllerApi.GetLatestExecutionsByConfigIdsUsingGET(
+		gateClient.Context, &gate.ExecutionsControllerApiGetLatestExecutionsByConfigIdsUsingGETOpts{
+			PipelineConfigIds: optional.NewString(pipelineConfigID),
+			Limit:             optional.NewInt32(1),
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var last *pipelineExecution
+	for _, raw := range executions {
+		execution, err := decodeExecution(raw)
+		if err != nil {
+			return nil, err
+		}
+		if last == nil || execution.BuildTime > last.BuildTime {
+			last = execution
+		}
+	}
+	return last, nil
+}
+
+func getExecution(gateClient gateclient.GatewayClient, id string) (*pipelineExecution, error) {
+	raw, resp, err := gateClient.PipelineControllerApi.GetPipelineUsingGET(gateClient.Context, id)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeExecution(raw)
+}
+
+func findExecutionByCorrelationID(gateClient gateclient.GatewayClient, application string, pipelineName string, correlationID string) (*pipelineExecution, error) {
+	trigger, err := json.Marshal(map[string]string{"correlationId": correlationID})
+	if err != nil {
+		return nil, err
+	}
+	executions, _, err := gateClient.ExecutionsControllerApi.SearchForPipelineExecutionsByTriggerUsingGET(
+		gateClient.Context, application, &gate.ExecutionsControllerApiSearchForPipelineExecutionsByTriggerUsingGETOpts{
+			PipelineName: optional.NewString(pipelineName),
+			Trigger:      optional.NewString(base64.StdEncoding.EncodeToString(trigger)),
+			Size:         optional.NewInt32(1),
+		})
+	if err != nil {
+		return nil, err
+	}
+	if len(executions) == 0 {
+		return nil, nil
+	}
+	return decodeExecution(executions[0])
+}
+
+func triggerPipeline(gateClient gateclient.GatewayClient, application string, pipelineName string, trigger map[string]interface{}) error {
+	resp, err := gateClient.PipelineControllerApi.InvokePipelineConfigUsingPOST1(
+		gateClient.Context, application, pipelineName, &gate.PipelineControllerApiInvokePipelineConfigUsingPOST1Opts{
+			Trigger: optional.NewInterface(trigger),
+		})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return xerrors.Errorf("encountered an error triggering pipeline %s, status code: %d", pipelineName, resp.StatusCode)
+	}
+	return nil
+}
+
+func cancelExecution(gateClient gateclient.GatewayClient, id string, reason string) error {
+	resp, err := gateClient.PipelineControllerApi.CancelPipelineUsingPUT1(
+		gateClient.Context, id, &gate.PipelineControllerApiCancelPipelineUsingPUT1Opts{
+			Reason: optional.NewString(reason),
+		})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return xerrors.Errorf("encountered an error canceling execution %s, status code: %d", id, resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *pipelineExecution) failedStage() string {
+	for _, stage := range e.Stages {
+		if stage.Status == executionStatusTerminal {
+			return stage.Name
+		}
+	}
+	return ""
+}
+
+func (e *pipelineExecution) toStatus() v1.SpinnakerPipelineExecution {
+	return v1.SpinnakerPipelineExecution{
+		ID:          e.ID,
+		Status:      e.Status,
+		StartTime:   millisToTime(e.StartTime),
+		EndTime:     millisToTime(e.EndTime),
+		FailedStage: e.failedStage(),
+	}
+}
+
+func millisToTime(millis int64) *metaV1.Time {
+	if millis <= 0 {
+		return nil
+	}
+	t := metaV1.NewTime(time.Unix(0, millis*int64(time.Millisecond)).Truncate(time.Second))
+	return &t
+}
+
+func timeEqual(a *metaV1.Time, b *metaV1.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
+
+func executionStatusEqual(a v1.SpinnakerPipelineExecution, b v1.SpinnakerPipelineExecution) bool {
+	return a.ID == b.ID &&
+		a.Status == b.Status &&
+		a.TriggerHash == b.TriggerHash &&
+		a.FailedStage == b.FailedStage &&
+		timeEqual(a.StartTime, b.StartTime) &&
+		timeEqual(a.EndTime, b.EndTime)
+}
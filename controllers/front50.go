@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// front50User is the user that the controller saves service accounts as, which tells the service accounts it has
+// created from the ones created by hand
+const front50User = "spinnaker-dcd-controller"
+
+// Front50Client manages Front50 resources that Gate does not expose for writing, such as service accounts.
+type Front50Client interface {
+	GetServiceAccount(ctx context.Context, name string) (*Front50ServiceAccount, error)
+	SaveServiceAccount(ctx context.Context, serviceAccount Front50ServiceAccount) error
+	DeleteServiceAccount(ctx context.Context, name string) error
+}
+
+// Front50ServiceAccount is the service account stored in Front50 and synced to Fiat
+type Front50ServiceAccount struct {
+	Name           string   `json:"name"`
+	MemberOf       []string `json:"memberOf"`
+	LastModifiedBy string   `json:"lastModifiedBy,omitempty"`
+}
+
+type front50Client struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+func NewFront50Client(endpoint string, httpClient *http.Client) Front50Client {
+	return &front50Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		httpClient: httpClient,
+	}
+}
+
+func (c *front50Client) GetServiceAccount(ctx context.Context, name string) (*Front50ServiceAccount, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/serviceAccounts", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-SPINNAKER-USER", front50User)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("encountered an error listing service accounts, status code: %d", resp.StatusCode)
+	}
+
+	var serviceAccounts []Front50ServiceAccount
+	if err := json.NewDecoder(resp.Body).Decode(&serviceAccounts); err != nil {
+		return nil, xerrors.Errorf("failed to decode service accounts: %w", err)
+	}
+	for _, serviceAccount := range serviceAccounts {
+		if strings.EqualFold(serviceAccount.Name, name) {
+			return &serviceAccount, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *front50Client) SaveServiceAccount(ctx context.Context, serviceAccount Front50ServiceAccount) error {
+	body, err := json.Marshal(serviceAccount)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/serviceAccounts", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SPINNAKER-USER", front50User)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return xerrors.Errorf("encountered an error saving service account %s, status code: %d", serviceAccount.Name, resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *front50Client) DeleteServiceAccount(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/serviceAccounts/%s", c.endpoint, url.PathEscape(name)), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-SPINNAKER-USER", front50User)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return xerrors.Errorf("encountered an error deleting service account %s, status code: %d", name, resp.StatusCode)
+	}
+	return nil
+}
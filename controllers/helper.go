@@ -1,7 +1,59 @@
 package controllers
 
+import (
+	"context"
+	"spinnaker-dcd-controller/internal/gateway"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
 const myFinalizerName = "spinnaker.finalizers.kaidotdev.github.io"
 
+// reconcileTimeout bounds all calls of one reconciliation, so that a hung Spinnaker cannot hold a worker forever.
+const reconcileTimeout = 5 * time.Minute
+
+func newReconcileContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), reconcileTimeout)
+}
+
+// requeueOnCancel requeues reconciliations whose calls were cancelled by the deadline or by shutdown, instead of
+// reporting them as failures, since nothing was decided and the same operation can simply be run again.
+type requeueOnCancel struct {
+	reconcile.Reconciler
+	log logr.Logger
+}
+
+func newRequeueOnCancel(reconciler reconcile.Reconciler, log logr.Logger) reconcile.Reconciler {
+	return &requeueOnCancel{Reconciler: reconciler, log: log}
+}
+
+func (r *requeueOnCancel) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	result, err := r.Reconciler.Reconcile(req)
+	if err != nil && isCanceled(err) {
+		r.log.Info("requeue cancelled reconciliation", "request", req.NamespacedName, "reason", err.Error())
+		return ctrl.Result{Requeue: true}, nil
+	}
+	return result, err
+}
+
+// withPriority lets deletions and updates of resources already in Spinnaker take requests to Gate ahead of creations,
+// so that a flood of new resources cannot starve them.
+func withPriority(ctx context.Context, object metaV1.Object, live bool) context.Context {
+	if live || !object.GetDeletionTimestamp().IsZero() {
+		return gateway.WithPriority(ctx, gateway.PriorityHigh)
+	}
+	return gateway.WithPriority(ctx, gateway.PriorityLow)
+}
+
+func isCanceled(err error) bool {
+	return gateway.IsRetryable(err) || xerrors.Is(err, context.Canceled) || xerrors.Is(err, context.DeadlineExceeded)
+}
+
 func containsString(slice []string, s string) bool {
 	for _, item := range slice {
 		if item == s {
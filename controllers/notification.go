@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	v1 "spinnaker-dcd-controller/api/v1"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// notificationsFor returns the Notifications whose target selects object of kind, ordered by name, so that a
+// notification of a later one wins over the one of an earlier one with the same type and address.
+func notificationsFor(ctx context.Context, c client.Reader, kind v1.NotificationTargetKind, object metaV1.Object) ([]v1.Notification, error) {
+	notificationList := &v1.NotificationList{}
+	if err := c.List(ctx, notificationList); err != nil {
+		return nil, err
+	}
+	var notifications []v1.Notification
+	for _, notification := range notificationList.Items {
+		if targets(&notification, kind, object) {
+			notifications = append(notifications, notification)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].Name < notifications[j].Name
+	})
+	return notifications, nil
+}
+
+// targets returns true when the target of notification selects object of kind. An invalid selector selects nothing.
+func targets(notification *v1.Notification, kind v1.NotificationTargetKind, object metaV1.Object) bool {
+	target := notification.Spec.Target
+	if target.Kind != kind {
+		return false
+	}
+	if target.Name != "" {
+		return target.Name == object.GetName()
+	}
+	if target.Selector == nil {
+		return true
+	}
+	selector, err := metaV1.LabelSelectorAsSelector(target.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(object.GetLabels()))
+}
+
+// notificationHash returns the hash of notifications, which is kept apart from the hash of the spec of the target, so
+// that a change of the Notifications saves the target again without counting as a change of its spec. It is empty
+// without notifications, so that existing resources are not saved again.
+func notificationHash(notifications []v1.Notification) string {
+	if len(notifications) == 0 {
+		return ""
+	}
+	hash := ""
+	for _, notification := range notifications {
+		data, _ := json.Marshal(notification.Spec.Notifications)
+		hash += notification.Name + "=" + string(data) + "\n"
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(hash)))
+}
+
+// notificationKey identifies a notification in Spinnaker, where a destination has one notification per level
+func notificationKey(notificationType string, address string) string {
+	return notificationType + ":" + address
+}
+
+// notificationEntries returns the notifications of notifications in the format of Spinnaker at level, e.g. pipeline,
+// with their keys in order.
+func notificationEntries(notifications []v1.Notification, level string) ([]string, map[string]map[string]interface{}) {
+	var keys []string
+	entries := map[string]map[string]interface{}{}
+	for _, notification := range notifications {
+		for _, config := range notification.Spec.Notifications {
+			var entry map[string]interface{}
+			data, _ := json.Marshal(config)
+			_ = json.Unmarshal(data, &entry)
+			entry["level"] = level
+
+			key := notificationKey(config.Type, config.Address)
+			if _, ok := entries[key]; !ok {
+				keys = append(keys, key)
+			}
+			entries[key] = entry
+		}
+	}
+	return keys, entries
+}
+
+// mergeNotifications returns existing without the notifications of the keys of removed and of entries, followed by
+// entries in the order of keys.
+func mergeNotifications(existing []interface{}, removed []string, keys []string, entries map[string]map[string]interface{}) []interface{} {
+	drop := map[string]bool{}
+	for _, key := range removed {
+		drop[key] = true
+	}
+	for _, key := range keys {
+		drop[key] = true
+	}
+	merged := []interface{}{}
+	for _, item := range existing {
+		if entry, ok := item.(map[string]interface{}); ok {
+			notificationType, _ := entry["type"].(string)
+			address, _ := entry["address"].(string)
+			if drop[notificationKey(notificationType, address)] {
+				continue
+			}
+		}
+		merged = append(merged, item)
+	}
+	for _, key := range keys {
+		merged = append(merged, entries[key])
+	}
+	return merged
+}
+
+// mapNotification returns a function that enqueues the objects of list of kind that a Notification targets, including
+// the ones it targeted before an update or a deletion, since the event of each version is mapped.
+func mapNotification(c client.Reader, log logr.Logger, kind v1.NotificationTargetKind, list runtime.Object) handler.ToRequestsFunc {
+	return func(object handler.MapObject) []ctrl.Request {
+		notification, ok := object.Object.(*v1.Notification)
+		if !ok || notification.Spec.Target.Kind != kind {
+			return nil
+		}
+		if notification.Spec.Target.Name != "" {
+			return []ctrl.Request{{NamespacedName: types.NamespacedName{Name: notification.Spec.Target.Name}}}
+		}
+		objects := list.DeepCopyObject()
+		if err := c.List(context.Background(), objects); err != nil {
+			log.Error(err, "failed to list targets of notification", "notification", notification.Name)
+			return nil
+		}
+		items, err := meta.ExtractList(objects)
+		if err != nil {
+			log.Error(err, "failed to list targets of notification", "notification", notification.Name)
+			return nil
+		}
+		var requests []ctrl.Request
+		for _, item := range items {
+			target, err := meta.Accessor(item)
+			if err != nil || !targets(notification, kind, target) {
+				continue
+			}
+			requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: target.GetName()}})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/fakegate"
+	"spinnaker-dcd-controller/internal/gateway"
+	"testing"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMergeNotifications(t *testing.T) {
+	notifications := []v1.Notification{
+		{
+			ObjectMeta: metaV1.ObjectMeta{Name: "a"},
+			Spec: v1.NotificationSpec{Notifications: []v1.NotificationConfig{
+				{Type: "slack", Address: "#deploy", When: []string{"pipeline.complete"}},
+			}},
+		},
+		{
+			ObjectMeta: metaV1.ObjectMeta{Name: "b"},
+			Spec: v1.NotificationSpec{Notifications: []v1.NotificationConfig{
+				{Type: "slack", Address: "#deploy", When: []string{"pipeline.failed"}},
+				{Type: "pagerDuty", Address: "service-key", When: []string{"pipeline.failed"}},
+			}},
+		},
+	}
+	keys, entries := notificationEntries(notifications, "pipeline")
+	if !reflect.DeepEqual(keys, []string{"slack:#deploy", "pagerDuty:service-key"}) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+	if when := entries["slack:#deploy"]["when"]; !reflect.DeepEqual(when, []interface{}{"pipeline.failed"}) {
+		t.Errorf("expected the later Notification to win, got %v", when)
+	}
+
+	existing := []interface{}{
+		map[string]interface{}{"type": "email", "address": "team@example.com"},
+		map[string]interface{}{"type": "slack", "address": "#deploy", "when": []interface{}{"pipeline.starting"}},
+		map[string]interface{}{"type": "slack", "address": "#stale"},
+	}
+	merged := mergeNotifications(existing, []string{"slack:#stale"}, keys, entries)
+	var got []string
+	for _, item := range merged {
+		entry := item.(map[string]interface{})
+		got = append(got, notificationKey(entry["type"].(string), entry["address"].(string)))
+		if entry["type"] != "email" && entry["level"] != "pipeline" {
+			t.Errorf("expected level of %v to be set", entry)
+		}
+	}
+	if !reflect.DeepEqual(got, []string{"email:team@example.com", "slack:#deploy", "pagerDuty:service-key"}) {
+		t.Errorf("unexpected notifications %v", got)
+	}
+}
+
+func TestSetNotifications(t *testing.T) {
+	body := map[string]interface{}{
+		"config": map[string]interface{}{
+			"configuration": map[string]interface{}{
+				"notifications": []interface{}{map[string]interface{}{"type": "email", "address": "team@example.com"}},
+			},
+		},
+	}
+	setNotifications(body, []v1.Notification{{
+		Spec: v1.NotificationSpec{Notifications: []v1.NotificationConfig{
+			{Type: "slack", Address: "#deploy", When: []string{"pipeline.failed"}},
+		}},
+	}})
+	if notifications := body["notifications"].([]interface{}); len(notifications) != 1 {
+		t.Errorf("unexpected notifications %v", notifications)
+	}
+	configuration := body["config"].(map[string]interface{})["configuration"].(map[string]interface{})
+	if notifications := configuration["notifications"].([]interface{}); len(notifications) != 2 {
+		t.Errorf("expected notifications of the template configuration to be merged, got %v", notifications)
+	}
+}
+
+func TestSaveApplicationNotifications(t *testing.T) {
+	server := fakegate.NewServer()
+	defer server.Close()
+	gatewayClient, err := gateway.New(server.URL(), gateway.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &ApplicationReconciler{Gateway: gatewayClient}
+	application := &v1.Application{ObjectMeta: metaV1.ObjectMeta{Name: "sample"}}
+	application.Status.ManagedNotifications = []string{"slack:#stale"}
+	server.AddApplicationNotifications("sample", map[string]interface{}{
+		"application":  "sample",
+		"lastModified": 1,
+		"slack": []interface{}{
+			map[string]interface{}{"type": "slack", "address": "#stale", "level": "application"},
+			map[string]interface{}{"type": "slack", "address": "#team", "level": "application"},
+		},
+	})
+
+	keys, err := r.saveNotifications(context.Background(), application, "sample", []v1.Notification{{
+		Spec: v1.NotificationSpec{Notifications: []v1.NotificationConfig{
+			{Type: "email", Address: "sre@example.com", When: []string{"pipeline.failed"}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"email:sre@example.com"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	saved, _ := server.ApplicationNotifications("sample")
+	if _, ok := saved["lastModified"]; ok {
+		t.Error("expected server managed fields to be left out")
+	}
+	if slack := saved["slack"].([]interface{}); len(slack) != 1 || slack[0].(map[string]interface{})["address"] != "#team" {
+		t.Errorf("expected only the notification set by hand to be kept, got %v", slack)
+	}
+	if email := saved["email"].([]interface{}); len(email) != 1 || email[0].(map[string]interface{})["level"] != "application" {
+		t.Errorf("unexpected email notifications %v", email)
+	}
+}
+
+func TestApplicationNotificationLifecycle(t *testing.T) {
+	requireEnvironment(t)
+
+	key := client.ObjectKey{Name: "envtest-application-notification"}
+	testGate.AddApplicationNotifications(key.Name, map[string]interface{}{
+		"application": key.Name,
+		"email":       []interface{}{map[string]interface{}{"type": "email", "address": "team@example.com", "level": "application"}},
+	})
+	application := &v1.Application{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name, Labels: map[string]string{"alerting": "standard"}},
+		Spec:       rawSpec("email: notification@example.com"),
+	}
+	create(t, application)
+	notification := &v1.Notification{
+		ObjectMeta: metaV1.ObjectMeta{Name: "envtest-standard-alerting"},
+		Spec: v1.NotificationSpec{
+			Target: v1.NotificationTarget{
+				Kind:     v1.NotificationTargetApplication,
+				Selector: &metaV1.LabelSelector{MatchLabels: map[string]string{"alerting": "standard"}},
+			},
+			Notifications: []v1.NotificationConfig{
+				{Type: "slack", Address: "#alerts", When: []string{"pipeline.failed"}},
+			},
+		},
+	}
+	create(t, notification)
+
+	addresses := func(notificationType string) []string {
+		saved, _ := testGate.ApplicationNotifications(key.Name)
+		group, _ := saved[notificationType].([]interface{})
+		var addresses []string
+		for _, item := range group {
+			addresses = append(addresses, item.(map[string]interface{})["address"].(string))
+		}
+		return addresses
+	}
+	eventually(t, func() error {
+		if got := addresses("slack"); !reflect.DeepEqual(got, []string{"#alerts"}) {
+			return xerrors.Errorf("unexpected slack notifications: %v", got)
+		}
+		return nil
+	})
+	if got := addresses("email"); !reflect.DeepEqual(got, []string{"team@example.com"}) {
+		t.Fatalf("expected notifications set by hand to be kept, got %v", got)
+	}
+
+	remove(t, notification)
+	eventually(t, func() error {
+		if got := addresses("slack"); len(got) != 0 {
+			return xerrors.Errorf("unexpected slack notifications: %v", got)
+		}
+		return nil
+	})
+	if got := addresses("email"); !reflect.DeepEqual(got, []string{"team@example.com"}) {
+		t.Fatalf("expected notifications set by hand to be kept, got %v", got)
+	}
+
+	remove(t, application)
+	waitForDeletion(t, key, &v1.Application{})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	pausedAnnotation = "spinnaker.kaidotdev.github.io/paused"
+	// pauseConfigMapKey is the key of the ConfigMap that pauses all resources when it is "true"
+	pauseConfigMapKey = "paused"
+	// pausedResyncInterval is how often paused resources check whether the pause has been lifted, since the
+	// cluster-wide pause is not watched.
+	pausedResyncInterval = 30 * time.Second
+)
+
+// Pause pauses reconciliation of all resources, by the flag or by a ConfigMap that can be edited during an incident.
+type Pause struct {
+	// Paused pauses all resources regardless of the ConfigMap
+	Paused bool
+	// Reader reads the ConfigMap, usually through a cache of its namespace
+	Reader client.Reader
+	// ConfigMap is the ConfigMap whose "paused" key pauses all resources, or empty to disable it
+	ConfigMap types.NamespacedName
+}
+
+type pausable interface {
+	runtime.Object
+	metaV1.Object
+	SetPaused(paused bool, message string) bool
+}
+
+// reason returns why reconciliation of object is paused, or an empty string when it is not.
+func (p *Pause) reason(ctx context.Context, object metaV1.Object) (string, error) {
+	if object.GetAnnotations()[pausedAnnotation] == "true" {
+		return fmt.Sprintf("Paused by annotation %s", pausedAnnotation), nil
+	}
+	if p == nil {
+		return "", nil
+	}
+	if p.Paused {
+		return "Paused cluster-wide by --paused", nil
+	}
+	if p.Reader == nil || p.ConfigMap.Name == "" {
+		return "", nil
+	}
+	configMap := &coreV1.ConfigMap{}
+	if err := p.Reader.Get(ctx, p.ConfigMap, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if configMap.Data[pauseConfigMapKey] == "true" {
+		return fmt.Sprintf("Paused cluster-wide by ConfigMap %s", p.ConfigMap), nil
+	}
+	return "", nil
+}
+
+// check records the Paused condition of object and returns true while it is paused, in which case the caller must
+// return the result without calling Gate. Deleted objects keep their finalizer, so that deletions are queued until the
+// pause is lifted.
+func (p *Pause) check(ctx context.Context, c client.Client, recorder record.EventRecorder, logger logr.Logger, object pausable) (bool, ctrl.Result, error) {
+	reason, err := p.reason(ctx, object)
+	if err != nil {
+		return true, ctrl.Result{}, err
+	}
+	if !object.SetPaused(reason != "", reason) {
+		if reason != "" {
+			return true, ctrl.Result{RequeueAfter: pausedResyncInterval}, nil
+		}
+		return false, ctrl.Result{}, nil
+	}
+
+	if reason != "" {
+		recorder.Event(object, coreV1.EventTypeNormal, "Paused", reason)
+		logger.V(1).Info("pause", "reason", reason)
+	} else {
+		recorder.Event(object, coreV1.EventTypeNormal, "Resumed", "Resumed reconciliation")
+		logger.V(1).Info("resume")
+	}
+	if err := c.Update(ctx, object); err != nil {
+		return true, ctrl.Result{}, err
+	}
+	if reason != "" {
+		return true, ctrl.Result{RequeueAfter: pausedResyncInterval}, nil
+	}
+	return false, ctrl.Result{}, nil
+}
@@ -5,7 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"path"
 	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
 	"strings"
 	"time"
 
@@ -14,27 +19,44 @@ import (
 	"github.com/mitchellh/mapstructure"
 
 	"github.com/spinnaker/roer/spinnaker"
+	gate "github.com/spinnaker/spin/gateapi"
+	"golang.org/x/xerrors"
 
 	"github.com/go-logr/logr"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type PipelineReconciler struct {
 	client.Client
-	Log             logr.Logger
-	Scheme          *runtime.Scheme
-	Recorder        record.EventRecorder
-	SpinnakerClient spinnaker.Client
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Gateway                 gateway.Client
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Revisions               *Revisions
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
 }
 
-func (r *PipelineReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *PipelineReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
 	pipeline := &v1.Pipeline{}
-	ctx := context.Background()
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "Pipeline", req)
+	defer func() { endReconcileSpan(span, pipeline, err) }()
 	logger := r.Log.WithValues("pipeline", req.NamespacedName)
 	if err := r.Get(ctx, req.NamespacedName, pipeline); err != nil {
 		if errors.IsNotFound(err) {
@@ -42,11 +64,39 @@ func (r *PipelineReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 		return ctrl.Result{}, err
 	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, pipeline) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, pipeline); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, pipeline, containsString(pipeline.ObjectMeta.Finalizers, myFinalizerName))
+	if rolledBack, err := r.rollback(ctx, pipeline); rolledBack {
+		return ctrl.Result{}, err
+	}
 
 	if pipeline.ObjectMeta.DeletionTimestamp.IsZero() {
-		hash := fmt.Sprintf("%x", sha256.Sum256(pipeline.Spec.Raw))
+		// Invalid execute annotations are reported instead of being guessed, and the pipeline is not executed until
+		// they are fixed.
+		policyErr := validateExecutePolicy(pipeline)
+		if r.setExecutePolicyCondition(pipeline, policyErr) {
+			if policyErr != nil {
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeWarning, "InvalidExecutePolicy", "Invalid execute policy: %v", policyErr)
+			}
+			if err := r.Update(ctx, pipeline); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		hash := r.hash(pipeline)
 		oldHash := pipeline.Status.Hash
-		if oldHash == "" { // cannot update pipeline
+		controlHash := r.controlHash(pipeline)
+		notifications, err := notificationsFor(ctx, r.Client, v1.NotificationTargetPipeline, pipeline)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		notificationHash := notificationHash(notifications)
+		if hash != oldHash || controlHash != pipeline.Status.ControlHash || notificationHash != pipeline.Status.NotificationHash {
 			pipelineConfig, err := r.buildPipelineConfig(pipeline)
 			if err != nil {
 				return ctrl.Result{}, err
@@ -57,7 +107,7 @@ func (r *PipelineReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			}
 			if !created {
 				logger.V(1).Info("wait for application to be created")
-				return ctrl.Result{RequeueAfter: dependencyWaitInterval}, nil
+				return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
 			}
 			published, err := r.isTemplatePublished(ctx, pipelineConfig)
 			if err != nil {
@@ -65,49 +115,135 @@ func (r *PipelineReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			}
 			if !published {
 				logger.V(1).Info("wait for pipeline template to be published")
-				return ctrl.Result{RequeueAfter: dependencyWaitInterval}, nil
+				return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+			}
+			runAsUser, err := r.resolveRunAsUser(ctx, pipeline)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if pipeline.Annotations[runAsUserAnnotation] != "" && runAsUser == "" {
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeWarning, "ServiceAccountNotFound", "Service account %q does not exist", pipeline.Annotations[runAsUserAnnotation])
+				logger.V(1).Info("wait for service account to be created")
+				return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+			}
+			oldApplicationName := pipeline.Status.SpinnakerResource.ApplicationName
+			oldName := pipeline.Status.SpinnakerResource.ID
+			moved := oldHash != "" && oldApplicationName != pipelineConfig.Application
+			renamed := oldHash != "" && !moved && oldName != pipelineConfig.Name
+			if oldHash != "" && pipelineConfig.ID == "" {
+				// Saving without ID creates another pipeline, so that reuse the ID of the saved one
+				existing, err := r.Gateway.Roer(ctx).GetPipelineConfig(pipelineConfig.Application, pipelineConfig.Name)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if existing != nil && (renamed || moved) {
+					conflict, err := r.findConflict(ctx, oldApplicationName, oldName, pipelineConfig)
+					if err != nil {
+						return ctrl.Result{}, err
+					}
+					if conflict != "" {
+						r.setCondition(pipeline, v1.PipelineConflict, "True", conflict)
+						r.Recorder.Eventf(pipeline, coreV1.EventTypeWarning, "ConflictingPipeline", conflict)
+						if err := r.Update(ctx, pipeline); err != nil {
+							return ctrl.Result{}, err
+						}
+						return ctrl.Result{RequeueAfter: r.Settings.get().InvalidResyncInterval}, nil
+					}
+				}
+				if existing == nil && renamed {
+					// Renaming keeps the ID, so that the execution history follows the pipeline
+					if err := r.renamePipeline(ctx, pipeline, pipelineConfig.Application, oldName, pipelineConfig.Name); err != nil {
+						return ctrl.Result{}, err
+					}
+					r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulRenamed", "Renamed pipeline %q to %q", oldName, pipelineConfig.Name)
+					existing, err = r.Gateway.Roer(ctx).GetPipelineConfig(pipelineConfig.Application, pipelineConfig.Name)
+					if err != nil {
+						return ctrl.Result{}, err
+					}
+				}
+				if existing != nil {
+					pipelineConfig.ID = existing.ID
+				}
+			}
+			r.setCondition(pipeline, v1.PipelineConflict, "False", "")
+			if moved {
+				// Executions belong to the application, so that the history cannot follow the pipeline to another one.
+				// The old pipeline is deleted before saving, so that a retry of a partial move never takes the saved one
+				// for a conflicting pipeline.
+				if err := r.deletePipeline(ctx, pipeline, oldApplicationName, oldName); err != nil {
+					return ctrl.Result{}, err
+				}
 			}
-			if err := r.SpinnakerClient.SavePipelineConfig(pipelineConfig); err != nil {
+			if err := r.savePipelineConfig(ctx, pipeline, pipelineConfig, runAsUser, notifications); err != nil {
 				return ctrl.Result{}, err
 			}
+			if moved {
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulMoved", "Moved pipeline %q from application %q to %q", pipelineConfig.Name, oldApplicationName, pipelineConfig.Application)
+				pipeline.Status.LastExecution = v1.SpinnakerPipelineExecution{}
+			}
 			pipeline.Status.SpinnakerResource.ApplicationName = pipelineConfig.Application
 			pipeline.Status.SpinnakerResource.ID = pipelineConfig.Name
 			pipeline.Status.Hash = hash
+			pipeline.Status.ControlHash = controlHash
+			pipeline.Status.NotificationHash = notificationHash
+			// A change of the control annotations or the Notifications alone is no reason to execute the pipeline, e.g.
+			// locking it for a change freeze.
+			policy := getExecutePolicy(pipeline)
+			if hash != oldHash && policyErr == nil && (policy == executePolicyOnChange || (policy == executePolicyOnCreate && oldHash == "")) {
+				// The execution is recorded as pending along with the hash, so that it is retried until triggered
+				pipeline.Status.PendingTriggerHash = hash
+			}
 			if !containsString(pipeline.ObjectMeta.Finalizers, myFinalizerName) {
 				pipeline.ObjectMeta.Finalizers = append(pipeline.ObjectMeta.Finalizers, myFinalizerName)
 			}
-			pipeline.Status.Conditions = append(pipeline.Status.Conditions, v1.PipelineCondition{
-				Type:   v1.PipelineCreationComplete,
-				Status: "True",
-			})
-			r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulCreated", "Created pipeline: %q", req.Name)
-			logger.V(1).Info("create", "pipeline", pipeline)
+			if oldHash == "" {
+				r.setCondition(pipeline, v1.PipelineCreationComplete, "True", "")
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulCreated", "Created pipeline: %q", req.Name)
+				logger.V(1).Info("create", "pipeline", pipeline)
+			} else {
+				r.setCondition(pipeline, v1.PipelineUpdateComplete, "True", "")
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulUpdated", "Updated pipeline: %q", req.Name)
+				logger.V(1).Info("update", "pipeline", pipeline)
+			}
 			if err := r.Update(ctx, pipeline); err != nil {
 				return ctrl.Result{}, err
 			}
+		}
+		if pipeline.Status.PendingTriggerHash != "" && policyErr == nil {
+			if err := r.execute(ctx, pipeline, pipeline.Status.PendingTriggerHash); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 
-			if pipeline.Annotations != nil && pipeline.Annotations["spinnaker.kaidotdev.github.io/execute-immediately"] == "true" {
-				if _, err := r.SpinnakerClient.ExecPipeline(pipeline.Status.SpinnakerResource.ApplicationName, pipeline.Status.SpinnakerResource.ID); err != nil {
-					r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "ExecuteFailed", "Failed to execute pipeline: %q", req.Name)
-					return ctrl.Result{}, nil
-				}
-				r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulExecuted", "Executed pipeline: %q", req.Name)
+		result, err := r.trackExecution(ctx, pipeline)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if policyErr == nil && getExecutePolicy(pipeline) == executePolicyOnSchedule {
+			next, err := r.executeOnSchedule(ctx, pipeline)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if result.RequeueAfter == 0 || next < result.RequeueAfter {
+				result.RequeueAfter = next
 			}
 		}
+		return result, nil
 	} else {
 		if containsString(pipeline.ObjectMeta.Finalizers, myFinalizerName) {
-			if err := r.SpinnakerClient.DeletePipeline(
-				pipeline.Status.SpinnakerResource.ApplicationName,
-				pipeline.Status.SpinnakerResource.ID,
-			); err != nil {
-				return ctrl.Result{}, err
+			if !r.Settings.retain(r.Recorder, pipeline) {
+				if err := r.deletePipeline(
+					ctx,
+					pipeline,
+					pipeline.Status.SpinnakerResource.ApplicationName,
+					pipeline.Status.SpinnakerResource.ID,
+				); err != nil {
+					return ctrl.Result{}, err
+				}
+				r.setCondition(pipeline, v1.PipelineDeletionComplete, "True", "")
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted pipeline: %q", req.Name)
+				logger.V(1).Info("delete", "pipeline", pipeline)
 			}
-			pipeline.Status.Conditions = append(pipeline.Status.Conditions, v1.PipelineCondition{
-				Type:   v1.PipelineDeletionComplete,
-				Status: "True",
-			})
-			r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted pipeline: %q", req.Name)
-			logger.V(1).Info("delete", "pipeline", pipeline)
 
 			pipeline.ObjectMeta.Finalizers = removeString(pipeline.ObjectMeta.Finalizers, myFinalizerName)
 			if err := r.Update(ctx, pipeline); err != nil {
@@ -123,8 +259,434 @@ const (
 	templateSourcePrefix    = "spinnaker://"
 	dependencyWaitInterval  = 10 * time.Second
 	pipelineTemplateIDField = "spec.id"
+
+	disabledAnnotation        = "spinnaker.kaidotdev.github.io/disabled"
+	lockedAnnotation          = "spinnaker.kaidotdev.github.io/locked"
+	allowUnlockUIAnnotation   = "spinnaker.kaidotdev.github.io/allow-unlock-ui"
+	lockDescriptionAnnotation = "spinnaker.kaidotdev.github.io/lock-description"
+	runAsUserAnnotation       = "spinnaker.kaidotdev.github.io/run-as-user"
+
+	// maxExecutionResyncBackoff caps how many times ExecutionResyncInterval an idle pipeline waits between checks
+	maxExecutionResyncBackoff = 32
 )
 
+var pipelineControlAnnotations = []string{
+	disabledAnnotation,
+	lockedAnnotation,
+	allowUnlockUIAnnotation,
+	lockDescriptionAnnotation,
+	runAsUserAnnotation,
+}
+
+func (r *PipelineReconciler) hash(pipeline *v1.Pipeline) string {
+	return fmt.Sprintf("%x", sha256.Sum256(pipeline.Spec.Raw))
+}
+
+// controlHash returns the hash of the control annotations, which is kept apart from the hash of the spec, so that a
+// change of them saves the pipeline again without counting as a change of its spec. It is empty without them.
+func (r *PipelineReconciler) controlHash(pipeline *v1.Pipeline) string {
+	controls := ""
+	for _, annotation := range pipelineControlAnnotations {
+		if value, ok := pipeline.Annotations[annotation]; ok {
+			controls += annotation + "=" + value + "\n"
+		}
+	}
+	if controls == "" {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(controls)))
+}
+
+// findConflict describes why the new name of a renamed or moved pipeline belongs to another pipeline, or returns an
+// empty string. The pipeline of the new name is another one as long as the old one exists, or else it was saved by
+// a previous attempt whose status was not recorded.
+func (r *PipelineReconciler) findConflict(ctx context.Context, oldApplicationName string, oldName string, pipelineConfig spinnaker.PipelineConfig) (string, error) {
+	old, err := r.Gateway.Roer(ctx).GetPipelineConfig(oldApplicationName, oldName)
+	if err != nil {
+		return "", err
+	}
+	if old == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("pipeline %s already exists in application %s", pipelineConfig.Name, pipelineConfig.Application), nil
+}
+
+func (r *PipelineReconciler) setCondition(pipeline *v1.Pipeline, conditionType v1.PipelineConditionType, status string, message string) {
+	for i, condition := range pipeline.Status.Conditions {
+		if condition.Type == conditionType {
+			pipeline.Status.Conditions[i].Status = status
+			pipeline.Status.Conditions[i].Message = message
+			return
+		}
+	}
+	pipeline.Status.Conditions = append(pipeline.Status.Conditions, v1.PipelineCondition{
+		Type:    conditionType,
+		Status:  status,
+		Message: message,
+	})
+}
+
+// setExecutePolicyCondition records whether the execute annotations are valid and returns true when the condition
+// changes. The condition is left out while they have always been valid.
+func (r *PipelineReconciler) setExecutePolicyCondition(pipeline *v1.Pipeline, policyErr error) bool {
+	status, message := "True", ""
+	if policyErr != nil {
+		status, message = "False", policyErr.Error()
+	}
+	for _, condition := range pipeline.Status.Conditions {
+		if condition.Type == v1.PipelineExecutePolicyValid {
+			if condition.Status == status && condition.Message == message {
+				return false
+			}
+			r.setCondition(pipeline, v1.PipelineExecutePolicyValid, status, message)
+			return true
+		}
+	}
+	if policyErr == nil {
+		return false
+	}
+	r.setCondition(pipeline, v1.PipelineExecutePolicyValid, status, message)
+	return true
+}
+
+func (r *PipelineReconciler) savePipelineConfig(ctx context.Context, pipeline *v1.Pipeline, pipelineConfig spinnaker.PipelineConfig, runAsUser string, notifications []v1.Notification) error {
+	var body map[string]interface{}
+	data, err := json.Marshal(pipelineConfig)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+
+	body["disabled"] = pipeline.Annotations[disabledAnnotation] == "true"
+	if pipeline.Annotations[lockedAnnotation] == "true" {
+		body["locked"] = spinnaker.PipelineLock{
+			UI:            true,
+			AllowUnlockUI: pipeline.Annotations[allowUnlockUIAnnotation] == "true",
+			Description:   pipeline.Annotations[lockDescriptionAnnotation],
+		}
+	}
+	if runAsUser != "" {
+		setRunAsUser(body, runAsUser)
+	}
+	if len(notifications) > 0 {
+		setNotifications(body, notifications)
+	}
+	return r.savePipelineBody(ctx, pipeline, body)
+}
+
+// savePipelineBody saves body, which is either built from the spec or the payload of a revision.
+func (r *PipelineReconciler) savePipelineBody(ctx context.Context, pipeline *v1.Pipeline, body map[string]interface{}) error {
+	applicationName, _ := body["application"].(string)
+	name, _ := body["name"].(string)
+	id, _ := body["id"].(string)
+
+	gateClient := r.Gateway.Gate(ctx)
+	change := audit.Change{
+		Operation: audit.OperationUpdate,
+		Object:    "pipeline",
+		ID:        path.Join(applicationName, name),
+		Before: auditBefore(r.Audit, func() (interface{}, error) {
+			return getSpinnakerPipeline(gateClient, applicationName, name)
+		}),
+		After: body,
+	}
+	if id == "" {
+		change.Operation = audit.OperationCreate
+	}
+	resp, err := gateClient.PipelineControllerApi.SavePipelineUsingPOST(gateClient.Context, body, &gate.PipelineControllerApiSavePipelineUsingPOSTOpts{})
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = xerrors.Errorf("encountered an error saving pipeline %s, status code: %d", name, resp.StatusCode)
+	}
+	r.Audit.Log(pipeline, change, err)
+	if err != nil {
+		return err
+	}
+	if err := r.Revisions.record(ctx, pipeline, body, ""); err != nil {
+		r.Log.Error(err, "unable to record revision", "pipeline", pipeline.Name)
+	}
+	return nil
+}
+
+func (r *PipelineReconciler) rollback(ctx context.Context, pipeline *v1.Pipeline) (bool, error) {
+	return r.Revisions.rollback(ctx, r.Client, r.Recorder, pipeline, func(ctx context.Context, payload json.RawMessage) error {
+		var body map[string]interface{}
+		if err := json.Unmarshal(payload, &body); err != nil {
+			return err
+		}
+		if id, _ := body["id"].(string); id == "" {
+			// The revision was recorded when the pipeline was created, so that reuse the ID Spinnaker gave it
+			applicationName, _ := body["application"].(string)
+			name, _ := body["name"].(string)
+			existing, err := r.Gateway.Roer(ctx).GetPipelineConfig(applicationName, name)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				body["id"] = existing.ID
+			}
+		}
+		return r.savePipelineBody(ctx, pipeline, body)
+	})
+}
+
+// deletePipeline deletes the pipeline, and succeeds when it is already deleted, e.g. by a move whose status was not
+// recorded.
+func (r *PipelineReconciler) deletePipeline(ctx context.Context, pipeline *v1.Pipeline, applicationName string, pipelineName string) error {
+	gateClient := r.Gateway.Gate(ctx)
+	change := audit.Change{
+		Operation: audit.OperationDelete,
+		Object:    "pipeline",
+		ID:        path.Join(applicationName, pipelineName),
+		Before: auditBefore(r.Audit, func() (interface{}, error) {
+			return getSpinnakerPipeline(gateClient, applicationName, pipelineName)
+		}),
+	}
+	resp, err := gateClient.PipelineControllerApi.DeletePipelineUsingDELETE(gateClient.Context, applicationName, pipelineName)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = xerrors.Errorf("encountered an error deleting pipeline %s, status code: %d", pipelineName, resp.StatusCode)
+	}
+	r.Audit.Log(pipeline, change, err)
+	return err
+}
+
+// resolveRunAsUser returns the Spinnaker name of the service account referenced by the run-as-user annotation, or
+// an empty string while it does not exist yet. The annotation names a SpinnakerServiceAccount, whose status records the
+// service account once saved, or a service account that is not managed by this controller, which is looked up in
+// Gate.
+func (r *PipelineReconciler) resolveRunAsUser(ctx context.Context, pipeline *v1.Pipeline) (string, error) {
+	name := pipeline.Annotations[runAsUserAnnotation]
+	if name == "" {
+		return "", nil
+	}
+
+	serviceAccount := &v1.SpinnakerServiceAccount{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name}, serviceAccount); err == nil {
+		return serviceAccount.Status.SpinnakerResource.Name, nil
+	} else if !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	// Gate lists the service accounts that the user of the controller may use, so that only the ones not managed by
+	// this controller are looked up.
+	gateClient := r.Gateway.Gate(ctx)
+	serviceAccounts, _, err := gateClient.AuthControllerApi.GetServiceAccountsUsingGET(gateClient.Context, &gate.AuthControllerApiGetServiceAccountsUsingGETOpts{})
+	if err != nil {
+		return "", err
+	}
+	for _, serviceAccount := range serviceAccounts {
+		var account struct {
+			Name string
+		}
+		if err := mapstructure.Decode(serviceAccount, &account); err != nil {
+			continue
+		}
+		if strings.EqualFold(account.Name, name) {
+			return account.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// setRunAsUser sets runAsUser on the triggers of the pipeline, including the ones of a templated pipeline configuration.
+func setRunAsUser(body map[string]interface{}, runAsUser string) {
+	triggerLists := []interface{}{body["triggers"]}
+	if config, ok := body["config"].(map[string]interface{}); ok {
+		if configuration, ok := config["configuration"].(map[string]interface{}); ok {
+			triggerLists = append(triggerLists, configuration["triggers"])
+		}
+	}
+	for _, triggers := range triggerLists {
+		list, _ := triggers.([]interface{})
+		for _, trigger := range list {
+			if t, ok := trigger.(map[string]interface{}); ok {
+				t["runAsUser"] = runAsUser
+			}
+		}
+	}
+}
+
+// setNotifications merges notifications into the ones of the pipeline, including the ones of a templated pipeline
+// configuration, which are the ones that a templated pipeline is rendered with.
+func setNotifications(body map[string]interface{}, notifications []v1.Notification) {
+	keys, entries := notificationEntries(notifications, "pipeline")
+	existing, _ := body["notifications"].([]interface{})
+	body["notifications"] = mergeNotifications(existing, nil, keys, entries)
+	if config, ok := body["config"].(map[string]interface{}); ok {
+		if configuration, ok := config["configuration"].(map[string]interface{}); ok {
+			existing, _ := configuration["notifications"].([]interface{})
+			configuration["notifications"] = mergeNotifications(existing, nil, keys, entries)
+		}
+	}
+}
+
+func (r *PipelineReconciler) renamePipeline(ctx context.Context, pipeline *v1.Pipeline, applicationName string, from string, to string) error {
+	gateClient := r.Gateway.Gate(ctx)
+	resp, err := gateClient.PipelineControllerApi.RenamePipelineUsingPOST(gateClient.Context, map[string]string{
+		"application": applicationName,
+		"from":        from,
+		"to":          to,
+	})
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = xerrors.Errorf("encountered an error renaming pipeline %s to %s, status code: %d", from, to, resp.StatusCode)
+	}
+	r.Audit.Log(pipeline, audit.Change{
+		Operation: audit.OperationRename,
+		Object:    "pipeline",
+		ID:        path.Join(applicationName, from),
+		Before:    map[string]string{"name": from},
+		After:     map[string]string{"name": to},
+	}, err)
+	return err
+}
+
+func (r *PipelineReconciler) execute(ctx context.Context, pipeline *v1.Pipeline, hash string) error {
+	applicationName := pipeline.Status.SpinnakerResource.ApplicationName
+	pipelineName := pipeline.Status.SpinnakerResource.ID
+
+	pipelineConfigID, err := getPipelineConfigID(r.Gateway.Gate(ctx), applicationName, pipelineName)
+	if err != nil {
+		return err
+	}
+	if pipelineConfigID != "" {
+		execution, err := getLastExecution(r.Gateway.Gate(ctx), pipelineConfigID)
+		if err != nil {
+			return err
+		}
+		if execution != nil && isExecutionRunning(execution.Status) {
+			if getExecuteConcurrency(pipeline) != executeConcurrencyCancel {
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SkippedExecution", "Skipped execution because %s is in flight", execution.ID)
+				pipeline.Status.PendingTriggerHash = ""
+				return r.Update(ctx, pipeline)
+			}
+			reason := fmt.Sprintf("Superseded by %s", hash)
+			err := cancelExecution(r.Gateway.Gate(ctx), execution.ID, reason)
+			r.Audit.Log(pipeline, audit.Change{
+				Operation: audit.OperationCancel,
+				Object:    "execution",
+				ID:        execution.ID,
+				After:     map[string]string{"reason": reason},
+			}, err)
+			if err != nil {
+				return err
+			}
+			r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulCanceled", "Canceled execution %s in flight", execution.ID)
+		}
+	}
+
+	ref, err := r.Gateway.Roer(ctx).ExecPipeline(applicationName, pipelineName)
+	r.Audit.Log(pipeline, audit.Change{
+		Operation: audit.OperationExecute,
+		Object:    "pipeline",
+		ID:        path.Join(applicationName, pipelineName),
+		After:     map[string]string{"triggerHash": hash},
+	}, err)
+	if err != nil {
+		r.Recorder.Eventf(pipeline, coreV1.EventTypeWarning, "ExecuteFailed", "Failed to execute pipeline: %q", pipeline.Name)
+		return err
+	}
+	r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulExecuted", "Executed pipeline: %q", pipeline.Name)
+
+	pipeline.Status.LastExecution = v1.SpinnakerPipelineExecution{
+		ID:          path.Base(ref.Ref),
+		Status:      "NOT_STARTED",
+		TriggerHash: hash,
+	}
+	pipeline.Status.PendingTriggerHash = ""
+	return r.Update(ctx, pipeline)
+}
+
+func (r *PipelineReconciler) executeOnSchedule(ctx context.Context, pipeline *v1.Pipeline) (time.Duration, error) {
+	schedule, err := getExecuteSchedule(pipeline)
+	if err != nil {
+		return 0, err
+	}
+
+	last := pipeline.ObjectMeta.CreationTimestamp.Time
+	if pipeline.Status.LastScheduleTime != nil {
+		last = pipeline.Status.LastScheduleTime.Time
+	}
+	now := time.Now()
+	next := schedule.Next(last)
+	if next.After(now) {
+		return next.Sub(now), nil
+	}
+
+	scheduleTime := metaV1.NewTime(now)
+	pipeline.Status.LastScheduleTime = &scheduleTime
+	pipeline.Status.PendingTriggerHash = pipeline.Status.Hash
+	if err := r.Update(ctx, pipeline); err != nil {
+		return 0, err
+	}
+	if err := r.execute(ctx, pipeline, pipeline.Status.PendingTriggerHash); err != nil {
+		return 0, err
+	}
+	return schedule.Next(now).Sub(now), nil
+}
+
+func (r *PipelineReconciler) trackExecution(ctx context.Context, pipeline *v1.Pipeline) (ctrl.Result, error) {
+	pipelineConfigID, err := getPipelineConfigID(r.Gateway.Gate(ctx), pipeline.Status.SpinnakerResource.ApplicationName, pipeline.Status.SpinnakerResource.ID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pipelineConfigID == "" {
+		return ctrl.Result{RequeueAfter: r.executionResyncInterval(pipeline, time.Now())}, nil
+	}
+	execution, err := getLastExecution(r.Gateway.Gate(ctx), pipelineConfigID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if execution == nil {
+		return ctrl.Result{RequeueAfter: r.executionResyncInterval(pipeline, time.Now())}, nil
+	}
+
+	lastExecution := execution.toStatus()
+	if lastExecution.ID == pipeline.Status.LastExecution.ID {
+		lastExecution.TriggerHash = pipeline.Status.LastExecution.TriggerHash
+	}
+	if !executionStatusEqual(lastExecution, pipeline.Status.LastExecution) {
+		if !isExecutionRunning(lastExecution.Status) && lastExecution.Status != pipeline.Status.LastExecution.Status {
+			if lastExecution.Status == "SUCCEEDED" {
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeNormal, "SuccessfulExecution", "Execution %s succeeded", lastExecution.ID)
+			} else {
+				r.Recorder.Eventf(pipeline, coreV1.EventTypeWarning, "FailedExecution", "Execution %s finished with %s at stage %q", lastExecution.ID, lastExecution.Status, lastExecution.FailedStage)
+			}
+		}
+		pipeline.Status.LastExecution = lastExecution
+		if err := r.Update(ctx, pipeline); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: r.executionResyncInterval(pipeline, time.Now())}, nil
+}
+
+// executionResyncInterval returns when to check the last execution again. It is polled while it runs, which includes
+// the ones triggered by the controller until Gate reports them, and otherwise checked less often the longer the
+// pipeline has been idle, so that idle pipelines do not keep calling Gate.
+func (r *PipelineReconciler) executionResyncInterval(pipeline *v1.Pipeline, now time.Time) time.Duration {
+	settings := r.Settings.get()
+	lastExecution := pipeline.Status.LastExecution
+	if isExecutionRunning(lastExecution.Status) {
+		return settings.ExecutionPollInterval
+	}
+	idleSince := pipeline.ObjectMeta.CreationTimestamp.Time
+	if lastExecution.EndTime != nil {
+		idleSince = lastExecution.EndTime.Time
+	} else if lastExecution.StartTime != nil {
+		idleSince = lastExecution.StartTime.Time
+	}
+	interval := settings.ExecutionResyncInterval
+	for interval < now.Sub(idleSince) && interval < maxExecutionResyncBackoff*settings.ExecutionResyncInterval {
+		interval *= 2
+	}
+	return interval
+}
+
 func (r *PipelineReconciler) isApplicationCreated(ctx context.Context, pipelineConfig spinnaker.PipelineConfig) (bool, error) {
 	if pipelineConfig.Application == "" {
 		return true, nil
@@ -186,5 +748,13 @@ func (r *PipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}); err != nil {
 		return err
 	}
-	return ctrl.NewControllerManagedBy(mgr).For(&v1.Pipeline{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Pipeline{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.Pipeline{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.PipelineList{}), &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &v1.Notification{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: mapNotification(r.Client, r.Log, v1.NotificationTargetPipeline, &v1.PipelineList{}),
+		}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(newRequeueOnCancel(r, r.Log))
 }
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/fakegate"
+	"spinnaker-dcd-controller/internal/gateway"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/roer/spinnaker"
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const envtestPipelineSpec = `
+schema: "1"
+pipeline:
+  application: envtest-pipeline-app
+  name: deploy
+  template:
+    source: spinnaker://envtest-pipeline-template
+  variables:
+    replicas: %s
+configuration:
+  inherit:
+    - triggers
+`
+
+func TestPipelineLifecycle(t *testing.T) {
+	requireEnvironment(t)
+	defer testGate.ClearFailures()
+
+	applicationKey := client.ObjectKey{Name: "envtest-pipeline-app"}
+	templateKey := client.ObjectKey{Name: "envtest-pipeline-template"}
+	key := client.ObjectKey{Name: "envtest-pipeline"}
+
+	// The application cannot be created until the failure is cleared, so that the pipeline has to wait for it.
+	testGate.Fail(http.MethodPost, "/applications/"+applicationKey.Name+"/tasks", http.StatusInternalServerError, 0)
+	application := &v1.Application{
+		ObjectMeta: metaV1.ObjectMeta{Name: applicationKey.Name},
+		Spec:       rawSpec("email: pipeline@example.com"),
+	}
+	create(t, application)
+	pipelineTemplate := &v1.PipelineTemplate{
+		ObjectMeta: metaV1.ObjectMeta{Name: templateKey.Name},
+		Spec:       rawSpec(templateManifest(templateKey.Name, "before")),
+	}
+	create(t, pipelineTemplate)
+	pipeline := &v1.Pipeline{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name},
+		Spec:       rawSpec(pipelineManifest("1")),
+	}
+	create(t, pipeline)
+
+	consistently(t, 3*time.Second, func() error {
+		if _, ok := testGate.Pipeline(applicationKey.Name, "deploy"); ok {
+			return xerrors.New("pipeline is saved before its application is created")
+		}
+		return nil
+	})
+
+	testGate.ClearFailures()
+	eventually(t, func() error {
+		if _, ok := testGate.Pipeline(applicationKey.Name, "deploy"); !ok {
+			return xerrors.New("pipeline is not saved")
+		}
+		return nil
+	})
+	saved, _ := testGate.Pipeline(applicationKey.Name, "deploy")
+	id := saved["id"]
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, pipeline); err != nil {
+			return err
+		}
+		if pipeline.Status.SpinnakerResource.ApplicationName != applicationKey.Name || pipeline.Status.SpinnakerResource.ID != "deploy" {
+			return xerrors.Errorf("unexpected status: %+v", pipeline.Status.SpinnakerResource)
+		}
+		return nil
+	})
+
+	update(t, key, pipeline, func() {
+		pipeline.Spec = rawSpec(pipelineManifest("2"))
+	})
+	eventually(t, func() error {
+		saved, _ := testGate.Pipeline(applicationKey.Name, "deploy")
+		config, _ := saved["config"].(map[string]interface{})
+		pipelineConfig, _ := config["pipeline"].(map[string]interface{})
+		variables, _ := pipelineConfig["variables"].(map[string]interface{})
+		if variables["replicas"] != float64(2) {
+			return xerrors.Errorf("pipeline is not updated: %v", saved)
+		}
+		if saved["id"] != id {
+			return xerrors.Errorf("pipeline is saved as another one: %v, expected %v", saved["id"], id)
+		}
+		return nil
+	})
+	if count := testGate.Pipelines(); count != 1 {
+		t.Fatalf("expected one pipeline, got %d", count)
+	}
+
+	remove(t, pipeline)
+	waitForDeletion(t, key, &v1.Pipeline{})
+	if _, ok := testGate.Pipeline(applicationKey.Name, "deploy"); ok {
+		t.Fatal("pipeline is not deleted from Spinnaker")
+	}
+
+	remove(t, pipelineTemplate)
+	waitForDeletion(t, templateKey, &v1.PipelineTemplate{})
+	remove(t, application)
+	waitForDeletion(t, applicationKey, &v1.Application{})
+}
+
+func pipelineManifest(replicas string) string {
+	return fmt.Sprintf(envtestPipelineSpec, replicas)
+}
+
+func TestPipelineHashIgnoresControlAnnotations(t *testing.T) {
+	r := &PipelineReconciler{}
+	pipeline := &v1.Pipeline{Spec: rawSpec(pipelineManifest("1"))}
+	hash := r.hash(pipeline)
+	if controlHash := r.controlHash(pipeline); controlHash != "" {
+		t.Fatalf("expected no control hash without annotations, got %q", controlHash)
+	}
+	pipeline.Annotations = map[string]string{lockedAnnotation: "true", lockDescriptionAnnotation: "Change freeze"}
+	if r.hash(pipeline) != hash {
+		t.Error("expected locking not to change the spec hash")
+	}
+	controlHash := r.controlHash(pipeline)
+	if controlHash == "" {
+		t.Fatal("expected a control hash")
+	}
+	pipeline.Annotations[lockedAnnotation] = "false"
+	if r.controlHash(pipeline) == controlHash {
+		t.Error("expected unlocking to change the control hash")
+	}
+}
+
+func TestFindPipelineConflict(t *testing.T) {
+	server := fakegate.NewServer()
+	defer server.Close()
+	gatewayClient, err := gateway.New(server.URL(), gateway.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &PipelineReconciler{Gateway: gatewayClient}
+	pipeline := &v1.Pipeline{}
+	ctx := context.Background()
+	for _, name := range []string{"deploy", "rollback"} {
+		if err := r.savePipelineBody(ctx, pipeline, map[string]interface{}{"application": "sample", "name": name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conflict, err := r.findConflict(ctx, "sample", "deploy", spinnaker.PipelineConfig{Application: "sample", Name: "rollback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflict == "" {
+		t.Error("expected renaming onto another pipeline to conflict")
+	}
+	conflict, err = r.findConflict(ctx, "sample", "deleted", spinnaker.PipelineConfig{Application: "sample", Name: "rollback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflict != "" {
+		t.Errorf("expected the pipeline saved by a previous attempt to be taken over, got %q", conflict)
+	}
+}
+
+func TestDeleteMissingPipeline(t *testing.T) {
+	server := fakegate.NewServer()
+	defer server.Close()
+	gatewayClient, err := gateway.New(server.URL(), gateway.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &PipelineReconciler{Gateway: gatewayClient}
+	if err := r.deletePipeline(context.Background(), &v1.Pipeline{}, "sample", "deploy"); err != nil {
+		t.Errorf("expected deleting a missing pipeline to succeed, got %v", err)
+	}
+}
+
+func TestResolveRunAsUser(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	serviceAccount := &v1.SpinnakerServiceAccount{ObjectMeta: metaV1.ObjectMeta{Name: "deployer"}}
+	serviceAccount.Status.SpinnakerResource.Name = "deploy-bot"
+	server, gatewayClient := newFakeGate(t)
+	server.AddServiceAccount("unmanaged", nil)
+	r := &PipelineReconciler{
+		Client:  fake.NewFakeClientWithScheme(scheme, serviceAccount),
+		Gateway: gatewayClient,
+	}
+
+	for annotation, expected := range map[string]string{
+		// The SpinnakerServiceAccount is resolved from its status without listing the service accounts of Gate.
+		"deployer":  "deploy-bot",
+		"unmanaged": "unmanaged",
+		"Unmanaged": "unmanaged",
+		"missing":   "",
+	} {
+		pipeline := &v1.Pipeline{ObjectMeta: metaV1.ObjectMeta{Annotations: map[string]string{runAsUserAnnotation: annotation}}}
+		runAsUser, err := r.resolveRunAsUser(context.Background(), pipeline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runAsUser != expected {
+			t.Errorf("expected %s to resolve to %q, got %q", annotation, expected, runAsUser)
+		}
+	}
+}
+
+func TestTrackExecution(t *testing.T) {
+	server := fakegate.NewServer()
+	defer server.Close()
+	gatewayClient, err := gateway.New(server.URL(), gateway.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	pipeline := &v1.Pipeline{ObjectMeta: metaV1.ObjectMeta{Name: "deploy", CreationTimestamp: metaV1.Now()}}
+	pipeline.Status.SpinnakerResource = v1.SpinnakerPipelineResource{ApplicationName: "sample", ID: "deploy"}
+	r := &PipelineReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme, pipeline),
+		Recorder: record.NewFakeRecorder(10),
+		Gateway:  gatewayClient,
+	}
+	settings := r.Settings.get()
+	ctx := context.Background()
+	if err := r.savePipelineBody(ctx, pipeline, map[string]interface{}{"application": "sample", "name": "deploy"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := r.trackExecution(ctx, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != settings.ExecutionResyncInterval || pipeline.Status.LastExecution.ID != "" {
+		t.Fatalf("expected a pipeline never executed to be checked every resync interval, got %v, %+v", result, pipeline.Status.LastExecution)
+	}
+
+	server.SetExecutionStatus("", "RUNNING")
+	ref, err := gatewayClient.Roer(ctx).ExecPipeline("sample", "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := path.Base(ref.Ref)
+	pipeline.Status.LastExecution = v1.SpinnakerPipelineExecution{ID: id, Status: "NOT_STARTED", TriggerHash: "hash"}
+	result, err = r.trackExecution(ctx, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastExecution := pipeline.Status.LastExecution
+	if lastExecution.Status != "RUNNING" || lastExecution.StartTime == nil || lastExecution.EndTime != nil || lastExecution.TriggerHash != "hash" {
+		t.Fatalf("unexpected running execution %+v", lastExecution)
+	}
+	if result.RequeueAfter != settings.ExecutionPollInterval {
+		t.Fatalf("expected a running execution to be polled, got %v", result)
+	}
+
+	server.SetExecutionStatus(id, "TERMINAL")
+	result, err = r.trackExecution(ctx, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastExecution = pipeline.Status.LastExecution
+	if lastExecution.ID != id || lastExecution.Status != "TERMINAL" || lastExecution.EndTime == nil || lastExecution.TriggerHash != "hash" {
+		t.Fatalf("unexpected finished execution %+v", lastExecution)
+	}
+	if result.RequeueAfter != settings.ExecutionResyncInterval {
+		t.Fatalf("expected a finished execution to be checked every resync interval, got %v", result)
+	}
+	stored := &v1.Pipeline{}
+	if err := r.Get(ctx, client.ObjectKey{Name: "deploy"}, stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.LastExecution.Status != "TERMINAL" {
+		t.Fatalf("expected the status to be updated, got %+v", stored.Status.LastExecution)
+	}
+
+	for idle, expected := range map[time.Duration]time.Duration{
+		0:                                       settings.ExecutionResyncInterval,
+		3 * settings.ExecutionResyncInterval:    4 * settings.ExecutionResyncInterval,
+		1000 * settings.ExecutionResyncInterval: maxExecutionResyncBackoff * settings.ExecutionResyncInterval,
+	} {
+		if interval := r.executionResyncInterval(pipeline, lastExecution.EndTime.Add(idle)); interval != expected {
+			t.Errorf("expected a pipeline idle for %v to be checked after %v, got %v", idle, expected, interval)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"context"
+	"path"
+	"reflect"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
+	"time"
+
+	"github.com/go-logr/logr"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+type PipelineExecutionReconciler struct {
+	client.Client
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Gateway                 gateway.Client
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
+}
+
+func (r *PipelineExecutionReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
+	pipelineExecution := &v1.PipelineExecution{}
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "PipelineExecution", req)
+	defer func() { endReconcileSpan(span, pipelineExecution, err) }()
+	logger := r.Log.WithValues("pipelineExecution", req.NamespacedName)
+	if err := r.Get(ctx, req.NamespacedName, pipelineExecution); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, pipelineExecution) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, pipelineExecution); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, pipelineExecution, pipelineExecution.Status.SpinnakerResource.ID != "")
+
+	if !pipelineExecution.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if pipelineExecution.Status.SpinnakerResource.ID == "" {
+		if r.findCondition(pipelineExecution, v1.PipelineExecutionComplete) != nil {
+			return ctrl.Result{}, nil
+		}
+		return r.trigger(ctx, pipelineExecution, logger)
+	}
+
+	if pipelineExecution.Status.Execution.Status != "" && !isExecutionRunning(pipelineExecution.Status.Execution.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	execution, err := getExecution(r.Gateway.Gate(ctx), pipelineExecution.Status.SpinnakerResource.ID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if execution == nil {
+		return ctrl.Result{RequeueAfter: r.Settings.get().ExecutionPollInterval}, nil
+	}
+
+	status := execution.toStatus()
+	conditions := r.buildConditions(pipelineExecution, execution)
+	if !executionStatusEqual(status, pipelineExecution.Status.Execution) || !reflect.DeepEqual(conditions, pipelineExecution.Status.Conditions) {
+		if !isExecutionRunning(status.Status) {
+			if status.Status == "SUCCEEDED" {
+				r.Recorder.Eventf(pipelineExecution, coreV1.EventTypeNormal, "SuccessfulExecution", "Execution %s succeeded", status.ID)
+			} else {
+				r.Recorder.Eventf(pipelineExecution, coreV1.EventTypeWarning, "FailedExecution", "Execution %s finished with %s at stage %q", status.ID, status.Status, status.FailedStage)
+			}
+		}
+		pipelineExecution.Status.Execution = status
+		pipelineExecution.Status.Conditions = conditions
+		logger.V(1).Info("update", "pipeline execution", pipelineExecution)
+		if err := r.Update(ctx, pipelineExecution); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if isExecutionRunning(status.Status) {
+		return ctrl.Result{RequeueAfter: r.Settings.get().ExecutionPollInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *PipelineExecutionReconciler) trigger(ctx context.Context, pipelineExecution *v1.PipelineExecution, logger logr.Logger) (ctrl.Result, error) {
+	if pipelineExecution.Status.SpinnakerResource.PipelineName == "" {
+		pipeline := &v1.Pipeline{}
+		if err := r.Get(ctx, client.ObjectKey{Name: pipelineExecution.Spec.PipelineName}, pipeline); err != nil {
+			if errors.IsNotFound(err) {
+				logger.V(1).Info("wait for pipeline to be created")
+				return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+			}
+			return ctrl.Result{}, err
+		}
+		if pipeline.Status.SpinnakerResource.ID == "" {
+			logger.V(1).Info("wait for pipeline to be saved")
+			return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+		}
+		pipelineExecution.Status.SpinnakerResource.ApplicationName = pipeline.Status.SpinnakerResource.ApplicationName
+		pipelineExecution.Status.SpinnakerResource.PipelineName = pipeline.Status.SpinnakerResource.ID
+	}
+
+	applicationName := pipelineExecution.Status.SpinnakerResource.ApplicationName
+	pipelineName := pipelineExecution.Status.SpinnakerResource.PipelineName
+	correlationID := string(pipelineExecution.UID)
+
+	// The correlation ID makes triggering idempotent, so that an execution started by a previous reconcile is adopted
+	// instead of started twice.
+	execution, err := findExecutionByCorrelationID(r.Gateway.Gate(ctx), applicationName, pipelineName, correlationID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if execution == nil {
+		if triggered := r.findCondition(pipelineExecution, v1.PipelineExecutionTriggered); triggered != nil && triggered.Status == "True" {
+			return r.waitForExecution(ctx, pipelineExecution, triggered)
+		}
+		trigger := r.buildTrigger(pipelineExecution)
+		err := triggerPipeline(r.Gateway.Gate(ctx), applicationName, pipelineName, trigger)
+		r.Audit.Log(pipelineExecution, audit.Change{
+			Operation: audit.OperationExecute,
+			Object:    "pipeline",
+			ID:        path.Join(applicationName, pipelineName),
+			After:     trigger,
+		}, err)
+		if err != nil {
+			r.Recorder.Eventf(pipelineExecution, coreV1.EventTypeWarning, "TriggerFailed", "Failed to trigger pipeline: %q", pipelineName)
+			return ctrl.Result{}, err
+		}
+		r.setCondition(pipelineExecution, v1.PipelineExecutionTriggered, "True", "")
+		r.Recorder.Eventf(pipelineExecution, coreV1.EventTypeNormal, "SuccessfulTriggered", "Triggered pipeline: %q", pipelineName)
+		logger.V(1).Info("trigger", "pipeline execution", pipelineExecution)
+		if err := r.Update(ctx, pipelineExecution); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: r.Settings.get().ExecutionPollInterval}, nil
+	}
+
+	pipelineExecution.Status.SpinnakerResource.ID = execution.ID
+	pipelineExecution.Status.Execution = execution.toStatus()
+	pipelineExecution.Status.Conditions = r.buildConditions(pipelineExecution, execution)
+	if err := r.Update(ctx, pipelineExecution); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.Settings.get().ExecutionPollInterval}, nil
+}
+
+// waitForExecution waits for the triggered execution to be found by its correlation ID, and fails it once
+// ExecutionTriggerTimeout has passed since it was triggered, e.g. when Orca dropped the trigger or the search no longer
+// reaches back to the execution.
+func (r *PipelineExecutionReconciler) waitForExecution(ctx context.Context, pipelineExecution *v1.PipelineExecution, triggered *v1.PipelineExecutionCondition) (ctrl.Result, error) {
+	settings := r.Settings.get()
+	triggeredTime := pipelineExecution.ObjectMeta.CreationTimestamp.Time
+	if triggered.LastTransitionTime != nil {
+		triggeredTime = triggered.LastTransitionTime.Time
+	}
+	if remaining := time.Until(triggeredTime.Add(settings.ExecutionTriggerTimeout)); remaining > 0 {
+		if remaining < settings.ExecutionPollInterval {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+		return ctrl.Result{RequeueAfter: settings.ExecutionPollInterval}, nil
+	}
+
+	r.setCondition(pipelineExecution, v1.PipelineExecutionComplete, "False", v1.PipelineExecutionTriggerTimeout)
+	r.Recorder.Eventf(pipelineExecution, coreV1.EventTypeWarning, "TriggerTimeout", "Execution of pipeline %q is not found within %s of being triggered", pipelineExecution.Status.SpinnakerResource.PipelineName, settings.ExecutionTriggerTimeout)
+	return ctrl.Result{}, r.Update(ctx, pipelineExecution)
+}
+
+func (r *PipelineExecutionReconciler) findCondition(pipelineExecution *v1.PipelineExecution, conditionType v1.PipelineExecutionConditionType) *v1.PipelineExecutionCondition {
+	for i, condition := range pipelineExecution.Status.Conditions {
+		if condition.Type == conditionType {
+			return &pipelineExecution.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setCondition upserts the condition, whose LastTransitionTime changes only along with its status.
+func (r *PipelineExecutionReconciler) setCondition(pipelineExecution *v1.PipelineExecution, conditionType v1.PipelineExecutionConditionType, status string, reason string) {
+	now := metaV1.Now()
+	if condition := r.findCondition(pipelineExecution, conditionType); condition != nil {
+		if condition.Status != status {
+			condition.LastTransitionTime = &now
+		}
+		condition.Status = status
+		condition.Reason = reason
+		return
+	}
+	pipelineExecution.Status.Conditions = append(pipelineExecution.Status.Conditions, v1.PipelineExecutionCondition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		LastTransitionTime: &now,
+	})
+}
+
+func (r *PipelineExecutionReconciler) buildTrigger(pipelineExecution *v1.PipelineExecution) map[string]interface{} {
+	trigger := map[string]interface{}{
+		"type":          "manual",
+		"user":          "spinnaker-dcd-controller",
+		"correlationId": string(pipelineExecution.UID),
+	}
+	if len(pipelineExecution.Spec.Parameters) > 0 {
+		trigger["parameters"] = pipelineExecution.Spec.Parameters
+	}
+	if len(pipelineExecution.Spec.Artifacts) > 0 {
+		trigger["artifacts"] = pipelineExecution.Spec.Artifacts
+	}
+	return trigger
+}
+
+func (r *PipelineExecutionReconciler) buildConditions(pipelineExecution *v1.PipelineExecution, execution *pipelineExecution) []v1.PipelineExecutionCondition {
+	conditions := []v1.PipelineExecutionCondition{
+		{
+			Type:   v1.PipelineExecutionTriggered,
+			Status: "True",
+		},
+	}
+	for _, stage := range execution.Stages {
+		conditions = append(conditions, v1.PipelineExecutionCondition{
+			Type:   v1.PipelineExecutionStagePrefix + v1.PipelineExecutionConditionType(stage.Name),
+			Status: conditionStatus(stage.Status),
+			Reason: stage.Status,
+		})
+	}
+	if !isExecutionRunning(execution.Status) {
+		conditions = append(conditions, v1.PipelineExecutionCondition{
+			Type:   v1.PipelineExecutionComplete,
+			Status: conditionStatus(execution.Status),
+			Reason: execution.Status,
+		})
+	}
+	now := metaV1.Now()
+	for i := range conditions {
+		if old := r.findCondition(pipelineExecution, conditions[i].Type); old != nil && old.Status == conditions[i].Status && old.LastTransitionTime != nil {
+			conditions[i].LastTransitionTime = old.LastTransitionTime
+		} else {
+			conditions[i].LastTransitionTime = &now
+		}
+	}
+	return conditions
+}
+
+func conditionStatus(executionStatus string) string {
+	switch {
+	case executionStatus == "SUCCEEDED" || executionStatus == "SKIPPED":
+		return "True"
+	case isExecutionRunning(executionStatus):
+		return "Unknown"
+	default:
+		return "False"
+	}
+}
+
+func (r *PipelineExecutionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.PipelineExecution{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.PipelineExecution{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.PipelineExecutionList{}), &handler.EnqueueRequestForObject{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(newRequeueOnCancel(r, r.Log))
+}
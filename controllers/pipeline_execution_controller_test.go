@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/fakegate"
+	"spinnaker-dcd-controller/internal/gateway"
+	"testing"
+	"time"
+
+	gate "github.com/spinnaker/spin/gateapi"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newPipelineExecutionReconciler returns a reconciler of a PipelineExecution of the pipeline deploy of sample, which
+// is saved in server.
+func newPipelineExecutionReconciler(t *testing.T, server *fakegate.Server, gatewayClient gateway.Client) *PipelineExecutionReconciler {
+	t.Helper()
+	gateClient := gatewayClient.Gate(context.Background())
+	if _, err := gateClient.PipelineControllerApi.SavePipelineUsingPOST(gateClient.Context, map[string]interface{}{"application": "sample", "name": "deploy"}, &gate.PipelineControllerApiSavePipelineUsingPOSTOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	pipeline := &v1.Pipeline{ObjectMeta: metaV1.ObjectMeta{Name: "deploy"}}
+	pipeline.Status.SpinnakerResource = v1.SpinnakerPipelineResource{ApplicationName: "sample", ID: "deploy"}
+	pipelineExecution := &v1.PipelineExecution{
+		ObjectMeta: metaV1.ObjectMeta{Name: "release", UID: "release-uid", CreationTimestamp: metaV1.Now()},
+		Spec:       v1.PipelineExecutionSpec{PipelineName: "deploy", Parameters: map[string]string{"version": "1.0.0"}},
+	}
+	return &PipelineExecutionReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme, pipeline, pipelineExecution),
+		Log:      ctrl.Log.WithName("controllers").WithName("PipelineExecution"),
+		Recorder: record.NewFakeRecorder(100),
+		Gateway:  gatewayClient,
+	}
+}
+
+func reconcilePipelineExecution(t *testing.T, r *PipelineExecutionReconciler) (ctrl.Result, *v1.PipelineExecution) {
+	t.Helper()
+	key := client.ObjectKey{Name: "release"}
+	result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipelineExecution := &v1.PipelineExecution{}
+	if err := r.Get(context.Background(), key, pipelineExecution); err != nil {
+		t.Fatal(err)
+	}
+	return result, pipelineExecution
+}
+
+func TestPipelineExecutionTriggersAndAdopts(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	r := newPipelineExecutionReconciler(t, server, gatewayClient)
+	settings := r.Settings.get()
+	server.SetExecutionStatus("", "RUNNING")
+	// An execution of the pipeline that was not triggered by the PipelineExecution must not be adopted.
+	if _, err := gatewayClient.Roer(context.Background()).ExecPipeline("sample", "deploy"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, pipelineExecution := reconcilePipelineExecution(t, r)
+	triggered := r.findCondition(pipelineExecution, v1.PipelineExecutionTriggered)
+	if triggered == nil || triggered.Status != "True" || triggered.LastTransitionTime == nil {
+		t.Fatalf("expected the Triggered condition, got %+v", pipelineExecution.Status.Conditions)
+	}
+	if pipelineExecution.Status.SpinnakerResource.ID != "" || result.RequeueAfter != settings.ExecutionPollInterval {
+		t.Fatalf("expected the execution to be searched for on the next poll, got %+v, %v", pipelineExecution.Status.SpinnakerResource, result)
+	}
+	triggeredTime := triggered.LastTransitionTime
+
+	_, pipelineExecution = reconcilePipelineExecution(t, r)
+	id := pipelineExecution.Status.SpinnakerResource.ID
+	if id == "" || pipelineExecution.Status.Execution.Status != "RUNNING" {
+		t.Fatalf("expected the triggered execution to be adopted, got %+v", pipelineExecution.Status)
+	}
+	if count := server.CountRequests(http.MethodPost, "/pipelines/sample/deploy"); count != 2 {
+		t.Fatalf("expected the pipeline to be triggered once, got %d", count-1)
+	}
+
+	server.SetExecutionStage(id, "Deploy", "RUNNING")
+	result, pipelineExecution = reconcilePipelineExecution(t, r)
+	if condition := r.findCondition(pipelineExecution, "Stage/Deploy"); condition == nil || condition.Status != "Unknown" || condition.Reason != "RUNNING" {
+		t.Fatalf("expected a running stage condition, got %+v", pipelineExecution.Status.Conditions)
+	}
+	if result.RequeueAfter != settings.ExecutionPollInterval {
+		t.Fatalf("expected a running execution to be polled, got %v", result)
+	}
+
+	server.SetExecutionStage(id, "Deploy", "SUCCEEDED")
+	server.SetExecutionStage(id, "Verify", "TERMINAL")
+	server.SetExecutionStatus(id, "TERMINAL")
+	result, pipelineExecution = reconcilePipelineExecution(t, r)
+	expected := map[v1.PipelineExecutionConditionType]string{
+		v1.PipelineExecutionTriggered: "True",
+		"Stage/Deploy":                "True",
+		"Stage/Verify":                "False",
+		v1.PipelineExecutionComplete:  "False",
+	}
+	for conditionType, status := range expected {
+		if condition := r.findCondition(pipelineExecution, conditionType); condition == nil || condition.Status != status {
+			t.Errorf("expected %s to be %s, got %+v", conditionType, status, condition)
+		}
+	}
+	if condition := r.findCondition(pipelineExecution, v1.PipelineExecutionTriggered); !condition.LastTransitionTime.Equal(triggeredTime) {
+		t.Errorf("expected the Triggered condition to keep its time %v, got %v", triggeredTime, condition.LastTransitionTime)
+	}
+	if pipelineExecution.Status.Execution.FailedStage != "Verify" || result.RequeueAfter != 0 {
+		t.Fatalf("expected the execution to finish at Verify, got %+v, %v", pipelineExecution.Status.Execution, result)
+	}
+}
+
+func TestPipelineExecutionTriggerTimeout(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	r := newPipelineExecutionReconciler(t, server, gatewayClient)
+	settings := r.Settings.get()
+	server.DropTriggers(1)
+
+	reconcilePipelineExecution(t, r)
+	result, pipelineExecution := reconcilePipelineExecution(t, r)
+	if r.findCondition(pipelineExecution, v1.PipelineExecutionComplete) != nil || result.RequeueAfter != settings.ExecutionPollInterval {
+		t.Fatalf("expected the dropped execution to be searched for until the timeout, got %+v, %v", pipelineExecution.Status.Conditions, result)
+	}
+
+	triggeredTime := metaV1.NewTime(time.Now().Add(-settings.ExecutionTriggerTimeout - time.Second))
+	r.findCondition(pipelineExecution, v1.PipelineExecutionTriggered).LastTransitionTime = &triggeredTime
+	if err := r.Update(context.Background(), pipelineExecution); err != nil {
+		t.Fatal(err)
+	}
+	result, pipelineExecution = reconcilePipelineExecution(t, r)
+	complete := r.findCondition(pipelineExecution, v1.PipelineExecutionComplete)
+	if complete == nil || complete.Status != "False" || complete.Reason != v1.PipelineExecutionTriggerTimeout || result.RequeueAfter != 0 {
+		t.Fatalf("expected the execution to fail by the timeout, got %+v, %v", pipelineExecution.Status.Conditions, result)
+	}
+
+	reconcilePipelineExecution(t, r)
+	if count := server.CountRequests(http.MethodPost, "/pipelines/sample/deploy"); count != 1 {
+		t.Fatalf("expected the failed execution not to be triggered again, got %d triggers", count)
+	}
+}
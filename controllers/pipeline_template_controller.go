@@ -6,52 +6,80 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
+	"spinnaker-dcd-controller/internal/tracing"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/spinnaker/roer/spinnaker"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/xerrors"
 
 	"github.com/go-logr/logr"
 	coreV1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 type PipelineTemplateReconciler struct {
 	client.Client
-	Log             logr.Logger
-	Scheme          *runtime.Scheme
-	Recorder        record.EventRecorder
-	SpinnakerClient spinnaker.Client
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Gateway                 gateway.Client
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Revisions               *Revisions
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
 }
 
-func (r *PipelineTemplateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+func (r *PipelineTemplateReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
 	pipelineTemplate := &v1.PipelineTemplate{}
 	logger := r.Log.WithValues("pipelineTemplate", req.NamespacedName)
-	ctx := context.Background()
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "PipelineTemplate", req)
+	defer func() { endReconcileSpan(span, pipelineTemplate, err) }()
 	if err := r.Get(ctx, req.NamespacedName, pipelineTemplate); err != nil {
 		if apierrors.IsNotFound(err) {
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, pipelineTemplate) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, pipelineTemplate); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, pipelineTemplate, containsString(pipelineTemplate.ObjectMeta.Finalizers, myFinalizerName))
+	if rolledBack, err := r.rollback(ctx, pipelineTemplate); rolledBack {
+		return ctrl.Result{}, err
+	}
 
 	if pipelineTemplate.ObjectMeta.DeletionTimestamp.IsZero() {
-		hash := fmt.Sprintf("%x", sha256.Sum256(pipelineTemplate.Spec.Raw))
+		hash := r.hash(pipelineTemplate)
 		oldHash := pipelineTemplate.Status.Hash
 		if hash != oldHash {
-			id, response, err := r.publishTemplate(pipelineTemplate)
+			id, response, err := r.publishTemplate(ctx, pipelineTemplate)
 			if err != nil {
 				if errors.Is(err, valueIsNotFoundError) {
-					return ctrl.Result{RequeueAfter: 60 * time.Second}, err
+					return ctrl.Result{RequeueAfter: r.Settings.get().InvalidResyncInterval}, err
 				}
 				return ctrl.Result{}, err
 			}
@@ -80,22 +108,24 @@ func (r *PipelineTemplateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		}
 	} else {
 		if containsString(pipelineTemplate.ObjectMeta.Finalizers, myFinalizerName) {
-			response, err := r.deleteTemplate(pipelineTemplate)
-			if err != nil {
-				return ctrl.Result{}, err
-			}
-			if response.Status == "TERMINAL" {
-				pipelineTemplate.Status.Conditions = append(pipelineTemplate.Status.Conditions, v1.PipelineTemplateCondition{
-					Type:   v1.PipelineTemplateDeletionComplete,
-					Status: "False",
-				})
-			} else {
-				pipelineTemplate.Status.Conditions = append(pipelineTemplate.Status.Conditions, v1.PipelineTemplateCondition{
-					Type:   v1.PipelineTemplateDeletionComplete,
-					Status: "True",
-				})
-				r.Recorder.Eventf(pipelineTemplate, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted pipeline template: %q", req.Name)
-				logger.V(1).Info("delete", "pipeline template", pipelineTemplate)
+			if !r.Settings.retain(r.Recorder, pipelineTemplate) {
+				response, err := r.deleteTemplate(ctx, pipelineTemplate)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if response.Status == "TERMINAL" {
+					pipelineTemplate.Status.Conditions = append(pipelineTemplate.Status.Conditions, v1.PipelineTemplateCondition{
+						Type:   v1.PipelineTemplateDeletionComplete,
+						Status: "False",
+					})
+				} else {
+					pipelineTemplate.Status.Conditions = append(pipelineTemplate.Status.Conditions, v1.PipelineTemplateCondition{
+						Type:   v1.PipelineTemplateDeletionComplete,
+						Status: "True",
+					})
+					r.Recorder.Eventf(pipelineTemplate, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted pipeline template: %q", req.Name)
+					logger.V(1).Info("delete", "pipeline template", pipelineTemplate)
+				}
 			}
 
 			pipelineTemplate.ObjectMeta.Finalizers = removeString(pipelineTemplate.ObjectMeta.Finalizers, myFinalizerName)
@@ -108,50 +138,144 @@ func (r *PipelineTemplateReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 	return ctrl.Result{}, nil
 }
 
-func (r *PipelineTemplateReconciler) publishTemplate(pipelineTemplate *v1.PipelineTemplate) (string, *spinnaker.ExecutionResponse, error) {
-	processedYAML, err := r.processTemplateVariables(pipelineTemplate.Spec.Raw)
+func (r *PipelineTemplateReconciler) publishTemplate(ctx context.Context, pipelineTemplate *v1.PipelineTemplate) (string, *spinnaker.ExecutionResponse, error) {
+	processedYAML, err := r.renderTemplate(ctx, pipelineTemplate)
 	if err != nil {
-		return "", nil, xerrors.Errorf("failed to process template variables: %w", err)
+		return "", nil, err
 	}
 
 	var templateMap map[string]interface{}
 	_ = json.Unmarshal(processedYAML, &templateMap)
 
-	id := templateMap["id"].(string)
-	ref, err := r.SpinnakerClient.PublishTemplate(templateMap, spinnaker.PublishTemplateOptions{
+	return r.applyTemplate(ctx, pipelineTemplate, templateMap)
+}
+
+// applyTemplate publishes templateMap, which is either rendered from the spec or the payload of a revision.
+func (r *PipelineTemplateReconciler) applyTemplate(ctx context.Context, pipelineTemplate *v1.PipelineTemplate, templateMap map[string]interface{}) (string, *spinnaker.ExecutionResponse, error) {
+	id, _ := templateMap["id"].(string)
+	if id == "" {
+		return "", nil, xerrors.New("pipeline template has no id")
+	}
+	change := audit.Change{
+		Operation: audit.OperationUpdate,
+		Object:    "pipelineTemplate",
+		ID:        id,
+		Before:    auditBefore(r.Audit, func() (interface{}, error) { return r.getTemplate(ctx, id) }),
+		After:     templateMap,
+	}
+	if pipelineTemplate.Status.SpinnakerResource.ID == "" {
+		change.Operation = audit.OperationCreate
+	}
+	ref, err := r.Gateway.Roer(ctx).PublishTemplate(templateMap, spinnaker.PublishTemplateOptions{
 		TemplateID: id,
 	})
 	if err != nil {
+		r.Audit.Log(pipelineTemplate, change, err)
 		return "", nil, err
 	}
 
-	response, err := r.SpinnakerClient.PollTaskStatus(ref.Ref, 30*time.Second)
+	response, err := pollTask(ctx, r.Gateway, ref.Ref, r.Settings.get().TaskPollTimeout)
 	if err != nil {
+		r.Audit.Log(pipelineTemplate, change, err)
 		return "", nil, err
 	}
+	r.Audit.Log(pipelineTemplate, change, taskError(ref.Ref, response.Status))
+	if response.Status != executionStatusTerminal {
+		if err := r.Revisions.record(ctx, pipelineTemplate, templateMap, response.ID); err != nil {
+			r.Log.Error(err, "unable to record revision", "pipelineTemplate", pipelineTemplate.Name)
+		}
+	}
 
 	return id, response, nil
 }
 
-func (r *PipelineTemplateReconciler) deleteTemplate(pipelineTemplate *v1.PipelineTemplate) (*spinnaker.ExecutionResponse, error) {
+func (r *PipelineTemplateReconciler) rollback(ctx context.Context, pipelineTemplate *v1.PipelineTemplate) (bool, error) {
+	return r.Revisions.rollback(ctx, r.Client, r.Recorder, pipelineTemplate, func(ctx context.Context, payload json.RawMessage) error {
+		var templateMap map[string]interface{}
+		if err := json.Unmarshal(payload, &templateMap); err != nil {
+			return err
+		}
+		_, response, err := r.applyTemplate(ctx, pipelineTemplate, templateMap)
+		if err != nil {
+			return err
+		}
+		return taskError(response.ID, response.Status)
+	})
+}
+
+func (r *PipelineTemplateReconciler) deleteTemplate(ctx context.Context, pipelineTemplate *v1.PipelineTemplate) (*spinnaker.ExecutionResponse, error) {
 	id := pipelineTemplate.Status.SpinnakerResource.ID
+	change := audit.Change{
+		Operation: audit.OperationDelete,
+		Object:    "pipelineTemplate",
+		ID:        id,
+		Before:    auditBefore(r.Audit, func() (interface{}, error) { return r.getTemplate(ctx, id) }),
+	}
 
-	ref, err := r.SpinnakerClient.DeleteTemplate(id)
+	ref, err := r.Gateway.Roer(ctx).DeleteTemplate(id)
 	if err != nil {
+		r.Audit.Log(pipelineTemplate, change, err)
 		return nil, err
 	}
 
-	response, err := r.SpinnakerClient.PollTaskStatus(ref.Ref, 30*time.Second)
+	response, err := pollTask(ctx, r.Gateway, ref.Ref, r.Settings.get().TaskPollTimeout)
 	if err != nil {
+		r.Audit.Log(pipelineTemplate, change, err)
 		return nil, err
 	}
+	r.Audit.Log(pipelineTemplate, change, taskError(ref.Ref, response.Status))
 
 	return response, nil
 }
 
+func (r *PipelineTemplateReconciler) getTemplate(ctx context.Context, id string) (interface{}, error) {
+	gateClient := r.Gateway.Gate(ctx)
+	template, resp, err := gateClient.PipelineTemplatesControllerApi.GetUsingGET(gateClient.Context, id)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	return template, err
+}
+
 var valueIsNotFoundError = errors.New("value is not found")
 
-func (r *PipelineTemplateReconciler) processTemplateVariables(yamlData []byte) (result []byte, err error) {
+func (r *PipelineTemplateReconciler) hash(pipelineTemplate *v1.PipelineTemplate) string {
+	hash := fmt.Sprintf("%x", sha256.Sum256(pipelineTemplate.Spec.Raw))
+	engine := pipelineTemplate.Annotations[templateEngineAnnotation]
+	if engine == "" {
+		return hash
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(hash+engine+pipelineTemplate.Annotations[templateValuesAnnotation])))
+}
+
+func (r *PipelineTemplateReconciler) renderTemplate(ctx context.Context, pipelineTemplate *v1.PipelineTemplate) ([]byte, error) {
+	switch engine := pipelineTemplate.Annotations[templateEngineAnnotation]; engine {
+	case "":
+		result, err := r.processTemplateVariables(ctx, pipelineTemplate.Spec.Raw)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to process template variables: %w", err)
+		}
+		return result, nil
+	case goTemplateEngine:
+		values := map[string]interface{}{}
+		if v, ok := pipelineTemplate.Annotations[templateValuesAnnotation]; ok {
+			if err := json.Unmarshal([]byte(v), &values); err != nil {
+				return nil, xerrors.Errorf("failed to parse %s annotation: %w", templateValuesAnnotation, err)
+			}
+		}
+		result, err := newTemplateRenderer(func(exportName string) (string, error) {
+			return r.getCloudFormationExportValue(ctx, exportName)
+		}).renderJSON(pipelineTemplate.Spec.Raw, values)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to render template: %w", err)
+		}
+		return result, nil
+	default:
+		return nil, xerrors.Errorf("unknown template engine: %s", engine)
+	}
+}
+
+func (r *PipelineTemplateReconciler) processTemplateVariables(ctx context.Context, yamlData []byte) (result []byte, err error) {
 	variablePattern := regexp.MustCompile(`\$\{([^}]+)\}`)
 
 	result = variablePattern.ReplaceAllFunc(yamlData, func(match []byte) []byte {
@@ -159,7 +283,7 @@ func (r *PipelineTemplateReconciler) processTemplateVariables(yamlData []byte) (
 
 		if strings.HasPrefix(expression, "ImportValue:") {
 			exportName := strings.TrimPrefix(expression, "ImportValue:")
-			exportValue, ierr := r.getCloudFormationExportValue(exportName)
+			exportValue, ierr := r.getCloudFormationExportValue(ctx, exportName)
 			if ierr != nil {
 				err = ierr
 				return match
@@ -173,9 +297,7 @@ func (r *PipelineTemplateReconciler) processTemplateVariables(yamlData []byte) (
 	return result, err
 }
 
-func (r *PipelineTemplateReconciler) getCloudFormationExportValue(exportName string) (string, error) {
-	ctx := context.Background()
-
+func (r *PipelineTemplateReconciler) getCloudFormationExportValue(ctx context.Context, exportName string) (string, error) {
 	configuration, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
 		return "", xerrors.Errorf("failed to load AWS configuration: %w", err)
@@ -184,7 +306,9 @@ func (r *PipelineTemplateReconciler) getCloudFormationExportValue(exportName str
 
 	input := &cloudformation.ListExportsInput{}
 	for {
-		output, err := c.ListExports(ctx, input)
+		callCtx, span := tracing.Start(ctx, "CloudFormation ListExports", attribute.String("aws.cloudformation.export", exportName))
+		output, err := c.ListExports(callCtx, input)
+		tracing.End(span, err)
 		if err != nil {
 			return "", xerrors.Errorf("failed to list CloudFormation exports: %w", err)
 		}
@@ -208,5 +332,10 @@ func (r *PipelineTemplateReconciler) getCloudFormationExportValue(exportName str
 }
 
 func (r *PipelineTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).For(&v1.PipelineTemplate{}).Complete(r)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.PipelineTemplate{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.PipelineTemplate{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.PipelineTemplateList{}), &handler.EnqueueRequestForObject{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(newRequeueOnCancel(r, r.Log))
 }
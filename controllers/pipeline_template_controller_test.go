@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"testing"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const envtestTemplateSpec = `
+schema: "1"
+id: %s
+metadata:
+  name: deploy
+  description: %s
+  owner: anonymous
+  scopes:
+    - global
+protect: false
+variables:
+  - name: replicas
+    defaultValue: 1
+configuration:
+  concurrentExecutions:
+    limitConcurrent: true
+    parallel: false
+`
+
+func TestPipelineTemplateLifecycle(t *testing.T) {
+	requireEnvironment(t)
+	defer testGate.ClearFailures()
+
+	key := client.ObjectKey{Name: "envtest-template"}
+	// A failure of the idempotent existence check is retried by the transport without the reconciler noticing.
+	testGate.Fail(http.MethodGet, "/pipelineTemplates/"+key.Name, http.StatusServiceUnavailable, 1)
+
+	pipelineTemplate := &v1.PipelineTemplate{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name},
+		Spec:       rawSpec(templateManifest(key.Name, "before")),
+	}
+	create(t, pipelineTemplate)
+	eventually(t, func() error {
+		saved, ok := testGate.PipelineTemplate(key.Name)
+		if !ok {
+			return xerrors.New("pipeline template is not published")
+		}
+		if description := saved["metadata"].(map[string]interface{})["description"]; description != "before" {
+			return xerrors.Errorf("unexpected description: %v", description)
+		}
+		return nil
+	})
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, pipelineTemplate); err != nil {
+			return err
+		}
+		if pipelineTemplate.Status.SpinnakerResource.ID != key.Name {
+			return xerrors.Errorf("unexpected status: %+v", pipelineTemplate.Status)
+		}
+		return nil
+	})
+
+	update(t, key, pipelineTemplate, func() {
+		pipelineTemplate.Spec = rawSpec(templateManifest(key.Name, "after"))
+	})
+	eventually(t, func() error {
+		saved, _ := testGate.PipelineTemplate(key.Name)
+		if description := saved["metadata"].(map[string]interface{})["description"]; description != "after" {
+			return xerrors.Errorf("unexpected description: %v", description)
+		}
+		return nil
+	})
+	if count := testGate.CountRequests(http.MethodPost, "/pipelineTemplates/"+key.Name); count != 1 {
+		t.Fatalf("expected the update to be published to the existing template once, got %d", count)
+	}
+
+	remove(t, pipelineTemplate)
+	waitForDeletion(t, key, &v1.PipelineTemplate{})
+	if _, ok := testGate.PipelineTemplate(key.Name); ok {
+		t.Fatal("pipeline template is not deleted from Spinnaker")
+	}
+}
+
+func templateManifest(id string, description string) string {
+	return fmt.Sprintf(envtestTemplateSpec, id, description)
+}
@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
+
+	"github.com/spinnaker/roer/spinnaker"
+
+	"github.com/go-logr/logr"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	ProjectUpsertTaskType string = "upsertProject"
+	ProjectDeleteTaskType string = "deleteProject"
+
+	// projectTaskApplication is the application Deck submits project tasks to, since projects do not belong to one
+	projectTaskApplication = "spinnaker"
+)
+
+type ProjectReconciler struct {
+	client.Client
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Gateway                 gateway.Client
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Revisions               *Revisions
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
+}
+
+type projectConfig struct {
+	Applications    []string                `json:"applications"`
+	PipelineConfigs []projectPipelineConfig `json:"pipelineConfigs"`
+}
+
+type projectPipelineConfig struct {
+	Application      string `json:"application"`
+	PipelineConfigID string `json:"pipelineConfigId"`
+	PipelineName     string `json:"pipelineName"`
+}
+
+func (r *ProjectReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
+	project := &v1.Project{}
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "Project", req)
+	defer func() { endReconcileSpan(span, project, err) }()
+	logger := r.Log.WithValues("project", req.NamespacedName)
+	if err := r.Get(ctx, req.NamespacedName, project); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, project) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, project); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, project, containsString(project.ObjectMeta.Finalizers, myFinalizerName))
+	if rolledBack, err := r.rollback(ctx, project); rolledBack {
+		return ctrl.Result{}, err
+	}
+
+	if project.ObjectMeta.DeletionTimestamp.IsZero() {
+		hash := fmt.Sprintf("%x", sha256.Sum256(project.Spec.Raw))
+		oldHash := project.Status.Hash
+		if oldHash != hash {
+			config := r.parseConfig(project)
+			created, err := r.areApplicationsCreated(ctx, config)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !created {
+				logger.V(1).Info("wait for applications to be created")
+				return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+			}
+			pipelineConfigs, resolved, err := r.resolvePipelineConfigs(ctx, config)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if !resolved {
+				logger.V(1).Info("wait for pipelines to be saved")
+				return ctrl.Result{RequeueAfter: r.Settings.get().DependencyWaitInterval}, nil
+			}
+
+			id, err := r.getProjectID(ctx, req.Name)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if conflict := r.findConflict(project, req.Name, id); conflict != "" {
+				r.setCondition(project, v1.ProjectConflict, "True", conflict)
+				r.Recorder.Eventf(project, coreV1.EventTypeWarning, "ConflictingProject", conflict)
+				if err := r.Update(ctx, project); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{RequeueAfter: r.Settings.get().InvalidResyncInterval}, nil
+			}
+			r.setCondition(project, v1.ProjectConflict, "False", "")
+			if project.Status.SpinnakerResource.ProjectName != req.Name || !containsString(project.ObjectMeta.Finalizers, myFinalizerName) {
+				// The name is recorded before the project is created, so that a retry takes over the project created
+				// by an attempt whose status was not recorded instead of reporting it as a conflict.
+				project.Status.SpinnakerResource.ProjectName = req.Name
+				if !containsString(project.ObjectMeta.Finalizers, myFinalizerName) {
+					project.ObjectMeta.Finalizers = append(project.ObjectMeta.Finalizers, myFinalizerName)
+				}
+				if err := r.Update(ctx, project); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+
+			operation := audit.OperationUpdate
+			conditionType := v1.ProjectUpdateComplete
+			if id == "" {
+				operation = audit.OperationCreate
+				conditionType = v1.ProjectCreationComplete
+			}
+			task := r.buildTask(req.Name, r.buildProject(req.Name, id, project, pipelineConfigs), ProjectUpsertTaskType)
+			response, err := r.submitTask(ctx, project, req.Name, task, operation)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if err := taskError(response.ID, response.Status); err != nil {
+				// The hash is left as it is, so that the project is saved again
+				r.setCondition(project, conditionType, "False", err.Error())
+				r.Recorder.Eventf(project, coreV1.EventTypeWarning, "FailedSaved", "Failed to save project %q: %v", req.Name, err)
+				if err := r.Update(ctx, project); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, err
+			}
+			if id == "" {
+				id, err = r.getProjectID(ctx, req.Name)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			project.Status.SpinnakerResource.ID = id
+			project.Status.Hash = hash
+			r.setCondition(project, conditionType, "True", "")
+			if operation == audit.OperationCreate {
+				r.Recorder.Eventf(project, coreV1.EventTypeNormal, "SuccessfulCreated", "Created project: %q", req.Name)
+				logger.V(1).Info("create", "project", project)
+			} else {
+				r.Recorder.Eventf(project, coreV1.EventTypeNormal, "SuccessfulUpdated", "Updated project: %q", req.Name)
+				logger.V(1).Info("update", "project", project)
+			}
+			if err := r.Update(ctx, project); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	} else {
+		if containsString(project.ObjectMeta.Finalizers, myFinalizerName) {
+			id := project.Status.SpinnakerResource.ID
+			if id == "" && project.Status.SpinnakerResource.ProjectName == req.Name {
+				// The project may have been created by an attempt whose status was not recorded
+				var err error
+				id, err = r.getProjectID(ctx, req.Name)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			if id != "" && !r.Settings.retain(r.Recorder, project) {
+				task := r.buildTask(req.Name, map[string]interface{}{"id": id}, ProjectDeleteTaskType)
+				response, err := r.submitTask(ctx, project, req.Name, task, audit.OperationDelete)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if err := taskError(response.ID, response.Status); err != nil {
+					// The finalizer is kept, so that the project is not left behind in Spinnaker
+					r.setCondition(project, v1.ProjectDeletionComplete, "False", err.Error())
+					r.Recorder.Eventf(project, coreV1.EventTypeWarning, "FailedDeleted", "Failed to delete project %q: %v", req.Name, err)
+					if err := r.Update(ctx, project); err != nil {
+						return ctrl.Result{}, err
+					}
+					return ctrl.Result{}, err
+				}
+				r.setCondition(project, v1.ProjectDeletionComplete, "True", "")
+				r.Recorder.Eventf(project, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted project: %q", req.Name)
+				logger.V(1).Info("delete", "project", project)
+			}
+
+			project.ObjectMeta.Finalizers = removeString(project.ObjectMeta.Finalizers, myFinalizerName)
+			if err := r.Update(ctx, project); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// findConflict describes why the project of name, whose ID is id, belongs to someone else, or returns an empty string.
+// The controller owns the project whose ID it has recorded, and the one of the name it recorded before creating it, so
+// that it never takes over a project created in Deck.
+func (r *ProjectReconciler) findConflict(project *v1.Project, name string, id string) string {
+	resource := project.Status.SpinnakerResource
+	if id == "" || id == resource.ID || (resource.ID == "" && resource.ProjectName == name) {
+		return ""
+	}
+	return fmt.Sprintf("project %s already exists and was not created by this controller", name)
+}
+
+func (r *ProjectReconciler) setCondition(project *v1.Project, conditionType v1.ProjectConditionType, status string, message string) {
+	for i, condition := range project.Status.Conditions {
+		if condition.Type == conditionType {
+			project.Status.Conditions[i].Status = status
+			project.Status.Conditions[i].Message = message
+			return
+		}
+	}
+	project.Status.Conditions = append(project.Status.Conditions, v1.ProjectCondition{
+		Type:    conditionType,
+		Status:  status,
+		Message: message,
+	})
+}
+
+func (r *ProjectReconciler) parseConfig(project *v1.Project) projectConfig {
+	var spec struct {
+		Config projectConfig `json:"config"`
+	}
+	_ = json.Unmarshal(project.Spec.Raw, &spec)
+	return spec.Config
+}
+
+// areApplicationsCreated returns false while any referenced Application CR has not been created in Spinnaker yet.
+// Applications that are not managed by this controller are assumed to exist.
+func (r *ProjectReconciler) areApplicationsCreated(ctx context.Context, config projectConfig) (bool, error) {
+	applicationNames := append([]string{}, config.Applications...)
+	for _, pipelineConfig := range config.PipelineConfigs {
+		applicationNames = append(applicationNames, pipelineConfig.Application)
+	}
+
+	for _, applicationName := range applicationNames {
+		if applicationName == "" {
+			continue
+		}
+		application := &v1.Application{}
+		if err := r.Get(ctx, client.ObjectKey{Name: applicationName}, application); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if application.Status.SpinnakerResource.ApplicationName == "" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// resolvePipelineConfigs fills pipelineConfigId from pipelineName, so that pipelines can be referenced by name.
+func (r *ProjectReconciler) resolvePipelineConfigs(ctx context.Context, config projectConfig) ([]map[string]interface{}, bool, error) {
+	pipelineConfigs := make([]map[string]interface{}, 0, len(config.PipelineConfigs))
+	for _, pipelineConfig := range config.PipelineConfigs {
+		id := pipelineConfig.PipelineConfigID
+		if id == "" && pipelineConfig.PipelineName != "" {
+			var err error
+			id, err = getPipelineConfigID(r.Gateway.Gate(ctx), pipelineConfig.Application, pipelineConfig.PipelineName)
+			if err != nil {
+				return nil, false, err
+			}
+			if id == "" {
+				return nil, false, nil
+			}
+		}
+		pipelineConfigs = append(pipelineConfigs, map[string]interface{}{
+			"application":      pipelineConfig.Application,
+			"pipelineConfigId": id,
+		})
+	}
+	return pipelineConfigs, true, nil
+}
+
+func (r *ProjectReconciler) getProjectID(ctx context.Context, projectName string) (string, error) {
+	gateClient := r.Gateway.Gate(ctx)
+	project, resp, err := gateClient.ProjectControllerApi.GetUsingGET1(gateClient.Context, projectName)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	id, _ := project["id"].(string)
+	return id, nil
+}
+
+func (r *ProjectReconciler) buildProject(projectName string, id string, project *v1.Project, pipelineConfigs []map[string]interface{}) map[string]interface{} {
+	var m map[string]interface{}
+	_ = json.Unmarshal(project.Spec.Raw, &m)
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	m["name"] = projectName
+	if id != "" {
+		m["id"] = id
+	}
+	config, _ := m["config"].(map[string]interface{})
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	if _, ok := config["applications"]; !ok {
+		config["applications"] = []interface{}{}
+	}
+	if _, ok := config["clusters"]; !ok {
+		config["clusters"] = []interface{}{}
+	}
+	config["pipelineConfigs"] = pipelineConfigs
+	m["config"] = config
+	return m
+}
+
+func (r *ProjectReconciler) submitTask(ctx context.Context, project *v1.Project, projectName string, task spinnaker.Task, operation audit.Operation) (*spinnaker.ExecutionResponse, error) {
+	change := audit.Change{
+		Operation: operation,
+		Object:    "project",
+		ID:        projectName,
+		Before: auditBefore(r.Audit, func() (interface{}, error) {
+			gateClient := r.Gateway.Gate(ctx)
+			existing, resp, err := gateClient.ProjectControllerApi.GetUsingGET1(gateClient.Context, projectName)
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, nil
+			}
+			return existing, err
+		}),
+	}
+	if operation != audit.OperationDelete {
+		change.After = task.Job[0].(map[string]interface{})["project"]
+	}
+
+	ref, err := r.Gateway.Roer(ctx).ApplicationSubmitTask(projectTaskApplication, task)
+	if err != nil {
+		r.Audit.Log(project, change, err)
+		return nil, err
+	}
+	response, err := pollTask(ctx, r.Gateway, ref.Ref, r.Settings.get().TaskPollTimeout)
+	if err != nil {
+		r.Audit.Log(project, change, err)
+		return nil, err
+	}
+	r.Audit.Log(project, change, taskError(ref.Ref, response.Status))
+	if operation != audit.OperationDelete && response.Status != executionStatusTerminal {
+		if err := r.Revisions.record(ctx, project, change.After, response.ID); err != nil {
+			r.Log.Error(err, "unable to record revision", "project", project.Name)
+		}
+	}
+
+	return response, nil
+}
+
+func (r *ProjectReconciler) rollback(ctx context.Context, project *v1.Project) (bool, error) {
+	return r.Revisions.rollback(ctx, r.Client, r.Recorder, project, func(ctx context.Context, payload json.RawMessage) error {
+		var m map[string]interface{}
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return err
+		}
+		projectName, _ := m["name"].(string)
+		// The revision was recorded without ID when the project was created, and upserting without ID creates another
+		id, err := r.getProjectID(ctx, projectName)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			m["id"] = id
+		}
+		response, err := r.submitTask(ctx, project, projectName, r.buildTask(projectName, m, ProjectUpsertTaskType), audit.OperationUpdate)
+		if err != nil {
+			return err
+		}
+		return taskError(response.ID, response.Status)
+	})
+}
+
+func (r *ProjectReconciler) buildTask(projectName string, project map[string]interface{}, taskType string) spinnaker.Task {
+	return spinnaker.Task{
+		Application: projectTaskApplication,
+		Description: fmt.Sprintf("Execute %s task: %s", taskType, projectName),
+		Job: []interface{}{
+			map[string]interface{}{
+				"type":    taskType,
+				"project": project,
+			},
+		},
+	}
+}
+
+func (r *ProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Project{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.Project{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.ProjectList{}), &handler.EnqueueRequestForObject{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(newRequeueOnCancel(r, r.Log))
+}
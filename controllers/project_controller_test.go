@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/gateway"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProjectLifecycle(t *testing.T) {
+	requireEnvironment(t)
+	defer testGate.ClearFailures()
+
+	applicationKey := client.ObjectKey{Name: "envtest-project-app"}
+	key := client.ObjectKey{Name: "envtest-project"}
+
+	// The application cannot be created until the failure is cleared, so that the project has to wait for it.
+	testGate.Fail(http.MethodPost, "/applications/"+applicationKey.Name+"/tasks", http.StatusInternalServerError, 0)
+	application := &v1.Application{
+		ObjectMeta: metaV1.ObjectMeta{Name: applicationKey.Name},
+		Spec:       rawSpec("email: project@example.com"),
+	}
+	create(t, application)
+	project := &v1.Project{
+		ObjectMeta: metaV1.ObjectMeta{Name: key.Name},
+		Spec:       rawSpec(projectManifest("before@example.com")),
+	}
+	create(t, project)
+
+	consistently(t, 3*time.Second, func() error {
+		if _, ok := testGate.Project(key.Name); ok {
+			return xerrors.New("project is saved before its application is created")
+		}
+		return nil
+	})
+
+	testGate.ClearFailures()
+	eventually(t, func() error {
+		if _, ok := testGate.Project(key.Name); !ok {
+			return xerrors.New("project is not saved")
+		}
+		return nil
+	})
+	saved, _ := testGate.Project(key.Name)
+	id := saved["id"]
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, project); err != nil {
+			return err
+		}
+		if project.Status.SpinnakerResource.ID != id || !containsString(project.Finalizers, myFinalizerName) {
+			return xerrors.Errorf("unexpected status: %+v", project.Status)
+		}
+		return nil
+	})
+
+	update(t, key, project, func() {
+		project.Spec = rawSpec(projectManifest("after@example.com"))
+	})
+	eventually(t, func() error {
+		saved, _ := testGate.Project(key.Name)
+		if saved["email"] != "after@example.com" {
+			return xerrors.Errorf("project is not updated: %v", saved)
+		}
+		if saved["id"] != id {
+			return xerrors.Errorf("project is saved as another one: %v, expected %v", saved["id"], id)
+		}
+		return nil
+	})
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, project); err != nil {
+			return err
+		}
+		if len(project.Status.Conditions) != 3 {
+			return xerrors.Errorf("expected Conflict, CreationComplete and UpdateComplete, got %+v", project.Status.Conditions)
+		}
+		return nil
+	})
+
+	remove(t, project)
+	waitForDeletion(t, key, &v1.Project{})
+	if _, ok := testGate.Project(key.Name); ok {
+		t.Fatal("project is not deleted from Spinnaker")
+	}
+
+	remove(t, application)
+	waitForDeletion(t, applicationKey, &v1.Application{})
+}
+
+func projectManifest(email string) string {
+	return `
+email: ` + email + `
+config:
+  applications:
+    - envtest-project-app
+`
+}
+
+func newProjectReconciler(gatewayClient gateway.Client, project *v1.Project) *ProjectReconciler {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	return &ProjectReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme, project),
+		Log:      ctrl.Log.WithName("controllers").WithName("Project"),
+		Recorder: record.NewFakeRecorder(100),
+		Gateway:  gatewayClient,
+	}
+}
+
+func reconcileProject(r *ProjectReconciler, name string) (*v1.Project, error) {
+	key := client.ObjectKey{Name: name}
+	_, reconcileErr := r.Reconcile(ctrl.Request{NamespacedName: key})
+	project := &v1.Project{}
+	if err := r.Get(context.Background(), key, project); err != nil {
+		return nil, err
+	}
+	return project, reconcileErr
+}
+
+func TestProjectRefusesForeignProject(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	server.AddProject("sample")
+	r := newProjectReconciler(gatewayClient, &v1.Project{
+		ObjectMeta: metaV1.ObjectMeta{Name: "sample"},
+		Spec:       rawSpec("email: sample@example.com"),
+	})
+
+	project, err := reconcileProject(r, "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Status.Conditions) != 1 || project.Status.Conditions[0].Type != v1.ProjectConflict || project.Status.Conditions[0].Status != "True" {
+		t.Fatalf("expected the Conflict condition, got %+v", project.Status.Conditions)
+	}
+	if saved, _ := server.Project("sample"); saved["email"] != nil || project.Status.Hash != "" || len(project.Finalizers) != 0 {
+		t.Fatalf("expected the project not to be taken over, got %v, %+v", saved, project)
+	}
+}
+
+func TestProjectAdoptsProjectOfPreviousAttempt(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	server.AddProject("sample")
+	project := &v1.Project{
+		ObjectMeta: metaV1.ObjectMeta{Name: "sample"},
+		Spec:       rawSpec("email: sample@example.com"),
+	}
+	project.Status.SpinnakerResource.ProjectName = "sample"
+	r := newProjectReconciler(gatewayClient, project)
+
+	project, err := reconcileProject(r, "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := server.Project("sample")
+	if saved["email"] != "sample@example.com" || project.Status.SpinnakerResource.ID != saved["id"] {
+		t.Fatalf("expected the project created before the status was recorded to be taken over, got %v, %+v", saved, project.Status)
+	}
+}
+
+func TestProjectRetriesFailedTasks(t *testing.T) {
+	server, gatewayClient := newFakeGate(t)
+	r := newProjectReconciler(gatewayClient, &v1.Project{
+		ObjectMeta: metaV1.ObjectMeta{Name: "sample"},
+		Spec:       rawSpec("email: sample@example.com"),
+	})
+
+	server.FailTasks(1)
+	project, err := reconcileProject(r, "sample")
+	if err == nil || project.Status.Hash != "" {
+		t.Fatalf("expected a failed task to be retried, got %v, %+v", err, project.Status)
+	}
+	project, err = reconcileProject(r, "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Status.Hash == "" || project.Status.SpinnakerResource.ID == "" {
+		t.Fatalf("expected the project to be created, got %+v", project.Status)
+	}
+	creations := 0
+	for _, condition := range project.Status.Conditions {
+		if condition.Type == v1.ProjectCreationComplete {
+			creations++
+			if condition.Status != "True" {
+				t.Errorf("expected CreationComplete to be True, got %+v", condition)
+			}
+		}
+	}
+	if creations != 1 {
+		t.Fatalf("expected one CreationComplete condition, got %+v", project.Status.Conditions)
+	}
+
+	// The fake client deletes objects at once, so that the deletion is started by setting the timestamp.
+	now := metaV1.Now()
+	project.DeletionTimestamp = &now
+	if err := r.Update(context.Background(), project); err != nil {
+		t.Fatal(err)
+	}
+	server.FailTasks(1)
+	project, err = reconcileProject(r, "sample")
+	if err == nil || !containsString(project.Finalizers, myFinalizerName) {
+		t.Fatalf("expected the finalizer to be kept after a failed deletion, got %v, %v", err, project.Finalizers)
+	}
+	if _, ok := server.Project("sample"); !ok {
+		t.Fatal("expected the project to be left until it is deleted")
+	}
+	project, err = reconcileProject(r, "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Project("sample"); ok || containsString(project.Finalizers, myFinalizerName) {
+		t.Fatalf("expected the project to be deleted, got %v", project.Finalizers)
+	}
+}
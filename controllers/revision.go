@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// rollbackAnnotation requests re-applying the payload of a revision, e.g. "3"
+	rollbackAnnotation = "spinnaker.kaidotdev.github.io/rollback-to"
+	// revisionOwnerLabel selects the ControllerRevisions of a resource by its UID, since names may be too long for a label
+	revisionOwnerLabel = "spinnaker.kaidotdev.github.io/owner-uid"
+)
+
+// Revisions keeps the last payloads applied to Spinnaker per resource in ControllerRevisions, as the apps controllers
+// do, so that a resource can be rolled back to one of them. A nil Revisions keeps no history.
+type Revisions struct {
+	// Client creates and deletes ControllerRevisions
+	Client client.Client
+	// Reader lists ControllerRevisions without the cache of the manager, which would watch them in all namespaces
+	Reader client.Reader
+	Scheme *runtime.Scheme
+	// Namespace is where ControllerRevisions are kept, since the resources are cluster-scoped
+	Namespace string
+	// Limit is the number of revisions kept per resource
+	Limit int
+}
+
+type rollbackKey struct{}
+
+// revisionData is the data of a ControllerRevision
+type revisionData struct {
+	Payload json.RawMessage `json:"payload"`
+	// TaskID is the Spinnaker task that applied the payload, if any
+	TaskID string `json:"taskId,omitempty"`
+	// RollbackOf is the revision whose payload was re-applied, if any
+	RollbackOf int64 `json:"rollbackOf,omitempty"`
+}
+
+// record stores payload applied to Spinnaker for object by the task taskID, if any, as the next revision, and deletes the
+// revisions over the limit. Payloads applied in rollback are recorded with the revision they came from.
+func (r *Revisions) record(ctx context.Context, object pausable, payload interface{}, taskID string) error {
+	if r == nil {
+		return nil
+	}
+	revisions, err := r.list(ctx, object)
+	if err != nil {
+		return err
+	}
+	next := int64(1)
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	rollbackOf, _ := ctx.Value(rollbackKey{}).(int64)
+	data, err := json.Marshal(revisionData{Payload: payloadJSON, TaskID: taskID, RollbackOf: rollbackOf})
+	if err != nil {
+		return err
+	}
+	gvk, err := apiutil.GVKForObject(object, r.Scheme)
+	if err != nil {
+		return err
+	}
+	revision := &appsV1.ControllerRevision{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%d", strings.ToLower(gvk.Kind), object.GetName(), next),
+			Namespace: r.Namespace,
+			Labels:    map[string]string{revisionOwnerLabel: string(object.GetUID())},
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: next,
+	}
+	// Revisions are garbage collected with their resource.
+	if err := controllerutil.SetControllerReference(object, revision, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Client.Create(ctx, revision); err != nil {
+		return err
+	}
+
+	revisions = append(revisions, *revision)
+	for len(revisions) > r.Limit {
+		if err := r.Client.Delete(ctx, &revisions[0]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		revisions = revisions[1:]
+	}
+	return nil
+}
+
+// get returns the data of revision of object, or nil when it is not kept.
+func (r *Revisions) get(ctx context.Context, object metaV1.Object, revision int64) (*revisionData, error) {
+	revisions, err := r.list(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range revisions {
+		if candidate.Revision != revision {
+			continue
+		}
+		var data revisionData
+		if err := json.Unmarshal(candidate.Data.Raw, &data); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+	return nil, nil
+}
+
+// list returns the revisions of object from the oldest.
+func (r *Revisions) list(ctx context.Context, object metaV1.Object) ([]appsV1.ControllerRevision, error) {
+	var list appsV1.ControllerRevisionList
+	if err := r.Reader.List(ctx, &list, client.InNamespace(r.Namespace), client.MatchingLabels{revisionOwnerLabel: string(object.GetUID())}); err != nil {
+		return nil, err
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Revision < list.Items[j].Revision
+	})
+	return list.Items, nil
+}
+
+// rollback re-applies the payload of the revision requested by the rollback annotation of object with apply, which
+// records it as the next revision as any other push, and removes the annotation. It returns true when a rollback was
+// requested, in which case the caller must return err. The payload stays in Spinnaker until the spec of object is
+// changed.
+func (r *Revisions) rollback(ctx context.Context, c client.Client, recorder record.EventRecorder, object pausable, apply func(ctx context.Context, payload json.RawMessage) error) (bool, error) {
+	value, ok := object.GetAnnotations()[rollbackAnnotation]
+	if !ok || !object.GetDeletionTimestamp().IsZero() {
+		return false, nil
+	}
+
+	revision, err := strconv.ParseInt(value, 10, 64)
+	switch {
+	case r == nil:
+		recorder.Event(object, coreV1.EventTypeWarning, "RollbackFailed", "Revision history is disabled")
+	case err != nil:
+		recorder.Eventf(object, coreV1.EventTypeWarning, "RollbackFailed", "Invalid revision %q", value)
+	default:
+		data, err := r.get(ctx, object, revision)
+		if err != nil {
+			return true, err
+		}
+		if data == nil {
+			recorder.Eventf(object, coreV1.EventTypeWarning, "RollbackFailed", "Revision %d is not found", revision)
+			break
+		}
+		if err := apply(context.WithValue(ctx, rollbackKey{}, revision), data.Payload); err != nil {
+			recorder.Eventf(object, coreV1.EventTypeWarning, "RollbackFailed", "Failed to roll back to revision %d: %s", revision, err)
+			return true, err
+		}
+		recorder.Eventf(object, coreV1.EventTypeNormal, "SuccessfulRolledBack", "Rolled back to revision %d", revision)
+	}
+
+	annotations := object.GetAnnotations()
+	delete(annotations, rollbackAnnotation)
+	object.SetAnnotations(annotations)
+	return true, c.Update(ctx, object)
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"testing"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRevisionsRecordAndRollback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1.AddToScheme(scheme)
+	pipelineTemplate := &v1.PipelineTemplate{
+		ObjectMeta: metaV1.ObjectMeta{Name: "sample", UID: "5f0c"},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, pipelineTemplate)
+	revisions := &Revisions{Client: c, Reader: c, Scheme: scheme, Namespace: "spinnaker-dcd-controller", Limit: 2}
+	ctx := context.Background()
+
+	for i, description := range []string{"first", "second", "third"} {
+		if err := revisions.record(ctx, pipelineTemplate, map[string]interface{}{"description": description}, "task-"+description); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+	}
+	kept, err := revisions.list(ctx, pipelineTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0].Revision != 2 || kept[1].Revision != 3 {
+		t.Fatalf("expected revisions 2 and 3 to be kept, got %v", kept)
+	}
+	if kept[0].Name != "pipelinetemplate-sample-2" {
+		t.Errorf("unexpected name %q", kept[0].Name)
+	}
+
+	if err := c.Get(ctx, client.ObjectKey{Name: pipelineTemplate.Name}, pipelineTemplate); err != nil {
+		t.Fatal(err)
+	}
+	pipelineTemplate.Annotations = map[string]string{rollbackAnnotation: "2"}
+	recorder := record.NewFakeRecorder(10)
+	var applied map[string]interface{}
+	rolledBack, err := revisions.rollback(ctx, c, recorder, pipelineTemplate, func(ctx context.Context, payload json.RawMessage) error {
+		if err := json.Unmarshal(payload, &applied); err != nil {
+			return err
+		}
+		return revisions.record(ctx, pipelineTemplate, applied, "task-rollback")
+	})
+	if !rolledBack || err != nil {
+		t.Fatalf("expected rollback, got %v, %v", rolledBack, err)
+	}
+	if applied["description"] != "second" {
+		t.Errorf("expected payload of revision 2 to be applied, got %v", applied)
+	}
+	data, err := revisions.get(ctx, pipelineTemplate, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data == nil || data.RollbackOf != 2 || data.TaskID != "task-rollback" {
+		t.Errorf("expected revision 4 to record the rollback of revision 2, got %+v", data)
+	}
+	if _, ok := pipelineTemplate.Annotations[rollbackAnnotation]; ok {
+		t.Error("expected rollback annotation to be removed")
+	}
+
+	pipelineTemplate.Annotations = map[string]string{rollbackAnnotation: "1"}
+	rolledBack, err = revisions.rollback(ctx, c, recorder, pipelineTemplate, func(ctx context.Context, payload json.RawMessage) error {
+		t.Error("expected pruned revision not to be applied")
+		return nil
+	})
+	if !rolledBack || err != nil {
+		t.Fatalf("expected rollback of pruned revision to be given up, got %v, %v", rolledBack, err)
+	}
+	if event := <-recorder.Events; event != "Normal SuccessfulRolledBack Rolled back to revision 2" {
+		t.Errorf("unexpected event %q", event)
+	}
+	if event := <-recorder.Events; event != "Warning RollbackFailed Revision 1 is not found" {
+		t.Errorf("unexpected event %q", event)
+	}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"reflect"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// selected returns true when object is managed by this controller, i.e. matches selector, so that several deployments
+// of the controller can share a cluster by label. A nil selector selects everything.
+func selected(selector labels.Selector, object metaV1.Object) bool {
+	return selector == nil || selector.Matches(labels.Set(object.GetLabels()))
+}
+
+// selectorPredicate drops events of objects of the kind of kind that do not match selector. Events of other kinds, such
+// as dependencies watched to enqueue their dependents, pass through, since the dependents are checked on reconciliation.
+func selectorPredicate(selector labels.Selector, kind runtime.Object) predicate.Predicate {
+	kindType := reflect.TypeOf(kind)
+	matches := func(meta metaV1.Object, object runtime.Object) bool {
+		return reflect.TypeOf(object) != kindType || selected(selector, meta)
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return matches(e.Meta, e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return matches(e.MetaNew, e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return matches(e.Meta, e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return matches(e.Meta, e.Object)
+		},
+	}
+}
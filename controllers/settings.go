@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"fmt"
+	"spinnaker-dcd-controller/internal/config"
+	"sync"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	deletionPolicyAnnotation = "spinnaker.kaidotdev.github.io/deletion-policy"
+
+	taskPollTimeout = 30 * time.Second
+)
+
+// SettingValues are the timings and defaults of reconciliation
+type SettingValues struct {
+	// DependencyWaitInterval is how often a resource checks whether its dependencies have been created
+	DependencyWaitInterval time.Duration
+	// ExecutionPollInterval is how often an execution in flight is polled
+	ExecutionPollInterval time.Duration
+	// ExecutionResyncInterval is how often the last execution of a pipeline is checked while it is idle, backing off up
+	// to 32 times as long the longer it has been idle
+	ExecutionResyncInterval time.Duration
+	// ExecutionTriggerTimeout is how long a triggered PipelineExecution is searched for before it fails
+	ExecutionTriggerTimeout time.Duration
+	// InvalidResyncInterval is how often an invalid resource is checked again
+	InvalidResyncInterval time.Duration
+	// TaskPollTimeout is how long a task submitted to Orca is polled until it finishes
+	TaskPollTimeout time.Duration
+	// DeletionPolicy is used for resources without the deletion-policy annotation
+	DeletionPolicy string
+}
+
+// DefaultSettingValues returns the values used when none are given
+func DefaultSettingValues() SettingValues {
+	return SettingValues{
+		DependencyWaitInterval:  dependencyWaitInterval,
+		ExecutionPollInterval:   executionPollInterval,
+		ExecutionResyncInterval: executionResyncInterval,
+		ExecutionTriggerTimeout: executionTriggerTimeout,
+		InvalidResyncInterval:   invalidCanaryConfigResyncInterval,
+		TaskPollTimeout:         taskPollTimeout,
+		DeletionPolicy:          config.DeletionPolicyDelete,
+	}
+}
+
+// Settings holds the values shared by all reconcilers, which can be replaced while they are running, e.g. when the
+// configuration file is reloaded. A nil Settings returns the defaults.
+type Settings struct {
+	mu     sync.RWMutex
+	values SettingValues
+}
+
+// NewSettings returns Settings holding values
+func NewSettings(values SettingValues) *Settings {
+	return &Settings{values: values}
+}
+
+// Set replaces the values, which take effect from the next reconciliation
+func (s *Settings) Set(values SettingValues) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = values
+}
+
+func (s *Settings) get() SettingValues {
+	if s == nil {
+		return DefaultSettingValues()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values
+}
+
+// retain returns true when the Spinnaker resource of object must be left in place on its deletion, in which case the
+// caller only removes the finalizer.
+func (s *Settings) retain(recorder record.EventRecorder, object pausable) bool {
+	policy := object.GetAnnotations()[deletionPolicyAnnotation]
+	if policy == "" {
+		policy = s.get().DeletionPolicy
+	}
+	if policy != config.DeletionPolicyRetain {
+		return false
+	}
+	recorder.Event(object, coreV1.EventTypeNormal, "Retained", fmt.Sprintf("Retained Spinnaker resource by deletion policy %s", policy))
+	return true
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/sharding"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// managed returns true when this replica reconciles object, i.e. object matches selector and its Spinnaker
+// application belongs to a shard owned by this replica.
+func managed(ctx context.Context, c client.Reader, selector labels.Selector, shards *sharding.Shards, object metaV1.Object) bool {
+	if !selected(selector, object) {
+		return false
+	}
+	return shards.Owns(shardKey(ctx, c, object))
+}
+
+// shardKey returns the name of the Spinnaker application of object, so that all resources of one application are
+// reconciled by one replica in order. Resources that do not belong to one application are keyed by their name.
+func shardKey(ctx context.Context, c client.Reader, object metaV1.Object) string {
+	switch o := object.(type) {
+	case *v1.Pipeline:
+		return pipelineApplication(o, o.Name)
+	case *v1.CanaryConfig:
+		if applications := parseCanaryConfigApplications(o); len(applications) > 0 {
+			return applications[0]
+		}
+	case *v1.CanaryAnalysis:
+		if o.Spec.Application != "" {
+			return o.Spec.Application
+		}
+	case *v1.PipelineExecution:
+		if o.Status.SpinnakerResource.ApplicationName != "" {
+			return o.Status.SpinnakerResource.ApplicationName
+		}
+		pipeline := &v1.Pipeline{}
+		if err := c.Get(ctx, client.ObjectKey{Name: o.Spec.PipelineName}, pipeline); err == nil {
+			return pipelineApplication(pipeline, o.Spec.PipelineName)
+		}
+		return o.Spec.PipelineName
+	}
+	return object.GetName()
+}
+
+func pipelineApplication(pipeline *v1.Pipeline, defaultKey string) string {
+	var spec struct {
+		Application string `json:"application"`
+	}
+	if err := json.Unmarshal(pipeline.Spec.Raw, &spec); err != nil || spec.Application == "" {
+		return defaultKey
+	}
+	return spec.Application
+}
+
+// shardSource returns a source that enqueues the objects of list in a shard whenever this replica acquires it, since
+// their events were dropped while another replica owned the shard.
+func shardSource(c client.Client, selector labels.Selector, shards *sharding.Shards, list runtime.Object) source.Source {
+	events := make(chan event.GenericEvent)
+	if shards == nil {
+		return &source.Channel{Source: events}
+	}
+	log := ctrl.Log.WithName("sharding")
+	shards.OnAcquire(func(shard int) {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			objects := list.DeepCopyObject()
+			if err := c.List(ctx, objects); err != nil {
+				log.Error(err, "unable to list objects of acquired shard", "shard", shard)
+				return
+			}
+			items, err := meta.ExtractList(objects)
+			if err != nil {
+				log.Error(err, "unable to list objects of acquired shard", "shard", shard)
+				return
+			}
+			for _, item := range items {
+				object, err := meta.Accessor(item)
+				if err != nil || !selected(selector, object) || shards.ShardOf(shardKey(ctx, c, object)) != shard {
+					continue
+				}
+				events <- event.GenericEvent{Meta: object, Object: item}
+			}
+		}()
+	})
+	return &source.Channel{Source: events}
+}
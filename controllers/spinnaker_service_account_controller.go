@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/sharding"
+	"strings"
+
+	"github.com/go-logr/logr"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+type SpinnakerServiceAccountReconciler struct {
+	client.Client
+	Log                     logr.Logger
+	Scheme                  *runtime.Scheme
+	Recorder                record.EventRecorder
+	Front50Client           Front50Client
+	Pause                   *Pause
+	Settings                *Settings
+	Audit                   *audit.Logger
+	Revisions               *Revisions
+	Selector                labels.Selector
+	Shards                  *sharding.Shards
+	MaxConcurrentReconciles int
+}
+
+func (r *SpinnakerServiceAccountReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, err error) {
+	serviceAccount := &v1.SpinnakerServiceAccount{}
+	ctx, cancel := newReconcileContext()
+	defer cancel()
+	ctx, span := startReconcileSpan(ctx, "SpinnakerServiceAccount", req)
+	defer func() { endReconcileSpan(span, serviceAccount, err) }()
+	logger := r.Log.WithValues("spinnakerserviceaccount", req.NamespacedName)
+	if err := r.Get(ctx, req.NamespacedName, serviceAccount); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if !managed(ctx, r.Client, r.Selector, r.Shards, serviceAccount) {
+		return ctrl.Result{}, nil
+	}
+	if paused, result, err := r.Pause.check(ctx, r.Client, r.Recorder, logger, serviceAccount); paused {
+		return result, err
+	}
+	ctx = withPriority(ctx, serviceAccount, containsString(serviceAccount.ObjectMeta.Finalizers, myFinalizerName))
+	if rolledBack, err := r.rollback(ctx, serviceAccount); rolledBack {
+		return ctrl.Result{}, err
+	}
+
+	if serviceAccount.ObjectMeta.DeletionTimestamp.IsZero() {
+		data, err := json.Marshal(serviceAccount.Spec)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		hash := fmt.Sprintf("%x", sha256.Sum256(data))
+		oldHash := serviceAccount.Status.Hash
+		if oldHash != hash {
+			name := serviceAccountName(serviceAccount)
+			oldName := serviceAccount.Status.SpinnakerResource.Name
+			operation := audit.OperationUpdate
+			if oldHash == "" || oldName != name {
+				operation = audit.OperationCreate
+			}
+			if oldName != name {
+				conflict, err := r.findConflict(ctx, serviceAccount, name)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				if conflict != "" {
+					r.setCondition(serviceAccount, v1.SpinnakerServiceAccountConflict, "True", conflict)
+					r.Recorder.Eventf(serviceAccount, coreV1.EventTypeWarning, "ConflictingServiceAccount", conflict)
+					if err := r.Update(ctx, serviceAccount); err != nil {
+						return ctrl.Result{}, err
+					}
+					return ctrl.Result{RequeueAfter: r.Settings.get().InvalidResyncInterval}, nil
+				}
+			}
+			r.setCondition(serviceAccount, v1.SpinnakerServiceAccountConflict, "False", "")
+			if err := r.saveServiceAccount(ctx, serviceAccount, Front50ServiceAccount{
+				Name:           name,
+				MemberOf:       normalizeRoles(serviceAccount.Spec.MemberOf),
+				LastModifiedBy: front50User,
+			}, operation); err != nil {
+				return ctrl.Result{}, err
+			}
+			if oldName != "" && oldName != name {
+				if err := r.deleteServiceAccount(ctx, serviceAccount, oldName); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			serviceAccount.Status.SpinnakerResource.Name = name
+			serviceAccount.Status.Hash = hash
+			if !containsString(serviceAccount.ObjectMeta.Finalizers, myFinalizerName) {
+				serviceAccount.ObjectMeta.Finalizers = append(serviceAccount.ObjectMeta.Finalizers, myFinalizerName)
+			}
+			if operation == audit.OperationCreate {
+				r.setCondition(serviceAccount, v1.SpinnakerServiceAccountCreationComplete, "True", "")
+				r.Recorder.Eventf(serviceAccount, coreV1.EventTypeNormal, "SuccessfulCreated", "Created service account: %q", name)
+				logger.V(1).Info("create", "spinnakerserviceaccount", serviceAccount)
+			} else {
+				r.setCondition(serviceAccount, v1.SpinnakerServiceAccountUpdateComplete, "True", "")
+				r.Recorder.Eventf(serviceAccount, coreV1.EventTypeNormal, "SuccessfulUpdated", "Updated service account: %q", name)
+				logger.V(1).Info("update", "spinnakerserviceaccount", serviceAccount)
+			}
+
+			if err := r.Update(ctx, serviceAccount); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	} else {
+		if containsString(serviceAccount.ObjectMeta.Finalizers, myFinalizerName) {
+			name := serviceAccount.Status.SpinnakerResource.Name
+			if name != "" && !r.Settings.retain(r.Recorder, serviceAccount) {
+				if err := r.deleteServiceAccount(ctx, serviceAccount, name); err != nil {
+					return ctrl.Result{}, err
+				}
+				r.setCondition(serviceAccount, v1.SpinnakerServiceAccountDeletionComplete, "True", "")
+				r.Recorder.Eventf(serviceAccount, coreV1.EventTypeNormal, "SuccessfulDeleted", "Deleted service account: %q", name)
+				logger.V(1).Info("delete", "spinnakerserviceaccount", serviceAccount)
+			}
+
+			serviceAccount.ObjectMeta.Finalizers = removeString(serviceAccount.ObjectMeta.Finalizers, myFinalizerName)
+			if err := r.Update(ctx, serviceAccount); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// findConflict describes why the service account of name belongs to someone else, or returns an empty string. The
+// controller only takes over a service account that it has saved itself, e.g. when the status of the save was not
+// recorded, so that it never deletes one created by hand or by another SpinnakerServiceAccount.
+func (r *SpinnakerServiceAccountReconciler) findConflict(ctx context.Context, serviceAccount *v1.SpinnakerServiceAccount, name string) (string, error) {
+	serviceAccountList := &v1.SpinnakerServiceAccountList{}
+	if err := r.List(ctx, serviceAccountList); err != nil {
+		return "", err
+	}
+	for _, other := range serviceAccountList.Items {
+		if other.UID != serviceAccount.UID && strings.EqualFold(other.Status.SpinnakerResource.Name, name) {
+			return fmt.Sprintf("service account %s is owned by SpinnakerServiceAccount %q", name, other.Name), nil
+		}
+	}
+
+	existing, err := r.Front50Client.GetServiceAccount(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.LastModifiedBy != front50User {
+		return fmt.Sprintf("service account %s already exists and was not created by the controller", name), nil
+	}
+	return "", nil
+}
+
+func (r *SpinnakerServiceAccountReconciler) setCondition(serviceAccount *v1.SpinnakerServiceAccount, conditionType v1.SpinnakerServiceAccountConditionType, status string, message string) {
+	for i, condition := range serviceAccount.Status.Conditions {
+		if condition.Type == conditionType {
+			serviceAccount.Status.Conditions[i].Status = status
+			serviceAccount.Status.Conditions[i].Message = message
+			return
+		}
+	}
+	serviceAccount.Status.Conditions = append(serviceAccount.Status.Conditions, v1.SpinnakerServiceAccountCondition{
+		Type:    conditionType,
+		Status:  status,
+		Message: message,
+	})
+}
+
+func (r *SpinnakerServiceAccountReconciler) saveServiceAccount(ctx context.Context, serviceAccount *v1.SpinnakerServiceAccount, account Front50ServiceAccount, operation audit.Operation) error {
+	change := audit.Change{
+		Operation: operation,
+		Object:    "serviceAccount",
+		ID:        account.Name,
+		Before: auditBefore(r.Audit, func() (interface{}, error) {
+			return r.getServiceAccount(ctx, account.Name)
+		}),
+		After: account,
+	}
+	err := r.Front50Client.SaveServiceAccount(ctx, account)
+	r.Audit.Log(serviceAccount, change, err)
+	if err != nil {
+		return err
+	}
+	if err := r.Revisions.record(ctx, serviceAccount, account, ""); err != nil {
+		r.Log.Error(err, "unable to record revision", "spinnakerServiceAccount", serviceAccount.Name)
+	}
+	return nil
+}
+
+func (r *SpinnakerServiceAccountReconciler) rollback(ctx context.Context, serviceAccount *v1.SpinnakerServiceAccount) (bool, error) {
+	return r.Revisions.rollback(ctx, r.Client, r.Recorder, serviceAccount, func(ctx context.Context, payload json.RawMessage) error {
+		var account Front50ServiceAccount
+		if err := json.Unmarshal(payload, &account); err != nil {
+			return err
+		}
+		// Rolling back restores the roles of the service account, which may have been renamed since
+		account.Name = serviceAccount.Status.SpinnakerResource.Name
+		return r.saveServiceAccount(ctx, serviceAccount, account, audit.OperationUpdate)
+	})
+}
+
+func (r *SpinnakerServiceAccountReconciler) deleteServiceAccount(ctx context.Context, serviceAccount *v1.SpinnakerServiceAccount, name string) error {
+	change := audit.Change{
+		Operation: audit.OperationDelete,
+		Object:    "serviceAccount",
+		ID:        name,
+		Before: auditBefore(r.Audit, func() (interface{}, error) {
+			return r.getServiceAccount(ctx, name)
+		}),
+	}
+	err := r.Front50Client.DeleteServiceAccount(ctx, name)
+	r.Audit.Log(serviceAccount, change, err)
+	return err
+}
+
+func (r *SpinnakerServiceAccountReconciler) getServiceAccount(ctx context.Context, name string) (interface{}, error) {
+	return r.Front50Client.GetServiceAccount(ctx, name)
+}
+
+func serviceAccountName(serviceAccount *v1.SpinnakerServiceAccount) string {
+	if serviceAccount.Spec.Name != "" {
+		return serviceAccount.Spec.Name
+	}
+	return serviceAccount.Name
+}
+
+func (r *SpinnakerServiceAccountReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.SpinnakerServiceAccount{}).
+		WithEventFilter(selectorPredicate(r.Selector, &v1.SpinnakerServiceAccount{})).
+		Watches(shardSource(r.Client, r.Selector, r.Shards, &v1.SpinnakerServiceAccountList{}), &handler.EnqueueRequestForObject{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(newRequeueOnCancel(r, r.Log))
+}
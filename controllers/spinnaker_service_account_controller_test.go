@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"testing"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeFront50Client struct {
+	serviceAccounts map[string]Front50ServiceAccount
+}
+
+func (c *fakeFront50Client) GetServiceAccount(_ context.Context, name string) (*Front50ServiceAccount, error) {
+	if serviceAccount, ok := c.serviceAccounts[name]; ok {
+		return &serviceAccount, nil
+	}
+	return nil, nil
+}
+
+func (c *fakeFront50Client) SaveServiceAccount(_ context.Context, serviceAccount Front50ServiceAccount) error {
+	c.serviceAccounts[serviceAccount.Name] = serviceAccount
+	return nil
+}
+
+func (c *fakeFront50Client) DeleteServiceAccount(_ context.Context, name string) error {
+	delete(c.serviceAccounts, name)
+	return nil
+}
+
+func TestFindServiceAccountConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	other := &v1.SpinnakerServiceAccount{ObjectMeta: metaV1.ObjectMeta{Name: "other", UID: "1"}}
+	other.Status.SpinnakerResource.Name = "deploy-bot"
+	r := &SpinnakerServiceAccountReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, other),
+		Front50Client: &fakeFront50Client{serviceAccounts: map[string]Front50ServiceAccount{
+			"deploy-bot":  {Name: "deploy-bot", LastModifiedBy: front50User},
+			"by-hand":     {Name: "by-hand", LastModifiedBy: "admin"},
+			"saved-twice": {Name: "saved-twice", LastModifiedBy: front50User},
+		}},
+	}
+	serviceAccount := &v1.SpinnakerServiceAccount{ObjectMeta: metaV1.ObjectMeta{Name: "sample", UID: "2"}}
+
+	for name, conflicting := range map[string]bool{
+		"deploy-bot":  true,
+		"by-hand":     true,
+		"saved-twice": false,
+		"new":         false,
+	} {
+		conflict, err := r.findConflict(context.Background(), serviceAccount, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (conflict != "") != conflicting {
+			t.Errorf("expected conflict of %s to be %v, got %q", name, conflicting, conflict)
+		}
+	}
+}
+
+func TestSpinnakerServiceAccountTellsCreateFromUpdate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	front50Client := &fakeFront50Client{serviceAccounts: map[string]Front50ServiceAccount{}}
+	recorder := record.NewFakeRecorder(100)
+	r := &SpinnakerServiceAccountReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, &v1.SpinnakerServiceAccount{
+			ObjectMeta: metaV1.ObjectMeta{Name: "deploy-bot"},
+			Spec:       v1.SpinnakerServiceAccountSpec{MemberOf: []string{"deployers"}},
+		}),
+		Log:           ctrl.Log.WithName("controllers").WithName("SpinnakerServiceAccount"),
+		Recorder:      recorder,
+		Front50Client: front50Client,
+	}
+	key := client.ObjectKey{Name: "deploy-bot"}
+	reconcile := func() *v1.SpinnakerServiceAccount {
+		t.Helper()
+		if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		serviceAccount := &v1.SpinnakerServiceAccount{}
+		if err := r.Get(context.Background(), key, serviceAccount); err != nil {
+			t.Fatal(err)
+		}
+		return serviceAccount
+	}
+	expectEvent := func(expected string) {
+		t.Helper()
+		select {
+		case event := <-recorder.Events:
+			if event != expected {
+				t.Errorf("expected event %q, got %q", expected, event)
+			}
+		default:
+			t.Errorf("expected event %q, got none", expected)
+		}
+	}
+
+	serviceAccount := reconcile()
+	expectEvent(`Normal SuccessfulCreated Created service account: "deploy-bot"`)
+	serviceAccount.Spec.MemberOf = []string{"admins"}
+	if err := r.Update(context.Background(), serviceAccount); err != nil {
+		t.Fatal(err)
+	}
+	serviceAccount = reconcile()
+	expectEvent(`Normal SuccessfulUpdated Updated service account: "deploy-bot"`)
+
+	conditions := map[v1.SpinnakerServiceAccountConditionType]int{}
+	for _, condition := range serviceAccount.Status.Conditions {
+		conditions[condition.Type]++
+	}
+	if conditions[v1.SpinnakerServiceAccountCreationComplete] != 1 || conditions[v1.SpinnakerServiceAccountUpdateComplete] != 1 {
+		t.Errorf("expected one CreationComplete and one UpdateComplete condition, got %+v", serviceAccount.Status.Conditions)
+	}
+	if memberOf := front50Client.serviceAccounts["deploy-bot"].MemberOf; len(memberOf) != 1 || memberOf[0] != "admins" {
+		t.Errorf("expected the service account to be updated, got %v", memberOf)
+	}
+}
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"spinnaker-dcd-controller/internal/fakegate"
+	"spinnaker-dcd-controller/internal/gateway"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/xerrors"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	eventuallyTimeout = 30 * time.Second
+	eventuallyPoll    = 250 * time.Millisecond
+)
+
+var (
+	testClient client.Client
+	testGate   *fakegate.Server
+	// skipReason is set when the control plane of envtest cannot be started, e.g. without KUBEBUILDER_ASSETS
+	skipReason string
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runSuite(m))
+}
+
+// runSuite runs all tests against one API server and one manager, which runs every reconciler against a fake Gate.
+func runSuite(m *testing.M) int {
+	if _, err := os.Stat(filepath.Join(kubebuilderAssets(), "kube-apiserver")); err != nil {
+		skipReason = fmt.Sprintf("kube-apiserver is not found in %s, set KUBEBUILDER_ASSETS to run the envtest suites", kubebuilderAssets())
+		if os.Getenv("ENVTEST_REQUIRED") == "true" {
+			fmt.Fprintf(os.Stderr, "ENVTEST_REQUIRED is set but %s\n", skipReason)
+			return 1
+		}
+		return m.Run()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true), zap.WriteTo(ioutil.Discard)))
+	crds, err := loadCRDs(filepath.Join("..", "manifests", "crd"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	testEnv := &envtest.Environment{CRDs: crds}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer func() {
+		_ = testEnv.Stop()
+	}()
+
+	testGate = fakegate.NewServer()
+	defer testGate.Close()
+	testGate.AddMetricsAccount("envtest-prometheus", "prometheus")
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1.AddToScheme(scheme)
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: scheme, MetricsBindAddress: "0"})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	stop := make(chan struct{})
+	options := gateway.DefaultOptions()
+	options.Timeout = 5 * time.Second
+	options.BaseBackoff = 10 * time.Millisecond
+	options.MaxBackoff = 50 * time.Millisecond
+	options.FailureThreshold = 0
+	options.Stop = stop
+	gatewayClient, err := gateway.New(testGate.URL(), options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := setupReconcilers(mgr, gatewayClient, options); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	go func() {
+		if err := mgr.Start(stop); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+	defer close(stop)
+	testClient = mgr.GetClient()
+
+	return m.Run()
+}
+
+// setupReconcilers registers every reconciler of the manager, with Gate and Front50 both served by testGate.
+func setupReconcilers(mgr ctrl.Manager, gatewayClient gateway.Client, options gateway.Options) error {
+	recorder := record.NewFakeRecorder(1024)
+	go func() {
+		for range recorder.Events {
+		}
+	}()
+	front50Options := options
+	front50Options.Headers = nil
+	front50Client := NewFront50Client(testGate.URL(), gateway.NewHTTPClient("Front50", front50Options))
+	logger := func(kind string) logr.Logger {
+		return ctrl.Log.WithName("controllers").WithName(kind)
+	}
+
+	reconcilers := []interface {
+		SetupWithManager(mgr ctrl.Manager) error
+	}{
+		&ApplicationReconciler{
+			Client:   mgr.GetClient(),
+			Log:      logger("Application"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: recorder,
+			Gateway:  gatewayClient,
+		},
+		&PipelineTemplateReconciler{
+			Client:   mgr.GetClient(),
+			Log:      logger("PipelineTemplate"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: recorder,
+			Gateway:  gatewayClient,
+		},
+		&PipelineReconciler{
+			Client:   mgr.GetClient(),
+			Log:      logger("Pipeline"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: recorder,
+			Gateway:  gatewayClient,
+		},
+		&CanaryConfigReconciler{
+			Client:   mgr.GetClient(),
+			Log:      logger("CanaryConfig"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: recorder,
+			Gateway:  gatewayClient,
+		},
+		&PipelineExecutionReconciler{
+			Client:   mgr.GetClient(),
+			Log:      logger("PipelineExecution"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: recorder,
+			Gateway:  gatewayClient,
+		},
+		&ProjectReconciler{
+			Client:   mgr.GetClient(),
+			Log:      logger("Project"),
+			Scheme:   mgr.GetScheme(),
+			Recorder: recorder,
+			Gateway:  gatewayClient,
+		},
+		&SpinnakerServiceAccountReconciler{
+			Client:        mgr.GetClient(),
+			Log:           logger("SpinnakerServiceAccount"),
+			Scheme:        mgr.GetScheme(),
+			Recorder:      recorder,
+			Front50Client: front50Client,
+		},
+		&CanaryAnalysisReconciler{
+			Client:       mgr.GetClient(),
+			Log:          logger("CanaryAnalysis"),
+			Scheme:       mgr.GetScheme(),
+			Recorder:     recorder,
+			Gateway:      gatewayClient,
+			DeckEndpoint: testGate.URL(),
+		},
+	}
+	for _, r := range reconcilers {
+		if err := r.SetupWithManager(mgr); err != nil {
+			return xerrors.Errorf("failed to set up %T: %w", r, err)
+		}
+	}
+	return nil
+}
+
+func kubebuilderAssets() string {
+	if assets := os.Getenv("KUBEBUILDER_ASSETS"); assets != "" {
+		return assets
+	}
+	return "/usr/local/kubebuilder/bin"
+}
+
+// loadCRDs reads the generated CRDs as v1beta1, which is the only version that envtest of this controller-runtime
+// installs. The schemas are compatible since they are generated with trivialVersions.
+func loadCRDs(dir string) ([]*v1beta1.CustomResourceDefinition, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var crds []*v1beta1.CustomResourceDefinition
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range strings.Split(string(data), "\n---") {
+			if strings.TrimSpace(document) == "" {
+				continue
+			}
+			crd := &v1beta1.CustomResourceDefinition{}
+			if err := yaml.Unmarshal([]byte(document), crd); err != nil {
+				return nil, xerrors.Errorf("failed to read CRD in %s: %w", file, err)
+			}
+			crd.APIVersion = v1beta1.SchemeGroupVersion.String()
+			crds = append(crds, crd)
+		}
+	}
+	return crds, nil
+}
+
+// newFakeGate starts a fake Gate for a unit test, which is closed when the test ends.
+func newFakeGate(t *testing.T) (*fakegate.Server, gateway.Client) {
+	t.Helper()
+	server := fakegate.NewServer()
+	t.Cleanup(server.Close)
+	options := gateway.DefaultOptions()
+	options.BaseBackoff = time.Millisecond
+	options.MaxBackoff = time.Millisecond
+	options.FailureThreshold = 0
+	gatewayClient, err := gateway.New(server.URL(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, gatewayClient
+}
+
+func requireEnvironment(t *testing.T) {
+	t.Helper()
+	if skipReason != "" {
+		t.Skip(skipReason)
+	}
+}
+
+// eventually calls condition until it returns nil, and fails the test with the last error after the timeout.
+func eventually(t *testing.T, condition func() error) {
+	t.Helper()
+	deadline := time.Now().Add(eventuallyTimeout)
+	for {
+		err := condition()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %s: %v", eventuallyTimeout, err)
+		}
+		time.Sleep(eventuallyPoll)
+	}
+}
+
+// consistently fails the test as soon as condition returns an error within duration.
+func consistently(t *testing.T, duration time.Duration, condition func() error) {
+	t.Helper()
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		if err := condition(); err != nil {
+			t.Fatalf("condition broken: %v", err)
+		}
+		time.Sleep(eventuallyPoll)
+	}
+}
+
+func create(t *testing.T, object runtime.Object) {
+	t.Helper()
+	if err := testClient.Create(context.Background(), object); err != nil {
+		t.Fatalf("failed to create %T: %v", object, err)
+	}
+}
+
+// update applies mutate to the latest object, retrying on conflicts with the reconcilers.
+func update(t *testing.T, key client.ObjectKey, object runtime.Object, mutate func()) {
+	t.Helper()
+	eventually(t, func() error {
+		if err := testClient.Get(context.Background(), key, object); err != nil {
+			return err
+		}
+		mutate()
+		return testClient.Update(context.Background(), object)
+	})
+}
+
+func remove(t *testing.T, object runtime.Object) {
+	t.Helper()
+	if err := testClient.Delete(context.Background(), object); err != nil && !errors.IsNotFound(err) {
+		t.Fatalf("failed to delete %T: %v", object, err)
+	}
+}
+
+// waitForDeletion waits until the finalizer has been removed and the object is gone.
+func waitForDeletion(t *testing.T, key client.ObjectKey, object runtime.Object) {
+	t.Helper()
+	eventually(t, func() error {
+		err := testClient.Get(context.Background(), key, object)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return xerrors.Errorf("%s still exists", key.Name)
+	})
+}
+
+func rawSpec(spec string) runtime.RawExtension {
+	data, err := yaml.YAMLToJSON([]byte(spec))
+	if err != nil {
+		panic(err)
+	}
+	return runtime.RawExtension{Raw: data}
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	"golang.org/x/xerrors"
+)
+
+const (
+	templateEngineAnnotation = "spinnaker.kaidotdev.github.io/template-engine"
+	templateValuesAnnotation = "spinnaker.kaidotdev.github.io/template-values"
+
+	goTemplateEngine = "gotemplate"
+
+	// envPrefix is prepended to the names of ${Env:...}, so that templates cannot read other environment variables of
+	// the controller, such as credentials of AWS or Gate, and publish them in Spinnaker
+	envPrefix = "SPINNAKER_DCD_"
+
+	ifDirective   = "$if"
+	thenDirective = "$then"
+	elseDirective = "$else"
+	forDirective  = "$for"
+	inDirective   = "$in"
+	doDirective   = "$do"
+)
+
+// engineExpressionPattern only matches expressions that start with a known resolver,
+// so that Spinnaker's own SpEL expressions such as ${trigger.parameters.foo} pass through untouched.
+var engineExpressionPattern = regexp.MustCompile(`\$\{\s*(ImportValue|Env|Var):([^|}]*)(\|[^}]*)?\}`)
+
+// unsafeFunctions are sprig functions that generate secrets or key material in addition to sprig's non-hermetic ones.
+var unsafeFunctions = []string{
+	"genPrivateKey",
+	"derivePassword",
+	"buildCustomCert",
+	"genCA",
+	"genSelfSignedCert",
+	"genSignedCert",
+}
+
+type resolverFunc func(argument string, scope map[string]interface{}) (interface{}, error)
+
+type omitted struct{}
+
+type expansion []interface{}
+
+type templateRenderer struct {
+	resolvers map[string]resolverFunc
+}
+
+func newTemplateRenderer(importValue func(string) (string, error)) *templateRenderer {
+	return &templateRenderer{
+		resolvers: map[string]resolverFunc{
+			"ImportValue": func(argument string, _ map[string]interface{}) (interface{}, error) {
+				return importValue(argument)
+			},
+			"Env": func(argument string, _ map[string]interface{}) (interface{}, error) {
+				return os.Getenv(envPrefix + argument), nil
+			},
+			"Var": func(argument string, scope map[string]interface{}) (interface{}, error) {
+				return lookupPath(scope, argument), nil
+			},
+		},
+	}
+}
+
+func (t *templateRenderer) renderJSON(data []byte, values map[string]interface{}) ([]byte, error) {
+	var spec interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	rendered, err := t.render(spec, values)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rendered.(omitted); ok {
+		rendered = map[string]interface{}{}
+	}
+	return json.Marshal(rendered)
+}
+
+func (t *templateRenderer) render(node interface{}, scope map[string]interface{}) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if _, ok := n[ifDirective]; ok {
+			return t.renderIf(n, scope)
+		}
+		if _, ok := n[forDirective]; ok {
+			return t.renderFor(n, scope)
+		}
+		result := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			rendered, err := t.render(value, scope)
+			if err != nil {
+				return nil, err
+			}
+			switch r := rendered.(type) {
+			case omitted:
+				continue
+			case expansion:
+				result[key] = []interface{}(r)
+			default:
+				result[key] = r
+			}
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(n))
+		for _, item := range n {
+			rendered, err := t.render(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			switch r := rendered.(type) {
+			case omitted:
+				continue
+			case expansion:
+				result = append(result, r...)
+			default:
+				result = append(result, r)
+			}
+		}
+		return result, nil
+	case string:
+		return t.renderString(n, scope)
+	default:
+		return node, nil
+	}
+}
+
+func (t *templateRenderer) renderIf(node map[string]interface{}, scope map[string]interface{}) (interface{}, error) {
+	condition, err := t.render(node[ifDirective], scope)
+	if err != nil {
+		return nil, err
+	}
+	branch := elseDirective
+	if isTruthy(condition) {
+		branch = thenDirective
+	}
+	value, ok := node[branch]
+	if !ok {
+		return omitted{}, nil
+	}
+	return t.render(value, scope)
+}
+
+func (t *templateRenderer) renderFor(node map[string]interface{}, scope map[string]interface{}) (interface{}, error) {
+	name, ok := node[forDirective].(string)
+	if !ok || name == "" {
+		return nil, xerrors.Errorf("%s must be a variable name", forDirective)
+	}
+	items, err := t.render(node[inDirective], scope)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := items.(string); ok {
+		items = strings.Split(s, ",")
+	}
+	list, ok := items.([]interface{})
+	if !ok {
+		if values, ok := items.([]string); ok {
+			for _, value := range values {
+				list = append(list, value)
+			}
+		} else if items != nil {
+			return nil, xerrors.Errorf("%s must be a list: %v", inDirective, items)
+		}
+	}
+
+	result := expansion{}
+	for _, item := range list {
+		itemScope := make(map[string]interface{}, len(scope)+1)
+		for key, value := range scope {
+			itemScope[key] = value
+		}
+		itemScope[name] = item
+		rendered, err := t.render(node[doDirective], itemScope)
+		if err != nil {
+			return nil, err
+		}
+		switch r := rendered.(type) {
+		case omitted:
+			continue
+		case expansion:
+			result = append(result, r...)
+		default:
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func (t *templateRenderer) renderString(s string, scope map[string]interface{}) (interface{}, error) {
+	matches := engineExpressionPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return t.evaluate(s, matches[0], scope)
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, match := range matches {
+		value, err := t.evaluate(s, match, scope)
+		if err != nil {
+			return nil, err
+		}
+		builder.WriteString(s[last:match[0]])
+		if value != nil {
+			builder.WriteString(fmt.Sprint(value))
+		}
+		last = match[1]
+	}
+	builder.WriteString(s[last:])
+	return builder.String(), nil
+}
+
+func (t *templateRenderer) evaluate(s string, match []int, scope map[string]interface{}) (interface{}, error) {
+	resolverName := s[match[2]:match[3]]
+	argument := strings.TrimSpace(s[match[4]:match[5]])
+	pipeline := ""
+	if match[6] >= 0 {
+		pipeline = s[match[6]:match[7]]
+	}
+
+	var result interface{}
+	funcs := safeFuncMap()
+	funcs["resolve"] = func() (interface{}, error) {
+		return t.resolvers[resolverName](argument, scope)
+	}
+	funcs["capture"] = func(value interface{}) string {
+		result = value
+		return ""
+	}
+
+	tpl, err := template.New("expression").Funcs(funcs).Parse(fmt.Sprintf("{{ capture (resolve %s) }}", pipeline))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to parse expression %s: %w", s[match[0]:match[1]], err)
+	}
+	if err := tpl.Execute(&strings.Builder{}, nil); err != nil {
+		return nil, xerrors.Errorf("failed to evaluate expression %s: %w", s[match[0]:match[1]], err)
+	}
+	return result, nil
+}
+
+func safeFuncMap() template.FuncMap {
+	funcs := sprig.HermeticTxtFuncMap()
+	for _, name := range unsafeFunctions {
+		delete(funcs, name)
+	}
+	return funcs
+}
+
+func lookupPath(scope map[string]interface{}, path string) interface{} {
+	var current interface{} = scope
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func isTruthy(value interface{}) bool {
+	if s, ok := value.(string); ok {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	truth, _ := template.IsTrue(value)
+	return truth
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+)
+
+func TestTemplateRenderer(t *testing.T) {
+	if err := os.Setenv(envPrefix+"STAGE", "production"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv(envPrefix + "STAGE")
+	if err := os.Setenv("TEST_SECRET", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("TEST_SECRET")
+
+	values := map[string]interface{}{
+		"enabled":  true,
+		"disabled": "false",
+		"regions":  []interface{}{"us-east-1", "eu-west-1"},
+		"nested":   map[string]interface{}{"name": "deploy"},
+	}
+	importValue := func(name string) (string, error) {
+		if name == "missing" {
+			return "", xerrors.New("export is not found")
+		}
+		return "export-of-" + name, nil
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "if then",
+			template: `{"stage": {"$if": "${Var:enabled}", "$then": "a", "$else": "b"}}`,
+			expected: `{"stage": "a"}`,
+		},
+		{
+			name:     "if else with a string condition",
+			template: `{"stage": {"$if": "${Var:disabled}", "$then": "a", "$else": "b"}}`,
+			expected: `{"stage": "b"}`,
+		},
+		{
+			name:     "if without the chosen branch is removed",
+			template: `{"stage": {"$if": "${Var:disabled}", "$then": "a"}, "stages": [{"$if": false, "$then": 1}, 2]}`,
+			expected: `{"stages": [2]}`,
+		},
+		{
+			name:     "for is spliced into the surrounding list",
+			template: `{"stages": [0, {"$for": "region", "$in": "${Var:regions}", "$do": "deploy-${Var:region}"}, 3]}`,
+			expected: `{"stages": [0, "deploy-us-east-1", "deploy-eu-west-1", 3]}`,
+		},
+		{
+			name:     "for over a comma separated string",
+			template: `{"stages": {"$for": "region", "$in": "a,b", "$do": {"name": "${Var:region}"}}}`,
+			expected: `{"stages": [{"name": "a"}, {"name": "b"}]}`,
+		},
+		{
+			name:     "for without a variable name",
+			template: `{"stages": {"$for": "", "$in": "a,b", "$do": 1}}`,
+			err:      true,
+		},
+		{
+			name:     "whole expression keeps the type of the value",
+			template: `{"regions": "${Var:regions}"}`,
+			expected: `{"regions": ["us-east-1", "eu-west-1"]}`,
+		},
+		{
+			name:     "expression inside a string",
+			template: `{"name": "${Var:nested.name} to ${ImportValue:region}"}`,
+			expected: `{"name": "deploy to export-of-region"}`,
+		},
+		{
+			name:     "default of an unresolved variable",
+			template: `{"name": "${Var:unknown | default \"dev\"}"}`,
+			expected: `{"name": "dev"}`,
+		},
+		{
+			name:     "unresolved variable inside a string is empty",
+			template: `{"name": "deploy-${Var:unknown}"}`,
+			expected: `{"name": "deploy-"}`,
+		},
+		{
+			name:     "env is prefixed",
+			template: `{"stage": "${Env:STAGE}", "secret": "${Env:TEST_SECRET | default \"none\"}"}`,
+			expected: `{"stage": "production", "secret": "none"}`,
+		},
+		{
+			name:     "pipeline of sprig functions",
+			template: `{"name": "${Var:nested.name | upper | printf \"%s-app\"}"}`,
+			expected: `{"name": "DEPLOY-app"}`,
+		},
+		{
+			name:     "key generation functions are disabled",
+			template: `{"key": "${Var:nested.name | genPrivateKey}"}`,
+			err:      true,
+		},
+		{
+			name:     "password derivation is disabled",
+			template: `{"key": "${Var:nested.name | derivePassword 1 \"long\" \"password\" \"user\"}"}`,
+			err:      true,
+		},
+		{
+			name:     "failure of a resolver",
+			template: `{"name": "${ImportValue:missing}"}`,
+			err:      true,
+		},
+		{
+			name:     "spel passes through",
+			template: `{"waitTime": "${trigger.parameters.waitTime}", "name": "${ #stage('Deploy')['status'] }"}`,
+			expected: `{"waitTime": "${trigger.parameters.waitTime}", "name": "${ #stage('Deploy')['status'] }"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rendered, err := newTemplateRenderer(importValue).renderJSON([]byte(test.template), values)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", rendered)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got, expected interface{}
+			if err := json.Unmarshal(rendered, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(test.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("expected %s, got %s", test.expected, rendered)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/tracing"
+	"time"
+
+	"github.com/spinnaker/roer/spinnaker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// startReconcileSpan starts the span of one reconciliation of req, which the calls to Gate and AWS are traced under.
+func startReconcileSpan(ctx context.Context, kind string, req ctrl.Request) (context.Context, trace.Span) {
+	return tracing.Start(ctx, kind+" Reconcile",
+		attribute.String("k8s.kind", kind),
+		attribute.String("k8s.namespace", req.Namespace),
+		attribute.String("k8s.name", req.Name),
+	)
+}
+
+// endReconcileSpan tags span with the Spinnaker IDs in the status of object, as they are at the end of the
+// reconciliation, and ends it.
+func endReconcileSpan(span trace.Span, object runtime.Object, err error) {
+	if span.IsRecording() {
+		if content, convertErr := runtime.DefaultUnstructuredConverter.ToUnstructured(object); convertErr == nil {
+			resource, _, _ := unstructured.NestedStringMap(content, "status", "spinnakerResource")
+			for key, value := range resource {
+				if value != "" {
+					span.SetAttributes(attribute.String("spinnaker."+key, value))
+				}
+			}
+		}
+	}
+	tracing.End(span, err)
+}
+
+// pollTask waits for the task of ref to finish in a span of its own, so that the time spent in Orca shows up apart from
+// the requests that submitted the task.
+func pollTask(ctx context.Context, client gateway.Client, ref string, timeout time.Duration) (response *spinnaker.ExecutionResponse, err error) {
+	ctx, span := tracing.Start(ctx, "Gate PollTaskStatus", attribute.String("spinnaker.task", ref))
+	defer func() { tracing.End(span, err) }()
+	return client.Roer(ctx).PollTaskStatus(ref, timeout)
+}
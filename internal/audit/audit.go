@@ -0,0 +1,191 @@
+// Package audit records every change that the controller pushes to Spinnaker, for compliance.
+//
+// Each record names the resource that caused the change, the Kubernetes field manager that last changed its spec, the
+// Spinnaker object that was changed, and a JSON Patch from the object as it was to what was pushed.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gomodules.xyz/jsonpatch/v2"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Operation is what a change did to the Spinnaker object
+type Operation string
+
+const (
+	OperationCreate  Operation = "Create"
+	OperationUpdate  Operation = "Update"
+	OperationDelete  Operation = "Delete"
+	OperationRename  Operation = "Rename"
+	OperationExecute Operation = "Execute"
+	OperationCancel  Operation = "Cancel"
+)
+
+// Change is one mutating call to Spinnaker
+type Change struct {
+	Operation Operation
+	// Object is the type of the Spinnaker object, e.g. pipeline
+	Object string
+	// ID identifies the Spinnaker object, e.g. the name of a pipeline prefixed with its application
+	ID string
+	// Before is the Spinnaker object as it was, or nil when it did not exist or could not be read
+	Before interface{}
+	// After is what was pushed, or nil when the object was deleted
+	After interface{}
+}
+
+// Record is the audit record of one Change
+type Record struct {
+	Time      time.Time             `json:"time"`
+	Kind      string                `json:"kind"`
+	Namespace string                `json:"namespace,omitempty"`
+	Name      string                `json:"name"`
+	UID       types.UID             `json:"uid"`
+	User      string                `json:"user,omitempty"`
+	Operation Operation             `json:"operation"`
+	Object    string                `json:"spinnakerObject"`
+	ID        string                `json:"spinnakerId"`
+	Diff      []jsonpatch.Operation `json:"diff"`
+	Succeeded bool                  `json:"succeeded"`
+	Error     string                `json:"error,omitempty"`
+}
+
+// Logger writes audit records to a dedicated log stream and, optionally, to a file sink as JSON lines.
+// A nil Logger records nothing.
+type Logger struct {
+	log  logr.Logger
+	mu   sync.Mutex
+	sink io.Writer
+	now  func() time.Time
+}
+
+// New returns a Logger that writes to log, and to sink unless it is nil
+func New(log logr.Logger, sink io.Writer) *Logger {
+	return &Logger{log: log, sink: sink, now: time.Now}
+}
+
+// Log records change made on behalf of object, and err of the call that made it, if any.
+func (l *Logger) Log(object metaV1.Object, change Change, err error) {
+	if l == nil {
+		return
+	}
+	record := Record{
+		Time:      l.now().UTC(),
+		Kind:      kindOf(object),
+		Namespace: object.GetNamespace(),
+		Name:      object.GetName(),
+		UID:       object.GetUID(),
+		User:      FieldManager(object),
+		Operation: change.Operation,
+		Object:    change.Object,
+		ID:        change.ID,
+		Succeeded: err == nil,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	diff, diffErr := Diff(change.Before, change.After)
+	if diffErr != nil {
+		l.log.Error(diffErr, "unable to diff audited change", "kind", record.Kind, "name", record.Name)
+	}
+	record.Diff = diff
+
+	l.log.Info("audit",
+		"kind", record.Kind,
+		"namespace", record.Namespace,
+		"name", record.Name,
+		"uid", record.UID,
+		"user", record.User,
+		"operation", record.Operation,
+		"spinnakerObject", record.Object,
+		"spinnakerId", record.ID,
+		"diff", record.Diff,
+		"succeeded", record.Succeeded,
+		"error", record.Error,
+	)
+	if l.sink == nil {
+		return
+	}
+	line, marshalErr := json.Marshal(record)
+	if marshalErr != nil {
+		l.log.Error(marshalErr, "unable to write audit record to file")
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, writeErr := l.sink.Write(append(line, '\n')); writeErr != nil {
+		l.log.Error(writeErr, "unable to write audit record to file")
+	}
+}
+
+// Diff returns the JSON Patch from before to after, where nil stands for an object that does not exist.
+func Diff(before interface{}, after interface{}) ([]jsonpatch.Operation, error) {
+	beforeJSON, err := marshalObject(before)
+	if err != nil {
+		return nil, err
+	}
+	afterJSON, err := marshalObject(after)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := jsonpatch.CreatePatch(beforeJSON, afterJSON)
+	if err != nil {
+		return nil, err
+	}
+	if diff == nil {
+		diff = []jsonpatch.Operation{}
+	}
+	return diff, nil
+}
+
+func marshalObject(object interface{}) ([]byte, error) {
+	if object == nil || reflect.ValueOf(object).Kind() == reflect.Ptr && reflect.ValueOf(object).IsNil() {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(object)
+}
+
+// FieldManager returns the field manager that last changed the spec of object, e.g. kubectl, which is as close to the
+// Kubernetes user as the object tells. The controller itself only changes metadata and status.
+func FieldManager(object metaV1.Object) string {
+	var manager string
+	var latest time.Time
+	for _, entry := range object.GetManagedFields() {
+		if entry.FieldsV1 == nil || !managesSpec(entry.FieldsV1.Raw) {
+			continue
+		}
+		var t time.Time
+		if entry.Time != nil {
+			t = entry.Time.Time
+		}
+		if manager == "" || !t.Before(latest) {
+			manager, latest = entry.Manager, t
+		}
+	}
+	return manager
+}
+
+func managesSpec(fields []byte) bool {
+	var set map[string]json.RawMessage
+	if err := json.Unmarshal(fields, &set); err != nil {
+		return false
+	}
+	_, ok := set["f:spec"]
+	return ok
+}
+
+func kindOf(object metaV1.Object) string {
+	t := reflect.TypeOf(object)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
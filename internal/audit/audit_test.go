@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	v1 "spinnaker-dcd-controller/api/v1"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestLog(t *testing.T) {
+	pipeline := &v1.Pipeline{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: "sample",
+			UID:  "0b1f3f2e",
+			ManagedFields: []metaV1.ManagedFieldsEntry{
+				{
+					Manager:  "kubectl",
+					Time:     &metaV1.Time{Time: time.Unix(100, 0)},
+					FieldsV1: &metaV1.FieldsV1{Raw: []byte(`{"f:spec":{}}`)},
+				},
+				{
+					Manager:  "spinnaker-dcd-controller",
+					Time:     &metaV1.Time{Time: time.Unix(200, 0)},
+					FieldsV1: &metaV1.FieldsV1{Raw: []byte(`{"f:status":{}}`)},
+				},
+			},
+		},
+	}
+	sink := &bytes.Buffer{}
+	logger := New(zap.New(zap.UseDevMode(true), zap.WriteTo(ioutil.Discard)), sink)
+
+	logger.Log(pipeline, Change{
+		Operation: OperationUpdate,
+		Object:    "pipeline",
+		ID:        "sample/deploy",
+		Before:    map[string]interface{}{"name": "deploy", "keepWaitingPipelines": true},
+		After:     map[string]interface{}{"name": "deploy", "keepWaitingPipelines": false},
+	}, nil)
+	logger.Log(pipeline, Change{Operation: OperationDelete, Object: "pipeline", ID: "sample/deploy"}, xerrors.New("forbidden"))
+
+	lines := bytes.Split(bytes.TrimSpace(sink.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %q", sink.String())
+	}
+	var record Record
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Kind != "Pipeline" || record.Name != "sample" || record.UID != "0b1f3f2e" || record.User != "kubectl" || !record.Succeeded {
+		t.Fatalf("unexpected record: %s", lines[0])
+	}
+	if len(record.Diff) != 1 || record.Diff[0].Operation != "replace" || record.Diff[0].Path != "/keepWaitingPipelines" {
+		t.Fatalf("unexpected diff: %s", lines[0])
+	}
+	if err := json.Unmarshal(lines[1], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Succeeded || record.Error != "forbidden" || len(record.Diff) != 0 {
+		t.Fatalf("unexpected record: %s", lines[1])
+	}
+}
+
+func TestNilLoggerRecordsNothing(t *testing.T) {
+	var logger *Logger
+	logger.Log(&v1.Pipeline{}, Change{Operation: OperationCreate}, nil)
+}
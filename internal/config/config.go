@@ -0,0 +1,419 @@
+// Package config provides the versioned configuration file of the controller.
+//
+// The file is a ControllerConfiguration in the style of Kubernetes component configs, which is usually mounted from a
+// ConfigMap. Command-line flags override the values of the file.
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the only version of the configuration file
+	APIVersion = "config.spinnaker.kaidotdev.github.io/v1alpha1"
+	// Kind is the kind of the configuration file
+	Kind = "ControllerConfiguration"
+)
+
+// Deletion policies decide what happens to a Spinnaker resource when its custom resource is deleted
+const (
+	// DeletionPolicyDelete deletes the Spinnaker resource
+	DeletionPolicyDelete = "Delete"
+	// DeletionPolicyRetain leaves the Spinnaker resource in place
+	DeletionPolicyRetain = "Retain"
+)
+
+// Kinds are the kinds of the controllers
+var Kinds = []string{
+	"Application",
+	"PipelineTemplate",
+	"Pipeline",
+	"CanaryConfig",
+	"PipelineExecution",
+	"Project",
+	"SpinnakerServiceAccount",
+	"CanaryAnalysis",
+}
+
+// ControllerConfiguration configures the controller
+type ControllerConfiguration struct {
+	metaV1.TypeMeta `json:",inline"`
+
+	// MetricsBindAddress is the address the metric endpoint binds to
+	MetricsBindAddress string `json:"metricsBindAddress,omitempty"`
+	// Port is the port of the webhook server of the manager
+	Port int `json:"port,omitempty"`
+	// LeaderElection configures leader election of the manager
+	LeaderElection LeaderElectionConfiguration `json:"leaderElection,omitempty"`
+	// SyncPeriod is how often all watched resources are reconciled even without change
+	SyncPeriod metaV1.Duration `json:"syncPeriod,omitempty"`
+	// Verbose makes the operation more talkative
+	Verbose bool `json:"verbose,omitempty"`
+
+	// Gate configures requests to Spinnaker Gate
+	Gate GateConfiguration `json:"gate,omitempty"`
+	// Front50Endpoint is the endpoint of Spinnaker Front50, which enables SpinnakerServiceAccount by calling Front50
+	// directly, since Gate does not expose service accounts for writing. It is empty by default, which disables it.
+	Front50Endpoint string `json:"front50Endpoint,omitempty"`
+	// DeckEndpoint is the endpoint of Spinnaker Deck, which is used to link canary analysis reports
+	DeckEndpoint string `json:"deckEndpoint,omitempty"`
+
+	// Reconciliation configures the timings and defaults of reconciliation, which are reloaded at runtime
+	Reconciliation ReconciliationConfiguration `json:"reconciliation,omitempty"`
+	// Pause pauses reconciliation of all resources
+	Pause PauseConfiguration `json:"pause,omitempty"`
+	// MaxConcurrentReconciles is the number of concurrent reconciliations of each controller
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+	// Controllers configures each controller by its kind, e.g. Pipeline
+	Controllers map[string]ControllerOptions `json:"controllers,omitempty"`
+	// Sharding spreads resources across replicas instead of leaving them to one leader
+	Sharding ShardingConfiguration `json:"sharding,omitempty"`
+	// Audit records every change pushed to Spinnaker
+	Audit AuditConfiguration `json:"audit,omitempty"`
+	// Tracing exports traces of reconciliations and of their calls to Spinnaker and AWS
+	Tracing TracingConfiguration `json:"tracing,omitempty"`
+	// RevisionHistory keeps the payloads applied to Spinnaker, so that resources can be rolled back
+	RevisionHistory RevisionHistoryConfiguration `json:"revisionHistory,omitempty"`
+	// WatchSelector is a label selector of the resources managed by the controller, or empty for all resources, so
+	// that several deployments of the controller can share a cluster
+	WatchSelector string `json:"watchSelector,omitempty"`
+}
+
+// LeaderElectionConfiguration configures leader election of the manager
+type LeaderElectionConfiguration struct {
+	// LeaderElect enables leader election
+	LeaderElect bool `json:"leaderElect,omitempty"`
+	// ResourceName is the name of the ConfigMap used for leader election
+	ResourceName string `json:"resourceName,omitempty"`
+	// ResourceNamespace is the namespace of the ConfigMap, or empty for the namespace of the controller
+	ResourceNamespace string `json:"resourceNamespace,omitempty"`
+}
+
+// ShardingConfiguration spreads resources across replicas by their Spinnaker application, and each replica reconciles
+// the resources of the shards whose Leases it holds
+type ShardingConfiguration struct {
+	// Shards is the number of shards, or 0 to disable sharding
+	Shards int `json:"shards,omitempty"`
+	// LeaseNamespace is the namespace of the Leases, or empty for the namespace of the controller
+	LeaseNamespace string `json:"leaseNamespace,omitempty"`
+	// LeaseName prefixes the names of the Leases
+	LeaseName string `json:"leaseName,omitempty"`
+	// LeaseDuration is how long other replicas wait before taking over a shard that is no longer renewed
+	LeaseDuration metaV1.Duration `json:"leaseDuration,omitempty"`
+	// RenewDeadline is how long a replica keeps a shard without renewing its Lease
+	RenewDeadline metaV1.Duration `json:"renewDeadline,omitempty"`
+	// RetryPeriod is how often shards are acquired, renewed and rebalanced
+	RetryPeriod metaV1.Duration `json:"retryPeriod,omitempty"`
+}
+
+// AuditConfiguration records every change pushed to Spinnaker, to the log stream named audit and optionally to a file
+type AuditConfiguration struct {
+	// File is where audit records are appended as JSON lines, or empty to only log them
+	File string `json:"file,omitempty"`
+}
+
+// TracingConfiguration exports OpenTelemetry traces
+type TracingConfiguration struct {
+	// Endpoint is the OTLP/HTTP endpoint that traces are exported to, or empty to disable tracing
+	Endpoint string `json:"endpoint,omitempty"`
+	// SampleRatio is the ratio of reconciliations traced
+	SampleRatio float64 `json:"sampleRatio,omitempty"`
+}
+
+// RevisionHistoryConfiguration keeps the last payloads applied to Spinnaker per resource in ControllerRevisions
+type RevisionHistoryConfiguration struct {
+	// Limit is the number of revisions kept per resource, or 0 to keep no history
+	Limit int `json:"limit,omitempty"`
+	// Namespace is where ControllerRevisions are kept, or empty for the namespace of the controller
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// GateConfiguration configures requests to Spinnaker Gate
+type GateConfiguration struct {
+	// Endpoint is the endpoint of Spinnaker Gate
+	Endpoint string `json:"endpoint,omitempty"`
+	// Timeout bounds each request
+	Timeout metaV1.Duration `json:"timeout,omitempty"`
+	// MaxRetries is the number of retries of a request that failed with 5xx or 429
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// QPS is the rate of requests shared by all controllers, or 0 to disable rate limiting
+	QPS float64 `json:"qps,omitempty"`
+	// Burst is the number of requests that can be sent at once above QPS
+	Burst int `json:"burst,omitempty"`
+	// Auth configures authentication to Gate
+	Auth GateAuthConfiguration `json:"auth,omitempty"`
+}
+
+// GateAuthConfiguration configures authentication to Gate. Secrets are read from files, e.g. mounted Secrets, so that
+// they do not live in the configuration file.
+type GateAuthConfiguration struct {
+	// Headers are added to every request
+	Headers map[string]string `json:"headers,omitempty"`
+	// BearerTokenFile is the file of a token sent in the Authorization header
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+	// CertFile is the file of the client certificate for x509 authentication
+	CertFile string `json:"certFile,omitempty"`
+	// KeyFile is the file of the key of the client certificate
+	KeyFile string `json:"keyFile,omitempty"`
+	// CAFile is the file of the certificate authorities that verify Gate, or empty for the ones of the system
+	CAFile string `json:"caFile,omitempty"`
+}
+
+// ReconciliationConfiguration configures the timings and defaults of reconciliation
+type ReconciliationConfiguration struct {
+	// DependencyWaitInterval is how often a resource checks whether its dependencies have been created
+	DependencyWaitInterval metaV1.Duration `json:"dependencyWaitInterval,omitempty"`
+	// ExecutionPollInterval is how often an execution in flight is polled
+	ExecutionPollInterval metaV1.Duration `json:"executionPollInterval,omitempty"`
+	// ExecutionResyncInterval is how often the last execution of a pipeline is checked while it is idle, backing off up
+	// to 32 times as long the longer it has been idle
+	ExecutionResyncInterval metaV1.Duration `json:"executionResyncInterval,omitempty"`
+	// ExecutionTriggerTimeout is how long a triggered PipelineExecution is searched for before it fails
+	ExecutionTriggerTimeout metaV1.Duration `json:"executionTriggerTimeout,omitempty"`
+	// InvalidResyncInterval is how often an invalid resource is checked again
+	InvalidResyncInterval metaV1.Duration `json:"invalidResyncInterval,omitempty"`
+	// TaskPollTimeout is how long a task submitted to Orca is polled until it finishes
+	TaskPollTimeout metaV1.Duration `json:"taskPollTimeout,omitempty"`
+	// DeletionPolicy is Delete or Retain, which is used unless a resource has its own
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+// PauseConfiguration pauses reconciliation of all resources
+type PauseConfiguration struct {
+	// Paused pauses all resources
+	Paused bool `json:"paused,omitempty"`
+	// ConfigMap is the namespace/name of a ConfigMap which pauses all resources while its "paused" key is "true"
+	ConfigMap string `json:"configMap,omitempty"`
+}
+
+// ControllerOptions configures one controller
+type ControllerOptions struct {
+	// Enabled registers the controller, which defaults to true
+	Enabled *bool `json:"enabled,omitempty"`
+	// MaxConcurrentReconciles overrides the number of concurrent reconciliations of the controller
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+}
+
+// Default returns the configuration used when no file is given
+func Default() *ControllerConfiguration {
+	return &ControllerConfiguration{
+		TypeMeta: metaV1.TypeMeta{
+			APIVersion: APIVersion,
+			Kind:       Kind,
+		},
+		MetricsBindAddress: ":8080",
+		Port:               9443,
+		LeaderElection: LeaderElectionConfiguration{
+			ResourceName: "spinnaker-dcd-controller",
+		},
+		SyncPeriod: metaV1.Duration{Duration: 10 * time.Hour},
+		Gate: GateConfiguration{
+			Endpoint:   "http://spin-gate.spinnaker.svc.cluster.local:8084",
+			Timeout:    metaV1.Duration{Duration: 30 * time.Second},
+			MaxRetries: 3,
+			QPS:        10,
+			Burst:      20,
+		},
+		Tracing: TracingConfiguration{
+			SampleRatio: 1,
+		},
+		RevisionHistory: RevisionHistoryConfiguration{
+			Limit: 10,
+		},
+		Sharding: ShardingConfiguration{
+			LeaseName:     "spinnaker-dcd-controller",
+			LeaseDuration: metaV1.Duration{Duration: 15 * time.Second},
+			RenewDeadline: metaV1.Duration{Duration: 10 * time.Second},
+			RetryPeriod:   metaV1.Duration{Duration: 2 * time.Second},
+		},
+		Reconciliation: ReconciliationConfiguration{
+			DependencyWaitInterval:  metaV1.Duration{Duration: 10 * time.Second},
+			ExecutionPollInterval:   metaV1.Duration{Duration: 10 * time.Second},
+			ExecutionResyncInterval: metaV1.Duration{Duration: 60 * time.Second},
+			ExecutionTriggerTimeout: metaV1.Duration{Duration: 10 * time.Minute},
+			InvalidResyncInterval:   metaV1.Duration{Duration: 60 * time.Second},
+			TaskPollTimeout:         metaV1.Duration{Duration: 30 * time.Second},
+			DeletionPolicy:          DeletionPolicyDelete,
+		},
+		MaxConcurrentReconciles: 1,
+	}
+}
+
+// Load returns the configuration of the file at path over the defaults
+func Load(path string) (*ControllerConfiguration, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read configuration file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse returns the configuration of data over the defaults. Unknown fields are rejected, so that a typo does not
+// silently fall back to a default.
+func Parse(data []byte) (*ControllerConfiguration, error) {
+	c := Default()
+	// The file must declare its version rather than inherit the one of the defaults.
+	c.TypeMeta = metaV1.TypeMeta{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, xerrors.Errorf("invalid configuration file: %w", err)
+	}
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		return nil, xerrors.Errorf("invalid configuration file: expected %s %s, got %s %s", APIVersion, Kind, c.APIVersion, c.Kind)
+	}
+	return c, nil
+}
+
+// Validate returns an error when c cannot be applied
+func (c *ControllerConfiguration) Validate() error {
+	if c.MaxConcurrentReconciles < 1 {
+		return xerrors.Errorf("invalid maxConcurrentReconciles %d: must be at least 1", c.MaxConcurrentReconciles)
+	}
+	for kind, options := range c.Controllers {
+		if !isKind(kind) {
+			return xerrors.Errorf("invalid controller %q: must be one of %s", kind, strings.Join(Kinds, ", "))
+		}
+		if options.MaxConcurrentReconciles < 0 {
+			return xerrors.Errorf("invalid maxConcurrentReconciles %d of %s: must not be negative", options.MaxConcurrentReconciles, kind)
+		}
+	}
+	switch c.Reconciliation.DeletionPolicy {
+	case DeletionPolicyDelete, DeletionPolicyRetain:
+	default:
+		return xerrors.Errorf("invalid deletionPolicy %q: must be %s or %s", c.Reconciliation.DeletionPolicy, DeletionPolicyDelete, DeletionPolicyRetain)
+	}
+	for name, d := range map[string]metaV1.Duration{
+		"dependencyWaitInterval":  c.Reconciliation.DependencyWaitInterval,
+		"executionPollInterval":   c.Reconciliation.ExecutionPollInterval,
+		"executionResyncInterval": c.Reconciliation.ExecutionResyncInterval,
+		"executionTriggerTimeout": c.Reconciliation.ExecutionTriggerTimeout,
+		"invalidResyncInterval":   c.Reconciliation.InvalidResyncInterval,
+		"taskPollTimeout":         c.Reconciliation.TaskPollTimeout,
+	} {
+		if d.Duration <= 0 {
+			return xerrors.Errorf("invalid %s %s: must be positive", name, d.Duration)
+		}
+	}
+	if c.Sharding.Shards < 0 {
+		return xerrors.Errorf("invalid sharding.shards %d: must not be negative", c.Sharding.Shards)
+	}
+	if c.Sharding.Shards > 0 {
+		if c.LeaderElection.LeaderElect {
+			return xerrors.New("invalid sharding: leader election must be disabled, since every replica reconciles its own shards")
+		}
+		if !(0 < c.Sharding.RetryPeriod.Duration && c.Sharding.RetryPeriod.Duration < c.Sharding.RenewDeadline.Duration && c.Sharding.RenewDeadline.Duration < c.Sharding.LeaseDuration.Duration) {
+			return xerrors.New("invalid sharding: retryPeriod, renewDeadline and leaseDuration must be positive and increasing")
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return xerrors.Errorf("invalid tracing.sampleRatio %g: must be between 0 and 1", c.Tracing.SampleRatio)
+	}
+	if c.RevisionHistory.Limit < 0 {
+		return xerrors.Errorf("invalid revisionHistory.limit %d: must not be negative", c.RevisionHistory.Limit)
+	}
+	if _, err := c.Selector(); err != nil {
+		return err
+	}
+	if c.Gate.Auth.CertFile != "" && c.Gate.Auth.KeyFile == "" || c.Gate.Auth.CertFile == "" && c.Gate.Auth.KeyFile != "" {
+		return xerrors.New("invalid gate.auth: certFile and keyFile must be set together")
+	}
+	return nil
+}
+
+// Enabled returns whether the controller of kind is registered
+func (c *ControllerConfiguration) Enabled(kind string) bool {
+	if enabled := c.Controllers[kind].Enabled; enabled != nil {
+		return *enabled
+	}
+	return true
+}
+
+// EnableOnly enables the controllers of kinds and disables the others
+func (c *ControllerConfiguration) EnableOnly(kinds []string) error {
+	enabled := map[string]bool{}
+	for _, kind := range kinds {
+		if !isKind(kind) {
+			return xerrors.Errorf("invalid controller %q: must be one of %s", kind, strings.Join(Kinds, ", "))
+		}
+		enabled[kind] = true
+	}
+	if c.Controllers == nil {
+		c.Controllers = map[string]ControllerOptions{}
+	}
+	for _, kind := range Kinds {
+		options := c.Controllers[kind]
+		options.Enabled = func(enabled bool) *bool { return &enabled }(enabled[kind])
+		c.Controllers[kind] = options
+	}
+	return nil
+}
+
+// Selector returns the selector of the resources managed by the controller, or nil for all resources
+func (c *ControllerConfiguration) Selector() (labels.Selector, error) {
+	if c.WatchSelector == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(c.WatchSelector)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid watchSelector %q: %w", c.WatchSelector, err)
+	}
+	return selector, nil
+}
+
+// ConcurrentReconciles returns the number of concurrent reconciliations of the controller of kind
+func (c *ControllerConfiguration) ConcurrentReconciles(kind string) int {
+	if n := c.Controllers[kind].MaxConcurrentReconciles; n > 0 {
+		return n
+	}
+	return c.MaxConcurrentReconciles
+}
+
+func isKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresRestart returns true when c and other differ in fields that are applied only on start, i.e. anything but
+// reconciliation and verbosity.
+func (c *ControllerConfiguration) RequiresRestart(other *ControllerConfiguration) bool {
+	strip := func(c *ControllerConfiguration) ControllerConfiguration {
+		stripped := *c
+		stripped.Reconciliation = ReconciliationConfiguration{}
+		stripped.Verbose = false
+		return stripped
+	}
+	return !reflect.DeepEqual(strip(c), strip(other))
+}
+
+// Watch calls onChange when the content of the file at path changes, until stop is closed. The file is polled, since a
+// ConfigMap volume is updated by swapping a symbolic link, which file notifications do not follow reliably.
+func Watch(path string, interval time.Duration, stop <-chan struct{}, onChange func()) {
+	last, _ := ioutil.ReadFile(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil || bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+		onChange()
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(`
+apiVersion: config.spinnaker.kaidotdev.github.io/v1alpha1
+kind: ControllerConfiguration
+gate:
+  endpoint: https://gate.example.com
+  qps: 2.5
+reconciliation:
+  dependencyWaitInterval: 30s
+  deletionPolicy: Retain
+controllers:
+  Pipeline:
+    maxConcurrentReconciles: 4
+  CanaryConfig:
+    enabled: false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Gate.Endpoint != "https://gate.example.com" || c.Gate.QPS != 2.5 {
+		t.Fatalf("unexpected gate: %+v", c.Gate)
+	}
+	if c.Gate.Burst != Default().Gate.Burst {
+		t.Fatalf("expected default burst, got %d", c.Gate.Burst)
+	}
+	if c.Reconciliation.DependencyWaitInterval.Duration != 30*time.Second || c.Reconciliation.DeletionPolicy != DeletionPolicyRetain {
+		t.Fatalf("unexpected reconciliation: %+v", c.Reconciliation)
+	}
+	if c.Reconciliation.TaskPollTimeout.Duration != 30*time.Second {
+		t.Fatalf("expected default task poll timeout, got %s", c.Reconciliation.TaskPollTimeout.Duration)
+	}
+	if c.ConcurrentReconciles("Pipeline") != 4 || c.ConcurrentReconciles("Application") != 1 {
+		t.Fatalf("unexpected concurrency: %+v", c.Controllers)
+	}
+	if c.Enabled("CanaryConfig") || !c.Enabled("Pipeline") {
+		t.Fatalf("unexpected enablement: %+v", c.Controllers)
+	}
+}
+
+func TestParseRejectsInvalidFile(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown field": "apiVersion: config.spinnaker.kaidotdev.github.io/v1alpha1\nkind: ControllerConfiguration\ngate:\n  endpiont: http://gate\n",
+		"unknown kind":  "apiVersion: config.spinnaker.kaidotdev.github.io/v1alpha1\nkind: Configuration\n",
+		"no version":    "kind: ControllerConfiguration\n",
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	c := Default()
+	c.Reconciliation.DeletionPolicy = "Orphan"
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for unknown deletion policy")
+	}
+
+	c = Default()
+	c.Tracing.SampleRatio = 1.5
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for sample ratio above 1")
+	}
+}
+
+func TestRequiresRestart(t *testing.T) {
+	c := Default()
+
+	reloaded := Default()
+	reloaded.Reconciliation.DependencyWaitInterval.Duration = time.Minute
+	reloaded.Verbose = true
+	if c.RequiresRestart(reloaded) {
+		t.Error("reconciliation and verbosity must be reloaded without restart")
+	}
+
+	reloaded.Gate.Endpoint = "http://another-gate:8084"
+	if !c.RequiresRestart(reloaded) {
+		t.Error("gate endpoint must require restart")
+	}
+}
+
+func TestEnableOnly(t *testing.T) {
+	c := Default()
+	if err := c.EnableOnly([]string{"CanaryConfig", "CanaryAnalysis"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range Kinds {
+		expected := kind == "CanaryConfig" || kind == "CanaryAnalysis"
+		if c.Enabled(kind) != expected {
+			t.Errorf("expected %s to be enabled=%t", kind, expected)
+		}
+	}
+
+	if err := c.EnableOnly([]string{"Canary"}); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestSelector(t *testing.T) {
+	c := Default()
+	if selector, err := c.Selector(); err != nil || selector != nil {
+		t.Fatalf("expected no selector, got %v, %v", selector, err)
+	}
+
+	c.WatchSelector = "spinnaker.io/managed-by=team-a"
+	selector, err := c.Selector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !selector.Matches(labels.Set{"spinnaker.io/managed-by": "team-a"}) || selector.Matches(labels.Set{"spinnaker.io/managed-by": "team-b"}) {
+		t.Fatalf("unexpected selector: %s", selector)
+	}
+
+	c.WatchSelector = "spinnaker.io/managed-by in team-a"
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for invalid selector")
+	}
+}
+
+func TestValidateSharding(t *testing.T) {
+	c := Default()
+	c.Sharding.Shards = 4
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.LeaderElection.LeaderElect = true
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for sharding with leader election")
+	}
+
+	c.LeaderElection.LeaderElect = false
+	c.Sharding.RenewDeadline = c.Sharding.LeaseDuration
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for renew deadline not shorter than lease duration")
+	}
+}
@@ -0,0 +1,962 @@
+// Package fakegate provides an in-memory Spinnaker Gate for tests of the reconcilers.
+//
+// It implements the endpoints that roer and the spin Gate API client call, keeps applications, pipelines, pipeline
+// templates, projects, canary configs and application notifications in memory, and completes every task synchronously. Failures are injected
+// per endpoint with Fail, so that retries and error paths can be driven deterministically. It also serves the service
+// accounts of Front50, which the controller calls directly, so that one server stands for both.
+package fakegate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	taskStatusSucceeded = "SUCCEEDED"
+	taskStatusTerminal  = "TERMINAL"
+)
+
+// Request is a request received by the server
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+type failure struct {
+	method     string
+	path       string
+	statusCode int
+	remaining  int
+}
+
+type task struct {
+	ID          string `json:"id"`
+	Application string `json:"application"`
+	Status      string `json:"status"`
+	StartTime   int64  `json:"startTime"`
+	EndTime     int64  `json:"endTime"`
+}
+
+type execution struct {
+	ID               string `json:"id"`
+	Application      string `json:"application"`
+	Name             string `json:"name"`
+	PipelineConfigID string `json:"pipelineConfigId"`
+	Status           string `json:"status"`
+	BuildTime        int64  `json:"buildTime"`
+	StartTime        int64  `json:"startTime"`
+	EndTime          int64  `json:"endTime,omitempty"`
+
+	Trigger map[string]interface{} `json:"trigger,omitempty"`
+	Stages  []*stage               `json:"stages,omitempty"`
+}
+
+type canaryExecution struct {
+	canaryConfigID            string
+	application               string
+	parentPipelineExecutionID string
+}
+
+type stage struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// Server is an in-memory Spinnaker Gate
+type Server struct {
+	server *httptest.Server
+
+	mu                sync.Mutex
+	nextID            int
+	requests          []Request
+	failures          []*failure
+	terminalTasks     int
+	droppedTriggers   int
+	applications      map[string]map[string]interface{}
+	pipelines         map[string]map[string]interface{}
+	pipelineTemplates map[string]map[string]interface{}
+	projects          map[string]map[string]interface{}
+	canaryConfigs     map[string]map[string]interface{}
+	notifications     map[string]map[string]interface{}
+	canaryExecutions  map[string]*canaryExecution
+	tasks             map[string]*task
+	executions        map[string]*execution
+	executionStatus   string
+	user              map[string]interface{}
+	serviceAccounts   []map[string]interface{}
+	credentials       []map[string]interface{}
+	metricDescriptors map[string][]map[string]interface{}
+	canaryVerdict     string
+	canaryScore       float64
+}
+
+// NewServer starts a server, which has to be closed by Close
+func NewServer() *Server {
+	s := &Server{
+		applications:      map[string]map[string]interface{}{},
+		pipelines:         map[string]map[string]interface{}{},
+		pipelineTemplates: map[string]map[string]interface{}{},
+		projects:          map[string]map[string]interface{}{},
+		canaryConfigs:     map[string]map[string]interface{}{},
+		notifications:     map[string]map[string]interface{}{},
+		canaryExecutions:  map[string]*canaryExecution{},
+		tasks:             map[string]*task{},
+		executions:        map[string]*execution{},
+		executionStatus:   taskStatusSucceeded,
+		user:              map[string]interface{}{"username": "anonymous"},
+		metricDescriptors: map[string][]map[string]interface{}{},
+		canaryVerdict:     "Pass",
+		canaryScore:       100,
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the endpoint of the server
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Fail makes the next times requests whose method and path match respond with statusCode, or all of them when times
+// is not positive. The path matches by prefix, so that "/v2/canaryConfig" also matches a single config.
+func (s *Server) Fail(method string, path string, statusCode int, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &failure{method: method, path: path, statusCode: statusCode, remaining: times})
+}
+
+// FailTasks makes the next times tasks end with TERMINAL without changing anything
+func (s *Server) FailTasks(times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terminalTasks = times
+}
+
+// DropTriggers makes the next times pipeline triggers be accepted without starting an execution, like Orca dropping them
+func (s *Server) DropTriggers(times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.droppedTriggers = times
+}
+
+// ClearFailures removes all failures set by Fail, FailTasks and DropTriggers
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+	s.terminalTasks = 0
+	s.droppedTriggers = 0
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// CountRequests returns the number of requests received with method and path
+func (s *Server) CountRequests(method string, path string) int {
+	count := 0
+	for _, request := range s.Requests() {
+		if request.Method == method && request.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+// Application returns the application saved by tasks
+func (s *Server) Application(name string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	application, ok := s.applications[strings.ToLower(name)]
+	return application, ok
+}
+
+// AddApplication saves the application as if it had been created outside the controller
+func (s *Server) AddApplication(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applications[strings.ToLower(name)] = map[string]interface{}{"name": name}
+}
+
+// Pipeline returns the pipeline config saved in the application
+func (s *Server) Pipeline(application string, name string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pipeline, ok := s.pipelines[pipelineKey(application, name)]
+	return pipeline, ok
+}
+
+// Pipelines returns the number of pipeline configs
+func (s *Server) Pipelines() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pipelines)
+}
+
+// PipelineTemplate returns the published pipeline template
+func (s *Server) PipelineTemplate(id string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	template, ok := s.pipelineTemplates[id]
+	return template, ok
+}
+
+// Project returns the project saved by tasks
+func (s *Server) Project(name string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	project, ok := s.projects[strings.ToLower(name)]
+	return project, ok
+}
+
+// AddProject saves the project as if it had been created outside the controller, e.g. in Deck
+func (s *Server) AddProject(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects[strings.ToLower(name)] = map[string]interface{}{"id": s.newID("project"), "name": name}
+}
+
+// CanaryConfig returns the saved canary config
+func (s *Server) CanaryConfig(id string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	canaryConfig, ok := s.canaryConfigs[id]
+	return canaryConfig, ok
+}
+
+// AddCanaryConfig saves the canary config as if it had been created outside the controller, e.g. in Deck
+func (s *Server) AddCanaryConfig(id string, canaryConfig map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	canaryConfig["id"] = id
+	s.canaryConfigs[id] = canaryConfig
+}
+
+// ApplicationNotifications returns the notifications saved for the application
+func (s *Server) ApplicationNotifications(name string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notifications, ok := s.notifications[strings.ToLower(name)]
+	return notifications, ok
+}
+
+// AddApplicationNotifications saves the notifications as if they had been set outside the controller, e.g. in Deck
+func (s *Server) AddApplicationNotifications(name string, notifications map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifications[strings.ToLower(name)] = notifications
+}
+
+// AddMetricsAccount registers a Kayenta account that stores metrics of metricsType
+func (s *Server) AddMetricsAccount(name string, metricsType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials = append(s.credentials, map[string]interface{}{
+		"name":           name,
+		"type":           metricsType,
+		"supportedTypes": []string{"METRICS_STORE"},
+	})
+}
+
+// AddMetricDescriptor makes the metrics service of the account list the metric. Accounts without descriptors do not
+// list metadata at all.
+func (s *Server) AddMetricDescriptor(metricsAccountName string, metricName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metricDescriptors[metricsAccountName] = append(s.metricDescriptors[metricsAccountName], map[string]interface{}{"name": metricName})
+}
+
+// SetUser sets the user that Gate authenticates the controller as
+func (s *Server) SetUser(username string, roles []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = map[string]interface{}{"username": username, "roles": roles}
+}
+
+// AddServiceAccount registers a service account visible to the user
+func (s *Server) AddServiceAccount(name string, memberOf []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serviceAccounts = append(s.serviceAccounts, map[string]interface{}{"name": name, "memberOf": memberOf})
+}
+
+// ServiceAccount returns the service account of name saved in Front50
+func (s *Server) ServiceAccount(name string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, serviceAccount := range s.serviceAccounts {
+		if serviceAccount["name"] == name {
+			return serviceAccount, true
+		}
+	}
+	return nil, false
+}
+
+// SetExecutionStatus sets the status of executions started from now on, and of the execution of id if it is given
+func (s *Server) SetExecutionStatus(id string, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == "" {
+		s.executionStatus = status
+		return
+	}
+	if e, ok := s.executions[id]; ok {
+		e.Status = status
+		if !isRunning(status) {
+			e.EndTime = now()
+		}
+	}
+}
+
+// SetExecutionStage sets the status of the stage of name in the execution of id, adding the stage if it is new
+func (s *Server) SetExecutionStage(id string, name string, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.executions[id]
+	if !ok {
+		return
+	}
+	for _, stage := range e.Stages {
+		if stage.Name == name {
+			stage.Status = status
+			return
+		}
+	}
+	e.Stages = append(e.Stages, &stage{Name: name, Status: status})
+}
+
+// AddCanaryExecution starts a canary analysis as if its ID had been lost, and returns the ID
+func (s *Server) AddCanaryExecution(canaryConfigID string, application string, parentPipelineExecutionID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.newID("canary-execution")
+	s.canaryExecutions[id] = &canaryExecution{
+		canaryConfigID:            canaryConfigID,
+		application:               application,
+		parentPipelineExecutionID: parentPipelineExecutionID,
+	}
+	return id
+}
+
+// SetCanaryVerdict sets the result of canary analyses
+func (s *Server) SetCanaryVerdict(classification string, score float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.canaryVerdict = classification
+	s.canaryScore = score
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: req.Method, Path: req.URL.Path, Body: body})
+	if statusCode, ok := s.failure(req.Method, req.URL.Path); ok {
+		writeJSON(w, statusCode, map[string]interface{}{"status": statusCode, "message": "injected failure"})
+		return
+	}
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	route := func(method string, pattern string) bool {
+		if req.Method != method {
+			return false
+		}
+		parts := strings.Split(strings.Trim(pattern, "/"), "/")
+		if len(parts) != len(segments) {
+			return false
+		}
+		for i, part := range parts {
+			if !strings.HasPrefix(part, "{") && part != segments[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case route(http.MethodGet, "/applications/{application}"):
+		s.getApplication(w, segments[1])
+	case route(http.MethodPost, "/applications/{application}/tasks"):
+		s.submitTask(w, body)
+	case route(http.MethodGet, "/tasks/{id}"):
+		s.getTask(w, segments[1])
+	case route(http.MethodGet, "/applications/{application}/pipelineConfigs"):
+		s.listPipelineConfigs(w, segments[1])
+	case route(http.MethodGet, "/applications/{application}/pipelineConfigs/{name}"):
+		s.getPipelineConfig(w, segments[1], segments[3])
+	case route(http.MethodGet, "/applications/{application}/executions/search"):
+		s.searchExecutions(w, segments[1], req.URL.Query().Get("pipelineName"), req.URL.Query().Get("trigger"))
+	case route(http.MethodPost, "/pipelines"):
+		s.savePipeline(w, body)
+	case route(http.MethodPost, "/pipelines/move"):
+		s.renamePipeline(w, body)
+	case route(http.MethodGet, "/pipelines/{id}"):
+		s.getExecution(w, segments[1])
+	case route(http.MethodPut, "/pipelines/{id}/cancel"):
+		s.cancelExecution(w, segments[1])
+	case route(http.MethodPost, "/pipelines/{application}/{name}"):
+		s.startExecution(w, segments[1], segments[2], body)
+	case route(http.MethodDelete, "/pipelines/{application}/{name}"):
+		s.deletePipeline(w, segments[1], segments[2])
+	case route(http.MethodGet, "/executions"):
+		s.listExecutions(w, req.URL.Query().Get("pipelineConfigIds"))
+	case route(http.MethodGet, "/pipelineTemplates/{id}"):
+		s.getPipelineTemplate(w, segments[1])
+	case route(http.MethodPost, "/pipelineTemplates"), route(http.MethodPost, "/pipelineTemplates/{id}"):
+		s.publishPipelineTemplate(w, body)
+	case route(http.MethodDelete, "/pipelineTemplates/{id}"):
+		s.deletePipelineTemplate(w, segments[1])
+	case route(http.MethodGet, "/projects/{id}"):
+		s.getProject(w, segments[1])
+	case route(http.MethodGet, "/v2/canaryConfig"):
+		s.listCanaryConfigs(w)
+	case route(http.MethodPost, "/v2/canaryConfig"):
+		s.saveCanaryConfig(w, "", body)
+	case route(http.MethodGet, "/v2/canaryConfig/{id}"):
+		s.getCanaryConfig(w, segments[2])
+	case route(http.MethodPut, "/v2/canaryConfig/{id}"):
+		s.saveCanaryConfig(w, segments[2], body)
+	case route(http.MethodDelete, "/v2/canaryConfig/{id}"):
+		s.deleteCanaryConfig(w, segments[2])
+	case route(http.MethodGet, "/v2/canaries/credentials"):
+		writeJSON(w, http.StatusOK, s.credentials)
+	case route(http.MethodGet, "/v2/canaries/metadata/metricsService"):
+		writeJSON(w, http.StatusOK, s.metricDescriptors[req.URL.Query().Get("metricsAccountName")])
+	case route(http.MethodPost, "/v2/canaries/canary/{canaryConfigId}"):
+		s.initiateCanary(w, segments[3], req.URL.Query())
+	case route(http.MethodGet, "/v2/canaries/canary/{canaryExecutionId}"):
+		s.getCanaryResult(w, segments[3])
+	case route(http.MethodGet, "/v2/canaries/{application}/executions"):
+		s.getCanaryExecutions(w, segments[2])
+	case route(http.MethodGet, "/notifications/application/{application}"):
+		s.getNotifications(w, segments[2])
+	case route(http.MethodPost, "/notifications/application/{application}"):
+		s.saveNotifications(w, segments[2], body)
+	case route(http.MethodGet, "/auth/user"):
+		writeJSON(w, http.StatusOK, s.user)
+	case route(http.MethodGet, "/auth/user/serviceAccounts"), route(http.MethodGet, "/serviceAccounts"):
+		writeJSON(w, http.StatusOK, s.serviceAccounts)
+	case route(http.MethodPost, "/serviceAccounts"):
+		s.saveServiceAccount(w, body, req.Header.Get("X-SPINNAKER-USER"))
+	case route(http.MethodDelete, "/serviceAccounts/{name}"):
+		s.deleteServiceAccount(w, segments[1])
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"status": http.StatusNotFound, "message": "no route"})
+	}
+}
+
+// saveServiceAccount records the user of the request as lastModifiedBy like Front50.
+func (s *Server) saveServiceAccount(w http.ResponseWriter, body []byte, user string) {
+	var serviceAccount map[string]interface{}
+	if err := json.Unmarshal(body, &serviceAccount); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	if user == "" {
+		user = "anonymous"
+	}
+	serviceAccount["lastModifiedBy"] = user
+	for i, existing := range s.serviceAccounts {
+		if existing["name"] == serviceAccount["name"] {
+			s.serviceAccounts[i] = serviceAccount
+			writeJSON(w, http.StatusOK, serviceAccount)
+			return
+		}
+	}
+	s.serviceAccounts = append(s.serviceAccounts, serviceAccount)
+	writeJSON(w, http.StatusOK, serviceAccount)
+}
+
+func (s *Server) deleteServiceAccount(w http.ResponseWriter, name string) {
+	for i, existing := range s.serviceAccounts {
+		if existing["name"] == name {
+			s.serviceAccounts = append(s.serviceAccounts[:i], s.serviceAccounts[i+1:]...)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	writeNotFound(w)
+}
+
+func (s *Server) failure(method string, path string) (int, bool) {
+	for i, f := range s.failures {
+		if f.method != method || !strings.HasPrefix(path, f.path) {
+			continue
+		}
+		if f.remaining > 0 {
+			f.remaining--
+			if f.remaining == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f.statusCode, true
+	}
+	return 0, false
+}
+
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d", prefix, s.nextID)
+}
+
+func (s *Server) getApplication(w http.ResponseWriter, name string) {
+	application, ok := s.applications[strings.ToLower(name)]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": name, "attributes": application})
+}
+
+// submitTask applies the jobs of the task right away, so that the task has already ended when it is polled.
+func (s *Server) submitTask(w http.ResponseWriter, body []byte) {
+	var request struct {
+		Application string                   `json:"application"`
+		Job         []map[string]interface{} `json:"job"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+
+	t := s.newTask(request.Application)
+	if t.Status == taskStatusSucceeded {
+		for _, job := range request.Job {
+			s.applyJob(job)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"ref": "/tasks/" + t.ID})
+}
+
+func (s *Server) applyJob(job map[string]interface{}) {
+	jobType, _ := job["type"].(string)
+	switch jobType {
+	case "createApplication", "updateApplication":
+		if application, ok := job["application"].(map[string]interface{}); ok {
+			name, _ := application["name"].(string)
+			s.applications[strings.ToLower(name)] = application
+		}
+	case "deleteApplication":
+		if application, ok := job["application"].(map[string]interface{}); ok {
+			name, _ := application["name"].(string)
+			delete(s.applications, strings.ToLower(name))
+		}
+	case "upsertProject":
+		if project, ok := job["project"].(map[string]interface{}); ok {
+			name, _ := project["name"].(string)
+			if _, ok := project["id"].(string); !ok {
+				project["id"] = s.newID("project")
+			}
+			s.projects[strings.ToLower(name)] = project
+		}
+	case "deleteProject":
+		if project, ok := job["project"].(map[string]interface{}); ok {
+			id, _ := project["id"].(string)
+			for name, existing := range s.projects {
+				if existing["id"] == id {
+					delete(s.projects, name)
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) getTask(w http.ResponseWriter, id string) {
+	t, ok := s.tasks[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) listPipelineConfigs(w http.ResponseWriter, application string) {
+	pipelines := []map[string]interface{}{}
+	for _, pipeline := range s.pipelines {
+		if name, _ := pipeline["application"].(string); strings.EqualFold(name, application) {
+			pipelines = append(pipelines, pipeline)
+		}
+	}
+	writeJSON(w, http.StatusOK, pipelines)
+}
+
+func (s *Server) getPipelineConfig(w http.ResponseWriter, application string, name string) {
+	pipeline, ok := s.pipelines[pipelineKey(application, name)]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, pipeline)
+}
+
+// savePipeline keeps the ID of the pipeline config with the same name like Front50, and issues one for a new one.
+func (s *Server) savePipeline(w http.ResponseWriter, body []byte) {
+	var pipeline map[string]interface{}
+	if err := json.Unmarshal(body, &pipeline); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	application, _ := pipeline["application"].(string)
+	name, _ := pipeline["name"].(string)
+	key := pipelineKey(application, name)
+	if id, _ := pipeline["id"].(string); id == "" {
+		if existing, ok := s.pipelines[key]; ok {
+			pipeline["id"] = existing["id"]
+		} else {
+			pipeline["id"] = s.newID("pipeline")
+		}
+	}
+	s.pipelines[key] = pipeline
+	w.WriteHeader(http.StatusOK)
+}
+
+// deletePipeline responds 404 to a missing pipeline like Front50.
+func (s *Server) deletePipeline(w http.ResponseWriter, application string, name string) {
+	if _, ok := s.pipelines[pipelineKey(application, name)]; !ok {
+		writeNotFound(w)
+		return
+	}
+	delete(s.pipelines, pipelineKey(application, name))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) renamePipeline(w http.ResponseWriter, body []byte) {
+	var request struct {
+		Application string `json:"application"`
+		From        string `json:"from"`
+		To          string `json:"to"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	pipeline, ok := s.pipelines[pipelineKey(request.Application, request.From)]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	delete(s.pipelines, pipelineKey(request.Application, request.From))
+	pipeline["name"] = request.To
+	s.pipelines[pipelineKey(request.Application, request.To)] = pipeline
+	w.WriteHeader(http.StatusOK)
+}
+
+// startExecution starts an execution with the trigger in body, which is empty when started by roer.
+func (s *Server) startExecution(w http.ResponseWriter, application string, name string, body []byte) {
+	pipeline, ok := s.pipelines[pipelineKey(application, name)]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if s.droppedTriggers > 0 {
+		s.droppedTriggers--
+		writeJSON(w, http.StatusAccepted, map[string]string{})
+		return
+	}
+	var trigger map[string]interface{}
+	_ = json.Unmarshal(body, &trigger)
+	pipelineConfigID, _ := pipeline["id"].(string)
+	e := &execution{
+		Trigger:          trigger,
+		ID:               s.newID("execution"),
+		Application:      application,
+		Name:             name,
+		PipelineConfigID: pipelineConfigID,
+		Status:           s.executionStatus,
+		BuildTime:        now(),
+		StartTime:        now(),
+	}
+	if !isRunning(e.Status) {
+		e.EndTime = now()
+	}
+	s.executions[e.ID] = e
+	writeJSON(w, http.StatusAccepted, map[string]string{"ref": "/pipelines/" + e.ID})
+}
+
+func (s *Server) getExecution(w http.ResponseWriter, id string) {
+	e, ok := s.executions[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (s *Server) cancelExecution(w http.ResponseWriter, id string) {
+	e, ok := s.executions[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	e.Status = "CANCELED"
+	e.EndTime = now()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listExecutions(w http.ResponseWriter, pipelineConfigIDs string) {
+	executions := []*execution{}
+	for _, e := range s.executions {
+		for _, id := range strings.Split(pipelineConfigIDs, ",") {
+			if e.PipelineConfigID == id {
+				executions = append(executions, e)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, executions)
+}
+
+// searchExecutions finds executions whose trigger contains every field of the base64 encoded JSON trigger, like Orca.
+func (s *Server) searchExecutions(w http.ResponseWriter, application string, pipelineName string, trigger string) {
+	fields := map[string]interface{}{}
+	if trigger != "" {
+		data, err := base64.StdEncoding.DecodeString(trigger)
+		if err == nil {
+			err = json.Unmarshal(data, &fields)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+			return
+		}
+	}
+	executions := []*execution{}
+	for _, e := range s.executions {
+		if !strings.EqualFold(e.Application, application) || (pipelineName != "" && e.Name != pipelineName) {
+			continue
+		}
+		matched := true
+		for key, value := range fields {
+			if fmt.Sprint(e.Trigger[key]) != fmt.Sprint(value) {
+				matched = false
+			}
+		}
+		if matched {
+			executions = append(executions, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, executions)
+}
+
+func (s *Server) getPipelineTemplate(w http.ResponseWriter, id string) {
+	template, ok := s.pipelineTemplates[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+func (s *Server) publishPipelineTemplate(w http.ResponseWriter, body []byte) {
+	var template map[string]interface{}
+	if err := json.Unmarshal(body, &template); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	t := s.newTask("spinnaker")
+	if t.Status == taskStatusSucceeded {
+		id, _ := template["id"].(string)
+		s.pipelineTemplates[id] = template
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"ref": "/tasks/" + t.ID})
+}
+
+func (s *Server) deletePipelineTemplate(w http.ResponseWriter, id string) {
+	t := s.newTask("spinnaker")
+	if t.Status == taskStatusSucceeded {
+		delete(s.pipelineTemplates, id)
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"ref": "/tasks/" + t.ID})
+}
+
+// newTask registers an ended task of the request, which is TERMINAL when tasks are set to fail.
+func (s *Server) newTask(application string) *task {
+	t := &task{
+		ID:          s.newID("task"),
+		Application: application,
+		Status:      taskStatusSucceeded,
+		StartTime:   now(),
+		EndTime:     now(),
+	}
+	if s.terminalTasks > 0 {
+		s.terminalTasks--
+		t.Status = taskStatusTerminal
+	}
+	s.tasks[t.ID] = t
+	return t
+}
+
+func (s *Server) getProject(w http.ResponseWriter, id string) {
+	for name, project := range s.projects {
+		if name == strings.ToLower(id) || project["id"] == id {
+			writeJSON(w, http.StatusOK, project)
+			return
+		}
+	}
+	writeNotFound(w)
+}
+
+// getNotifications responds with an empty config for an application without notifications, in the same way as Front50.
+func (s *Server) getNotifications(w http.ResponseWriter, application string) {
+	notifications, ok := s.notifications[strings.ToLower(application)]
+	if !ok {
+		notifications = map[string]interface{}{}
+	}
+	writeJSON(w, http.StatusOK, notifications)
+}
+
+func (s *Server) saveNotifications(w http.ResponseWriter, application string, body []byte) {
+	var notifications map[string]interface{}
+	if err := json.Unmarshal(body, &notifications); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	s.notifications[strings.ToLower(application)] = notifications
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) listCanaryConfigs(w http.ResponseWriter) {
+	canaryConfigs := []map[string]interface{}{}
+	for _, canaryConfig := range s.canaryConfigs {
+		canaryConfigs = append(canaryConfigs, canaryConfig)
+	}
+	writeJSON(w, http.StatusOK, canaryConfigs)
+}
+
+func (s *Server) getCanaryConfig(w http.ResponseWriter, id string) {
+	canaryConfig, ok := s.canaryConfigs[id]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, canaryConfig)
+}
+
+// saveCanaryConfig creates a canary config when id is empty and updates the one of id otherwise, in the same way as
+// Kayenta that rejects updating a missing config and creating an existing one.
+func (s *Server) saveCanaryConfig(w http.ResponseWriter, id string, body []byte) {
+	var canaryConfig map[string]interface{}
+	if err := json.Unmarshal(body, &canaryConfig); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	if id == "" {
+		id, _ = canaryConfig["id"].(string)
+		if id == "" {
+			id = s.newID("canary-config")
+		}
+		if _, ok := s.canaryConfigs[id]; ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"message": fmt.Sprintf("canary config %s already exists", id)})
+			return
+		}
+	} else if _, ok := s.canaryConfigs[id]; !ok {
+		writeNotFound(w)
+		return
+	}
+	canaryConfig["id"] = id
+	s.canaryConfigs[id] = canaryConfig
+	writeJSON(w, http.StatusOK, map[string]string{"canaryConfigId": id})
+}
+
+func (s *Server) deleteCanaryConfig(w http.ResponseWriter, id string) {
+	if _, ok := s.canaryConfigs[id]; !ok {
+		writeNotFound(w)
+		return
+	}
+	delete(s.canaryConfigs, id)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) initiateCanary(w http.ResponseWriter, canaryConfigID string, query url.Values) {
+	if _, ok := s.canaryConfigs[canaryConfigID]; !ok {
+		writeNotFound(w)
+		return
+	}
+	id := s.newID("canary-execution")
+	s.canaryExecutions[id] = &canaryExecution{
+		canaryConfigID:            canaryConfigID,
+		application:               query.Get("application"),
+		parentPipelineExecutionID: query.Get("parentPipelineExecutionId"),
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"canaryExecutionId": id})
+}
+
+func (s *Server) getCanaryExecutions(w http.ResponseWriter, application string) {
+	executions := []map[string]interface{}{}
+	for id, execution := range s.canaryExecutions {
+		if execution.application != application {
+			continue
+		}
+		executions = append(executions, map[string]interface{}{
+			"pipelineId":                id,
+			"application":               execution.application,
+			"canaryConfigId":            execution.canaryConfigID,
+			"parentPipelineExecutionId": execution.parentPipelineExecutionID,
+			"complete":                  true,
+			"status":                    strings.ToLower(taskStatusSucceeded),
+		})
+	}
+	writeJSON(w, http.StatusOK, executions)
+}
+
+func (s *Server) getCanaryResult(w http.ResponseWriter, id string) {
+	if _, ok := s.canaryExecutions[id]; !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"complete": true,
+		"status":   strings.ToLower(taskStatusSucceeded),
+		"result": map[string]interface{}{
+			"judgeResult": map[string]interface{}{
+				"score": map[string]interface{}{
+					"score":          s.canaryScore,
+					"classification": s.canaryVerdict,
+				},
+				"results": []interface{}{},
+			},
+		},
+	})
+}
+
+func pipelineKey(application string, name string) string {
+	return strings.ToLower(application) + "/" + name
+}
+
+func isRunning(status string) bool {
+	switch status {
+	case "NOT_STARTED", "RUNNING", "PAUSED", "SUSPENDED", "BUFFERED":
+		return true
+	}
+	return false
+}
+
+func now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]interface{}{"status": http.StatusNotFound, "message": "not found"})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,247 @@
+package fakegate
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"spinnaker-dcd-controller/internal/gateway"
+
+	"github.com/spinnaker/roer/spinnaker"
+	gate "github.com/spinnaker/spin/gateapi"
+)
+
+func newClient(t *testing.T, s *Server) gateway.Client {
+	t.Helper()
+	options := gateway.DefaultOptions()
+	options.BaseBackoff = time.Millisecond
+	options.MaxBackoff = time.Millisecond
+	options.FailureThreshold = 0
+	client, err := gateway.New(s.URL(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestApplicationTasks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	roer := newClient(t, s).Roer(context.Background())
+
+	submit := func(taskType string) *spinnaker.ExecutionResponse {
+		ref, err := roer.ApplicationSubmitTask("sample", spinnaker.Task{
+			Application: "sample",
+			Job: []interface{}{spinnaker.ApplicationJob{
+				Application: map[string]interface{}{"name": "sample", "email": "sample@example.com"},
+				Type:        taskType,
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := roer.GetTask(ref.Ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	if response := submit("createApplication"); response.Status != taskStatusSucceeded || response.EndTime == 0 {
+		t.Fatalf("unexpected task: %+v", response)
+	}
+	if application, ok := s.Application("sample"); !ok || application["email"] != "sample@example.com" {
+		t.Fatalf("application is not created: %v", application)
+	}
+
+	s.FailTasks(1)
+	if response := submit("deleteApplication"); response.Status != taskStatusTerminal {
+		t.Fatalf("expected TERMINAL task, got %+v", response)
+	}
+	if _, ok := s.Application("sample"); !ok {
+		t.Fatal("failed task must not delete the application")
+	}
+
+	submit("deleteApplication")
+	if _, ok := s.Application("sample"); ok {
+		t.Fatal("application is not deleted")
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+	gateClient := client.Gate(context.Background())
+
+	save := func(pipeline map[string]interface{}) {
+		resp, err := gateClient.PipelineControllerApi.SavePipelineUsingPOST(gateClient.Context, pipeline, &gate.PipelineControllerApiSavePipelineUsingPOSTOpts{})
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("failed to save pipeline: %v, %v", resp, err)
+		}
+	}
+	save(map[string]interface{}{"application": "sample", "name": "deploy"})
+	config, err := client.Roer(context.Background()).GetPipelineConfig("sample", "deploy")
+	if err != nil || config == nil || config.ID == "" {
+		t.Fatalf("pipeline is not saved: %+v, %v", config, err)
+	}
+	save(map[string]interface{}{"application": "sample", "name": "deploy", "description": "updated"})
+	if pipeline, _ := s.Pipeline("sample", "deploy"); pipeline["id"] != config.ID || s.Pipelines() != 1 {
+		t.Fatalf("pipeline is saved as another one: %v", pipeline)
+	}
+
+	resp, err := gateClient.PipelineControllerApi.RenamePipelineUsingPOST(gateClient.Context, map[string]string{
+		"application": "sample", "from": "deploy", "to": "release",
+	})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to rename pipeline: %v, %v", resp, err)
+	}
+	if pipeline, ok := s.Pipeline("sample", "release"); !ok || pipeline["id"] != config.ID {
+		t.Fatalf("pipeline is not renamed: %v", pipeline)
+	}
+
+	if err := client.Roer(context.Background()).DeletePipeline("sample", "release"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Pipelines() != 0 {
+		t.Fatal("pipeline is not deleted")
+	}
+}
+
+func TestPipelineTemplates(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	roer := newClient(t, s).Roer(context.Background())
+
+	for _, description := range []string{"before", "after"} {
+		ref, err := roer.PublishTemplate(map[string]interface{}{
+			"id":       "sample",
+			"metadata": map[string]interface{}{"description": description},
+		}, spinnaker.PublishTemplateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := roer.GetTask(ref.Ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	template, ok := s.PipelineTemplate("sample")
+	if !ok || template["metadata"].(map[string]interface{})["description"] != "after" {
+		t.Fatalf("template is not published: %v", template)
+	}
+	if count := s.CountRequests(http.MethodPost, "/pipelineTemplates/sample"); count != 1 {
+		t.Fatalf("expected the existing template to be updated once, got %d", count)
+	}
+
+	if _, err := roer.DeleteTemplate("sample"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.PipelineTemplate("sample"); ok {
+		t.Fatal("template is not deleted")
+	}
+}
+
+func TestCanaryConfigs(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	gateClient := newClient(t, s).Gate(context.Background())
+	api := gateClient.V2CanaryConfigControllerApi
+
+	_, resp, _ := api.GetCanaryConfigUsingGET(gateClient.Context, "sample", &gate.V2CanaryConfigControllerApiGetCanaryConfigUsingGETOpts{})
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %v", resp)
+	}
+	if _, _, err := api.CreateCanaryConfigUsingPOST(gateClient.Context, map[string]interface{}{"id": "sample", "name": "before"}, &gate.V2CanaryConfigControllerApiCreateCanaryConfigUsingPOSTOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := api.UpdateCanaryConfigUsingPUT(gateClient.Context, map[string]interface{}{"id": "sample", "name": "after"}, "sample", &gate.V2CanaryConfigControllerApiUpdateCanaryConfigUsingPUTOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if canaryConfig, _ := s.CanaryConfig("sample"); canaryConfig["name"] != "after" {
+		t.Fatalf("canary config is not updated: %v", canaryConfig)
+	}
+
+	raw, _, err := gateClient.V2CanaryControllerApi.InitiateCanaryUsingPOST(gateClient.Context, "sample", map[string]interface{}{}, &gate.V2CanaryControllerApiInitiateCanaryUsingPOSTOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := raw.(map[string]interface{})["canaryExecutionId"].(string)
+	result, _, err := gateClient.V2CanaryControllerApi.GetCanaryResultUsingGET1(gateClient.Context, id, &gate.V2CanaryControllerApiGetCanaryResultUsingGET1Opts{})
+	if err != nil || result.(map[string]interface{})["complete"] != true {
+		t.Fatalf("unexpected canary result: %v, %v", result, err)
+	}
+
+	if _, err := api.DeleteCanaryConfigUsingDELETE(gateClient.Context, "sample", &gate.V2CanaryConfigControllerApiDeleteCanaryConfigUsingDELETEOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.CanaryConfig("sample"); ok {
+		t.Fatal("canary config is not deleted")
+	}
+}
+
+func TestServiceAccounts(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	do := func(method string, path string, body string) int {
+		req, _ := http.NewRequest(method, s.URL()+path, strings.NewReader(body))
+		req.Header.Set("X-SPINNAKER-USER", "controller")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := do(http.MethodPost, "/serviceAccounts", `{"name": "deployer", "memberOf": ["a"]}`); status != http.StatusOK {
+		t.Fatalf("failed to save service account: %d", status)
+	}
+	do(http.MethodPost, "/serviceAccounts", `{"name": "deployer", "memberOf": ["b"]}`)
+	serviceAccount, ok := s.ServiceAccount("deployer")
+	if !ok || serviceAccount["lastModifiedBy"] != "controller" || serviceAccount["memberOf"].([]interface{})[0] != "b" {
+		t.Fatalf("service account is not saved: %v", serviceAccount)
+	}
+
+	if status := do(http.MethodDelete, "/serviceAccounts/deployer", ""); status != http.StatusOK {
+		t.Fatalf("failed to delete service account: %d", status)
+	}
+	if _, ok := s.ServiceAccount("deployer"); ok {
+		t.Fatal("service account is not deleted")
+	}
+	if status := do(http.MethodDelete, "/serviceAccounts/deployer", ""); status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", status)
+	}
+}
+
+func TestFail(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddApplication("sample")
+	gateClient := newClient(t, s).Gate(context.Background())
+	get := func() *http.Response {
+		_, resp, _ := gateClient.ApplicationControllerApi.GetApplicationUsingGET(gateClient.Context, "sample", &gate.ApplicationControllerApiGetApplicationUsingGETOpts{})
+		return resp
+	}
+
+	// The transport retries idempotent requests, so that one injected failure is invisible to the caller.
+	s.Fail(http.MethodGet, "/applications/sample", http.StatusServiceUnavailable, 1)
+	if resp := get(); resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected retry to succeed, got %v", resp)
+	}
+	if count := s.CountRequests(http.MethodGet, "/applications/sample"); count != 2 {
+		t.Fatalf("expected 2 requests, got %d", count)
+	}
+
+	s.Fail(http.MethodGet, "/applications/sample", http.StatusForbidden, 0)
+	for i := 0; i < 2; i++ {
+		if resp := get(); resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected persistent failure, got %v", resp)
+		}
+	}
+	s.ClearFailures()
+	if resp := get(); resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected failures to be cleared, got %v", resp)
+	}
+}
@@ -0,0 +1,173 @@
+// Package gateway provides the client of Spinnaker Gate shared by all reconcilers.
+//
+// It wraps both roer and the spin Gate API client on top of one HTTP transport, so that retries, timeouts, rate limiting,
+// circuit breaking and tracing are configured in one place.
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"spinnaker-dcd-controller/internal/tracing"
+	"strings"
+	"time"
+
+	"github.com/spinnaker/roer/spinnaker"
+	"github.com/spinnaker/spin/cmd/gateclient"
+	gate "github.com/spinnaker/spin/gateapi"
+	"golang.org/x/xerrors"
+)
+
+const userAgent = "spinnaker-dcd-controller"
+
+// Client is the client of Spinnaker Gate
+type Client interface {
+	// Roer returns the roer client bound to ctx, which submits tasks and templates
+	Roer(ctx context.Context) spinnaker.Client
+	// Gate returns the spin Gate API client bound to ctx
+	Gate(ctx context.Context) gateclient.GatewayClient
+	// Do sends req, whose URL is a path of Gate, for the APIs that neither roer nor spin covers
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Options configures the behavior of requests to Gate
+type Options struct {
+	// Timeout bounds each attempt of a request
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// BaseBackoff is the ceiling of the wait before the first retry, which doubles for each retry
+	BaseBackoff time.Duration
+	// MaxBackoff caps the wait before a retry
+	MaxBackoff time.Duration
+	// FailureThreshold is the number of consecutive failures that opens the circuit breaker, or 0 to disable it
+	FailureThreshold int
+	// CooldownPeriod is how long the circuit breaker stays open
+	CooldownPeriod time.Duration
+	// QPS is the rate of requests to Gate shared by all reconcilers, or 0 to disable rate limiting
+	QPS float64
+	// Burst is the number of requests that can be sent at once above QPS
+	Burst int
+	// MaxThrottleWait is how long a low priority request waits for the rate limiter before it is given up
+	MaxThrottleWait time.Duration
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// TLSConfig configures TLS to Gate, e.g. a client certificate for x509 authentication, or nil for the default
+	TLSConfig *tls.Config
+	// Stop cancels all requests in flight when closed, e.g. on shutdown of the manager
+	Stop <-chan struct{}
+}
+
+// DefaultOptions returns the options used when none are given
+func DefaultOptions() Options {
+	return Options{
+		Timeout:          30 * time.Second,
+		MaxRetries:       3,
+		BaseBackoff:      200 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		FailureThreshold: 5,
+		CooldownPeriod:   30 * time.Second,
+		QPS:              10,
+		Burst:            20,
+		MaxThrottleWait:  10 * time.Second,
+	}
+}
+
+type client struct {
+	endpoint   string
+	httpClient *http.Client
+	gate       gateclient.GatewayClient
+}
+
+// New returns the client of Gate at endpoint
+func New(endpoint string, options Options) (Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid Gate endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, xerrors.Errorf("invalid Gate endpoint %q: must be an absolute http(s) URL", endpoint)
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	httpClient := NewHTTPClient("Gate", options)
+	return &client{
+		endpoint:   endpoint,
+		httpClient: httpClient,
+		gate: gateclient.GatewayClient{
+			APIClient: gate.NewAPIClient(&gate.Configuration{
+				BasePath:      endpoint,
+				DefaultHeader: map[string]string{},
+				UserAgent:     userAgent,
+				HTTPClient:    httpClient,
+			}),
+			Context: context.Background(),
+		},
+	}, nil
+}
+
+// NewHTTPClient returns an HTTP client with the retries, timeouts, rate limiting, circuit breaking and tracing of
+// options for service, e.g. Front50 for the APIs that Gate does not expose. Each client has a rate limiter and a circuit
+// breaker of its own, so that one service failing does not stop the requests to another.
+func NewHTTPClient(service string, options Options) *http.Client {
+	var base http.RoundTripper = http.DefaultTransport
+	if options.TLSConfig != nil {
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+		defaultTransport.TLSClientConfig = options.TLSConfig
+		base = defaultTransport
+	}
+	return &http.Client{
+		Transport: tracing.NewTransport(newTransport(&headerTransport{base: base, headers: options.Headers}, options), service),
+	}
+}
+
+// Roer returns a roer client whose requests carry ctx, since roer builds requests without context.
+func (c *client) Roer(ctx context.Context) spinnaker.Client {
+	return spinnaker.New(c.endpoint, &http.Client{
+		Transport: &contextTransport{base: c.httpClient.Transport, ctx: ctx},
+	})
+}
+
+func (c *client) Gate(ctx context.Context) gateclient.GatewayClient {
+	gateClient := c.gate
+	gateClient.Context = ctx
+	return gateClient
+}
+
+func (c *client) Do(req *http.Request) (*http.Response, error) {
+	u, err := url.Parse(c.endpoint + req.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL = u
+	req.Host = u.Host
+	req.Header.Set("User-Agent", userAgent)
+	return c.httpClient.Do(req)
+}
+
+type contextTransport struct {
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	return t.base.RoundTrip(req)
+}
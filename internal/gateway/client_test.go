@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewRejectsInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "gate.example.com", "ftp://gate.example.com", "http://"} {
+		if _, err := New(endpoint, DefaultOptions()); err == nil {
+			t.Errorf("expected %q to be rejected", endpoint)
+		}
+	}
+}
+
+func TestDoSendsToGate(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	options := DefaultOptions()
+	options.Headers = map[string]string{"Authorization": "Bearer token"}
+	client, err := New(server.URL+"/", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/notifications/application/sample?expand=true", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.URL.Path != "/notifications/application/sample" || got.URL.RawQuery != "expand=true" {
+		t.Errorf("unexpected URL %s", got.URL)
+	}
+	if got.Header.Get("Authorization") != "Bearer token" || got.Header.Get("User-Agent") != userAgent {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	if req.URL.Host != "" {
+		t.Error("expected the request of the caller to be left alone")
+	}
+}
@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrThrottled is returned to low priority requests that could not get a token in time, so that the reconciliation is
+// requeued and its worker is freed for deletions and updates waiting behind it.
+var ErrThrottled = &RetryableError{Err: xerrors.New("request to Gate is throttled")}
+
+// Priority orders requests waiting for the rate limiter
+type Priority int
+
+const (
+	// PriorityHigh is for deletions and updates of resources already in Spinnaker, and is the default
+	PriorityHigh Priority = iota
+	// PriorityLow is for creations, which give way to PriorityHigh requests
+	PriorityLow
+)
+
+type priorityKey struct{}
+
+// WithPriority returns ctx whose requests to Gate wait for the rate limiter with priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityHigh
+}
+
+// limiter is a token bucket shared by all requests to one Gate. Low priority requests never take a token while a high
+// priority request is waiting for one, and give up after maxWait.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    float64
+	maxWait  time.Duration
+	now      func() time.Time
+	tokens   float64
+	last     time.Time
+	waiting  int
+}
+
+func newLimiter(qps float64, burst int, maxWait time.Duration) *limiter {
+	if qps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{
+		interval: time.Duration(float64(time.Second) / qps),
+		burst:    float64(burst),
+		maxWait:  maxWait,
+		now:      time.Now,
+		tokens:   float64(burst),
+	}
+}
+
+// wait blocks until a token is taken, ctx is done or stop is closed.
+func (l *limiter) wait(ctx context.Context, stop <-chan struct{}) error {
+	if l == nil {
+		return nil
+	}
+	priority := priorityFrom(ctx)
+	var deadline <-chan time.Time
+	if priority == PriorityLow && l.maxWait > 0 {
+		timer := time.NewTimer(l.maxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	waiting := false
+	defer func() {
+		if waiting {
+			l.mu.Lock()
+			l.waiting--
+			l.mu.Unlock()
+		}
+	}()
+	for {
+		ok, delay := l.take(priority, &waiting)
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryableError{Err: ctx.Err()}
+		case <-stop:
+			timer.Stop()
+			return &RetryableError{Err: context.Canceled}
+		case <-deadline:
+			timer.Stop()
+			return ErrThrottled
+		case <-timer.C:
+		}
+	}
+}
+
+// take takes a token, or returns how long to wait before trying again.
+func (l *limiter) take(priority Priority, waiting *bool) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 && (priority == PriorityHigh || l.waiting == 0) {
+		l.tokens--
+		if *waiting {
+			l.waiting--
+			*waiting = false
+		}
+		return true, 0
+	}
+	if priority == PriorityHigh && !*waiting {
+		l.waiting++
+		*waiting = true
+	}
+	if l.tokens >= 1 {
+		// A token is left for the high priority request that is waiting for it.
+		return false, l.interval
+	}
+	return false, time.Duration((1 - l.tokens) * float64(l.interval))
+}
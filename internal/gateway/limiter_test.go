@@ -0,0 +1,90 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterRefillsTokens(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(10, 2, 0)
+	l.now = func() time.Time { return now }
+
+	waiting := false
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.take(PriorityHigh, &waiting); !ok {
+			t.Fatalf("expected the burst to be taken at once, failed at %d", i)
+		}
+	}
+	if ok, delay := l.take(PriorityHigh, &waiting); ok || delay != 100*time.Millisecond {
+		t.Fatalf("expected to wait for a token for 100ms, got %v, %v", ok, delay)
+	}
+	now = now.Add(50 * time.Millisecond)
+	if ok, delay := l.take(PriorityHigh, &waiting); ok || delay != 50*time.Millisecond {
+		t.Fatalf("expected to wait for the rest of the token for 50ms, got %v, %v", ok, delay)
+	}
+	now = now.Add(50 * time.Millisecond)
+	if ok, _ := l.take(PriorityHigh, &waiting); !ok {
+		t.Fatal("expected a refilled token to be taken")
+	}
+	if waiting || l.waiting != 0 {
+		t.Fatalf("expected the request to stop waiting, got %v, %d", waiting, l.waiting)
+	}
+}
+
+func TestLimiterOrdersByPriority(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(10, 1, 0)
+	l.now = func() time.Time { return now }
+	l.tokens = 0
+
+	high, low := false, false
+	if ok, _ := l.take(PriorityHigh, &high); ok {
+		t.Fatal("expected no token to be left")
+	}
+	now = now.Add(100 * time.Millisecond)
+	if ok, delay := l.take(PriorityLow, &low); ok || delay != 100*time.Millisecond {
+		t.Fatalf("expected the token to be left for the high priority request, got %v, %v", ok, delay)
+	}
+	if ok, _ := l.take(PriorityHigh, &high); !ok {
+		t.Fatal("expected the high priority request to take the token")
+	}
+	now = now.Add(100 * time.Millisecond)
+	if ok, _ := l.take(PriorityLow, &low); !ok {
+		t.Fatal("expected the low priority request to take a token once no high priority request is waiting")
+	}
+}
+
+func TestLimiterMaxThrottleWait(t *testing.T) {
+	l := newLimiter(0.001, 1, 20*time.Millisecond)
+	if err := l.wait(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.wait(WithPriority(context.Background(), PriorityLow), nil); err != ErrThrottled {
+		t.Fatalf("expected the low priority request to be throttled, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := l.wait(ctx, nil)
+	if _, ok := err.(*RetryableError); !ok || ctx.Err() == nil {
+		t.Fatalf("expected the high priority request to wait beyond MaxThrottleWait until its deadline, got %v", err)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if err := l.wait(context.Background(), stop); !IsRetryable(err) {
+		t.Fatalf("expected the wait to end on stop, got %v", err)
+	}
+	if l.waiting != 0 {
+		t.Fatalf("expected no request to be left waiting, got %d", l.waiting)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	if l := newLimiter(0, 1, 0); l != nil || l.wait(context.Background(), nil) != nil {
+		t.Fatal("expected no rate limiting without QPS")
+	}
+}
@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrCircuitOpen is returned without calling Gate while Gate keeps failing.
+var ErrCircuitOpen = &RetryableError{Err: xerrors.New("circuit breaker is open")}
+
+// RetryableError is returned when a request did not reach a result, such as when it was cancelled by a deadline or
+// shutdown, so that the operation can be retried as it is.
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable returns true when err, or an error wrapped in it, is a RetryableError.
+func IsRetryable(err error) bool {
+	for err != nil {
+		if _, ok := err.(*RetryableError); ok {
+			return true
+		}
+		if causer, ok := err.(interface{ Cause() error }); ok {
+			err = causer.Cause()
+			continue
+		}
+		err = xerrors.Unwrap(err)
+	}
+	return false
+}
+
+// transport retries Gate requests with jittered backoff, bounds each attempt with a timeout, limits the rate of
+// attempts, and stops calling Gate for a while after consecutive failures.
+type transport struct {
+	base    http.RoundTripper
+	options Options
+	limiter *limiter
+	breaker *circuitBreaker
+	random  func(int64) int64
+}
+
+func newTransport(base http.RoundTripper, options Options) *transport {
+	return &transport{
+		base:    base,
+		options: options,
+		limiter: newLimiter(options.QPS, options.Burst, options.MaxThrottleWait),
+		breaker: &circuitBreaker{
+			threshold: options.FailureThreshold,
+			cooldown:  options.CooldownPeriod,
+			now:       time.Now,
+		},
+		random: rand.Int63n,
+	}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.canceled(ctx); err != nil {
+			return nil, err
+		}
+		if err := t.limiter.wait(ctx, t.options.Stop); err != nil {
+			return nil, err
+		}
+		if err := t.breaker.allow(); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.attempt(ctx, req, body)
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			t.breaker.succeed()
+			return resp, nil
+		}
+		if cancelErr := t.canceled(ctx); cancelErr != nil {
+			// Cancellation is not a failure of Gate, so that it does not count toward the circuit breaker.
+			t.breaker.cancel()
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+			return nil, cancelErr
+		}
+		t.breaker.fail()
+		if attempt >= t.options.MaxRetries || !isRetryable(req.Method, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if resp != nil {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &RetryableError{Err: ctx.Err()}
+		case <-t.options.Stop:
+			timer.Stop()
+			return nil, &RetryableError{Err: context.Canceled}
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *transport) attempt(ctx context.Context, req *http.Request, body []byte) (*http.Response, error) {
+	attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+	if t.options.Timeout > 0 {
+		attemptCtx, cancel = context.WithTimeout(ctx, t.options.Timeout)
+	}
+	if t.options.Stop != nil {
+		stopCtx, stop := context.WithCancel(attemptCtx)
+		go func() {
+			select {
+			case <-t.options.Stop:
+				stop()
+			case <-stopCtx.Done():
+			}
+		}()
+		attemptCtx, cancel = stopCtx, cancelBoth(stop, cancel)
+	}
+	attemptReq := req.Clone(attemptCtx)
+	if body != nil {
+		attemptReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		attemptReq.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := t.base.RoundTrip(attemptReq)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout also bounds reading the body, so that it is released when the body is closed.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *transport) canceled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &RetryableError{Err: err}
+	}
+	select {
+	case <-t.options.Stop:
+		return &RetryableError{Err: context.Canceled}
+	default:
+		return nil
+	}
+}
+
+func cancelBoth(first context.CancelFunc, second context.CancelFunc) context.CancelFunc {
+	return func() {
+		first()
+		second()
+	}
+}
+
+// backoff returns the wait before the next attempt with full jitter, unless Gate asks for a specific wait.
+func (t *transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait := time.Duration(seconds) * time.Second
+			if wait > t.options.MaxBackoff {
+				wait = t.options.MaxBackoff
+			}
+			return wait
+		}
+	}
+	ceiling := t.options.BaseBackoff << uint(attempt)
+	if ceiling <= 0 || ceiling > t.options.MaxBackoff {
+		ceiling = t.options.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(t.random(int64(ceiling)) + 1)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return ioutil.ReadAll(req.Body)
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// isRetryable retries requests that Gate has not processed for any method, and requests that may have been processed
+// only when they are idempotent, so that a task is never submitted twice.
+func isRetryable(method string, resp *http.Response, err error) bool {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return err != nil || (resp != nil && isRetryableStatus(resp.StatusCode))
+	}
+	return false
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// circuitBreaker opens after threshold consecutive failures, and lets one request through after cooldown to see
+// whether Gate has recovered.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func (b *circuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+func (b *circuitBreaker) succeed() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) fail() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestTransport returns a transport without rate limiting, whose backoff records its ceilings and does not wait.
+func newTestTransport(options Options) (*transport, *[]time.Duration) {
+	t := newTransport(http.DefaultTransport, options)
+	var ceilings []time.Duration
+	t.random = func(n int64) int64 {
+		ceilings = append(ceilings, time.Duration(n))
+		return 0
+	}
+	return t, &ceilings
+}
+
+func testOptions() Options {
+	options := DefaultOptions()
+	options.BaseBackoff = time.Millisecond
+	options.MaxBackoff = 4 * time.Millisecond
+	options.QPS = 0
+	options.FailureThreshold = 0
+	return options
+}
+
+type countingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+	bodies   []string
+}
+
+// newCountingServer responds with statuses in order, and with the last one after them.
+func newCountingServer(statuses ...int) *countingServer {
+	s := &countingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		s.mu.Lock()
+		status := statuses[len(statuses)-1]
+		if s.requests < len(statuses) {
+			status = statuses[s.requests]
+		}
+		s.requests++
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+func (s *countingServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func TestTransportRetriesWithBackoff(t *testing.T) {
+	server := newCountingServer(http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	defer server.Close()
+	transport, ceilings := newTestTransport(testOptions())
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("payload"))
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || server.count() != 3 {
+		t.Fatalf("expected success after 3 attempts, got %d after %d", resp.StatusCode, server.count())
+	}
+	for i, body := range server.bodies {
+		if body != "payload" {
+			t.Errorf("expected the body to be sent again on attempt %d, got %q", i, body)
+		}
+	}
+	if expected := []time.Duration{time.Millisecond, 2 * time.Millisecond}; !equalDurations(*ceilings, expected) {
+		t.Errorf("expected backoff ceilings %v, got %v", expected, *ceilings)
+	}
+}
+
+func TestTransportGivesUpAfterMaxRetries(t *testing.T) {
+	server := newCountingServer(http.StatusInternalServerError)
+	defer server.Close()
+	options := testOptions()
+	options.MaxRetries = 4
+	transport, ceilings := newTestTransport(options)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || server.count() != 5 {
+		t.Fatalf("expected the last failure after 5 attempts, got %d after %d", resp.StatusCode, server.count())
+	}
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	if !equalDurations(*ceilings, expected) {
+		t.Errorf("expected backoff ceilings capped by MaxBackoff %v, got %v", expected, *ceilings)
+	}
+}
+
+func TestTransportRetriesPostOnlyWhenNotProcessed(t *testing.T) {
+	for status, attempts := range map[int]int{
+		// A task may have been submitted, so that it is never submitted twice.
+		http.StatusInternalServerError: 1,
+		http.StatusServiceUnavailable:  2,
+		http.StatusTooManyRequests:     2,
+	} {
+		server := newCountingServer(status, http.StatusOK)
+		transport, _ := newTestTransport(testOptions())
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("task"))
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if server.count() != attempts {
+			t.Errorf("expected %d attempts of POST after %d, got %d", attempts, status, server.count())
+		}
+		server.Close()
+	}
+}
+
+func TestTransportBackoffHonorsRetryAfter(t *testing.T) {
+	options := testOptions()
+	options.MaxBackoff = 5 * time.Second
+	transport, ceilings := newTestTransport(options)
+
+	for retryAfter, expected := range map[string]time.Duration{
+		"2":  2 * time.Second,
+		"0":  0,
+		"60": 5 * time.Second,
+	} {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{retryAfter}}}
+		if wait := transport.backoff(0, resp); wait != expected {
+			t.Errorf("expected Retry-After %s to wait %v, got %v", retryAfter, expected, wait)
+		}
+	}
+	if len(*ceilings) != 0 {
+		t.Errorf("expected no jitter with Retry-After, got %v", *ceilings)
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"Wed, 21 Oct 2015 07:28:00 GMT"}}}
+	if wait := transport.backoff(1, resp); wait != 1 {
+		t.Errorf("expected an unsupported Retry-After to fall back to jitter, got %v", wait)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := &circuitBreaker{
+		threshold: 2,
+		cooldown:  10 * time.Second,
+		now:       func() time.Time { return now },
+	}
+
+	breaker.fail()
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected the breaker to be closed below the threshold, got %v", err)
+	}
+	breaker.fail()
+	if err := breaker.allow(); err != ErrCircuitOpen {
+		t.Fatalf("expected the breaker to open, got %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %v", err)
+	}
+	if err := breaker.allow(); err != ErrCircuitOpen {
+		t.Fatalf("expected one probe at a time, got %v", err)
+	}
+	breaker.fail()
+	if err := breaker.allow(); err != ErrCircuitOpen {
+		t.Fatalf("expected a failed probe to open the breaker again, got %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %v", err)
+	}
+	breaker.cancel()
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected a canceled probe to let another one through, got %v", err)
+	}
+	breaker.succeed()
+	breaker.fail()
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("expected a successful probe to close the breaker, got %v", err)
+	}
+}
+
+func TestTransportStopsCallingWhileCircuitIsOpen(t *testing.T) {
+	server := newCountingServer(http.StatusInternalServerError)
+	defer server.Close()
+	options := testOptions()
+	options.MaxRetries = 0
+	options.FailureThreshold = 1
+	transport, _ := newTestTransport(options)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	_, err = transport.RoundTrip(req)
+	if err != ErrCircuitOpen || !IsRetryable(err) {
+		t.Fatalf("expected the circuit to be open, got %v", err)
+	}
+	if server.count() != 1 {
+		t.Fatalf("expected Gate not to be called while the circuit is open, got %d requests", server.count())
+	}
+}
+
+func equalDurations(a []time.Duration, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,380 @@
+// Package sharding spreads resources across replicas of the controller.
+//
+// Resources are assigned to a fixed number of shards by a consistent hash of a key, i.e. their Spinnaker application
+// name, and each shard is owned by the replica holding its Lease, so that resources of one application are handled by
+// one writer in order while the replicas share the load. Each replica announces itself with a member Lease, and holds
+// at most its fair share of the shards.
+package sharding
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationV1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationV1Client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+)
+
+const (
+	shardLabel  = "spinnaker.kaidotdev.github.io/shard-of"
+	memberLabel = "spinnaker.kaidotdev.github.io/shard-member-of"
+)
+
+// Options configures sharding
+type Options struct {
+	// Shards is the number of shards
+	Shards int
+	// Namespace is the namespace of the Leases
+	Namespace string
+	// Name prefixes the names of the Leases, which must differ between deployments sharing a namespace
+	Name string
+	// Identity identifies this replica, which must be unique among the replicas
+	Identity string
+	// LeaseDuration is how long other replicas wait before taking over a Lease that is no longer renewed
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the owner of a shard keeps it without renewing its Lease, which must be shorter than
+	// LeaseDuration
+	RenewDeadline time.Duration
+	// RetryPeriod is how often Leases are acquired, renewed and rebalanced, which must be shorter than RenewDeadline
+	RetryPeriod time.Duration
+}
+
+// Shards holds the shards owned by this replica. A nil Shards owns everything, i.e. sharding is disabled.
+type Shards struct {
+	options Options
+	client  coordinationV1Client.LeasesGetter
+	log     logr.Logger
+	now     func() time.Time
+
+	mu        sync.RWMutex
+	owned     map[int]time.Time
+	callbacks []func(shard int)
+
+	// observations are the records of Leases of other replicas and when they were seen to change, so that expiry
+	// does not depend on the clocks of other replicas.
+	observations map[string]observation
+}
+
+type observation struct {
+	record string
+	at     time.Time
+}
+
+// New returns Shards, which start acquiring Leases once it is started
+func New(client coordinationV1Client.LeasesGetter, options Options, log logr.Logger) *Shards {
+	return &Shards{
+		options:      options,
+		client:       client,
+		log:          log,
+		now:          time.Now,
+		owned:        map[int]time.Time{},
+		observations: map[string]observation{},
+	}
+}
+
+// ShardOf returns the shard of key
+func (s *Shards) ShardOf(key string) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return jumpHash(h.Sum64(), s.options.Shards)
+}
+
+// Owns returns true when this replica owns the shard of key
+func (s *Shards) Owns(key string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	renewed, ok := s.owned[s.ShardOf(key)]
+	return ok && s.now().Sub(renewed) < s.options.RenewDeadline
+}
+
+// OnAcquire registers f, which is called with a shard whenever this replica acquires it, e.g. to reconcile the
+// resources of the shard whose events were dropped while another replica owned it.
+func (s *Shards) OnAcquire(f func(shard int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.callbacks = append(s.callbacks, f)
+}
+
+// NeedLeaderElection returns false, since every replica owns shards regardless of leader election
+func (s *Shards) NeedLeaderElection() bool {
+	return false
+}
+
+// Start acquires, renews and rebalances shards until stop is closed, and releases them on return.
+func (s *Shards) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(s.options.RetryPeriod)
+	defer ticker.Stop()
+	for {
+		if err := s.sync(); err != nil {
+			s.log.Error(err, "unable to sync shards")
+		}
+		select {
+		case <-stop:
+			s.stop()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync renews the Leases of the owned shards, acquires free shards while this replica owns less than its fair share,
+// and gives up one shard at a time while it owns more, so that the shards spread over the replicas without all of
+// them moving at once.
+func (s *Shards) sync() error {
+	if err := s.renewMembership(); err != nil {
+		return err
+	}
+	members, err := s.countMembers()
+	if err != nil {
+		return err
+	}
+	limit := (s.options.Shards + members - 1) / members
+
+	list, err := s.client.Leases(s.options.Namespace).List(metaV1.ListOptions{LabelSelector: shardLabel + "=" + s.options.Name})
+	if err != nil {
+		return err
+	}
+	leases := map[string]*coordinationV1.Lease{}
+	for i := range list.Items {
+		leases[list.Items[i].Name] = &list.Items[i]
+	}
+
+	released := false
+	for shard := 0; shard < s.options.Shards; shard++ {
+		lease := leases[s.shardName(shard)]
+		switch {
+		case lease != nil && holder(lease) == s.options.Identity:
+			if s.countOwned() > limit && !released {
+				s.log.Info("release shard", "shard", shard, "limit", limit)
+				s.release(lease, shard)
+				released = true
+				continue
+			}
+			s.renew(lease, shard)
+		case lease == nil || holder(lease) == "" || s.expired(lease):
+			s.lose(shard)
+			if s.countOwned() < limit {
+				s.acquire(lease, shard)
+			}
+		default:
+			s.lose(shard)
+		}
+	}
+	return nil
+}
+
+func (s *Shards) renew(lease *coordinationV1.Lease, shard int) {
+	now := metaV1.NewMicroTime(s.now())
+	lease.Spec.RenewTime = &now
+	if _, err := s.client.Leases(s.options.Namespace).Update(lease); err != nil {
+		// The shard is kept until RenewDeadline, so that a transient error does not move it.
+		s.log.Error(err, "unable to renew shard", "shard", shard)
+		return
+	}
+	s.mu.Lock()
+	s.owned[shard] = now.Time
+	s.mu.Unlock()
+}
+
+func (s *Shards) acquire(lease *coordinationV1.Lease, shard int) {
+	now := metaV1.NewMicroTime(s.now())
+	spec := coordinationV1.LeaseSpec{
+		HolderIdentity:       &s.options.Identity,
+		LeaseDurationSeconds: s.durationSeconds(),
+		AcquireTime:          &now,
+		RenewTime:            &now,
+	}
+	var err error
+	if lease == nil {
+		_, err = s.client.Leases(s.options.Namespace).Create(&coordinationV1.Lease{
+			ObjectMeta: metaV1.ObjectMeta{
+				Namespace: s.options.Namespace,
+				Name:      s.shardName(shard),
+				Labels:    map[string]string{shardLabel: s.options.Name},
+			},
+			Spec: spec,
+		})
+	} else {
+		// The resource version makes the update fail when another replica has acquired the shard in the meantime.
+		lease.Spec = spec
+		_, err = s.client.Leases(s.options.Namespace).Update(lease)
+	}
+	if err != nil {
+		if !errors.IsConflict(err) && !errors.IsAlreadyExists(err) {
+			s.log.Error(err, "unable to acquire shard", "shard", shard)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	s.owned[shard] = now.Time
+	callbacks := append([]func(int){}, s.callbacks...)
+	s.mu.Unlock()
+	s.log.Info("acquire shard", "shard", shard)
+	for _, f := range callbacks {
+		f(shard)
+	}
+}
+
+// release gives up shard, and lets other replicas acquire it without waiting for the Lease to expire.
+func (s *Shards) release(lease *coordinationV1.Lease, shard int) {
+	s.lose(shard)
+	empty := ""
+	lease.Spec.HolderIdentity = &empty
+	if _, err := s.client.Leases(s.options.Namespace).Update(lease); err != nil && !errors.IsConflict(err) {
+		s.log.Error(err, "unable to release shard", "shard", shard)
+	}
+}
+
+func (s *Shards) lose(shard int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.owned[shard]; ok {
+		s.log.Info("lose shard", "shard", shard)
+		delete(s.owned, shard)
+	}
+}
+
+func (s *Shards) countOwned() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.owned)
+}
+
+// stop releases all shards and the membership, so that other replicas take over without waiting for them to expire.
+func (s *Shards) stop() {
+	leases := s.client.Leases(s.options.Namespace)
+	for shard := 0; shard < s.options.Shards; shard++ {
+		s.mu.RLock()
+		_, ok := s.owned[shard]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		lease, err := leases.Get(s.shardName(shard), metaV1.GetOptions{})
+		if err != nil {
+			s.lose(shard)
+			continue
+		}
+		if holder(lease) == s.options.Identity {
+			s.release(lease, shard)
+		}
+	}
+	if err := leases.Delete(s.memberName(), &metaV1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		s.log.Error(err, "unable to delete membership")
+	}
+}
+
+func (s *Shards) renewMembership() error {
+	leases := s.client.Leases(s.options.Namespace)
+	now := metaV1.NewMicroTime(s.now())
+	lease, err := leases.Get(s.memberName(), metaV1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = leases.Create(&coordinationV1.Lease{
+			ObjectMeta: metaV1.ObjectMeta{
+				Namespace: s.options.Namespace,
+				Name:      s.memberName(),
+				Labels:    map[string]string{memberLabel: s.options.Name},
+			},
+			Spec: coordinationV1.LeaseSpec{
+				HolderIdentity:       &s.options.Identity,
+				LeaseDurationSeconds: s.durationSeconds(),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(lease)
+	return err
+}
+
+// countMembers returns the number of replicas that have renewed their membership in time including this one, and
+// deletes the memberships of replicas that have long gone.
+func (s *Shards) countMembers() (int, error) {
+	leases := s.client.Leases(s.options.Namespace)
+	list, err := leases.List(metaV1.ListOptions{LabelSelector: memberLabel + "=" + s.options.Name})
+	if err != nil {
+		return 0, err
+	}
+	members := 1
+	for i := range list.Items {
+		lease := &list.Items[i]
+		if lease.Name == s.memberName() {
+			continue
+		}
+		if !s.expired(lease) {
+			members++
+		} else if s.now().Sub(s.observations[lease.Name].at) > 10*s.options.LeaseDuration {
+			if err := leases.Delete(lease.Name, &metaV1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				s.log.Error(err, "unable to delete stale membership", "lease", lease.Name)
+			}
+		}
+	}
+	return members, nil
+}
+
+// expired returns true when lease has not been renewed for its duration since this replica observed it.
+func (s *Shards) expired(lease *coordinationV1.Lease) bool {
+	record := fmt.Sprintf("%s/%v", holder(lease), lease.Spec.RenewTime)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.observations[lease.Name]
+	if !ok || o.record != record {
+		o = observation{record: record, at: s.now()}
+		s.observations[lease.Name] = o
+	}
+	duration := s.options.LeaseDuration
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return s.now().Sub(o.at) > duration
+}
+
+func (s *Shards) durationSeconds() *int32 {
+	seconds := int32(s.options.LeaseDuration / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &seconds
+}
+
+func (s *Shards) shardName(shard int) string {
+	return fmt.Sprintf("%s-shard-%d", s.options.Name, shard)
+}
+
+func (s *Shards) memberName() string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s.options.Identity))
+	return fmt.Sprintf("%s-member-%x", s.options.Name, h.Sum32())
+}
+
+func holder(lease *coordinationV1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// jumpHash is the jump consistent hash of Lamping and Veach, which moves only 1/n of the keys when the number of
+// buckets grows to n.
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,94 @@
+package sharding
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestJumpHash(t *testing.T) {
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := uint64(i) * 0x9e3779b97f4a7c15
+		before, after := jumpHash(key, 8), jumpHash(key, 9)
+		if before < 0 || before >= 8 || after < 0 || after >= 9 {
+			t.Fatalf("out of range: %d, %d", before, after)
+		}
+		if before != after {
+			if after != 8 {
+				t.Fatalf("key moved between existing buckets: %d -> %d", before, after)
+			}
+			moved++
+		}
+	}
+	// About 1/9 of the keys move to the new bucket.
+	if moved < 50 || moved > 180 {
+		t.Fatalf("unexpected number of moved keys: %d", moved)
+	}
+}
+
+func TestShardsSpreadOverReplicas(t *testing.T) {
+	client := fake.NewSimpleClientset().CoordinationV1()
+	newShards := func(identity string) *Shards {
+		return New(client, Options{
+			Shards:        4,
+			Namespace:     "default",
+			Name:          "test",
+			Identity:      identity,
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   20 * time.Millisecond,
+		}, zap.New(zap.UseDevMode(true), zap.WriteTo(ioutil.Discard)))
+	}
+	owned := func(s *Shards) int {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.owned)
+	}
+	eventually := func(condition func() bool, message string) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for !condition() {
+			if time.Now().After(deadline) {
+				t.Fatal(message)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	first, second := newShards("first"), newShards("second")
+	firstStop, secondStop := make(chan struct{}), make(chan struct{})
+	firstDone := make(chan error)
+	go func() { firstDone <- first.Start(firstStop) }()
+	eventually(func() bool { return owned(first) == 4 }, "first replica did not own all shards")
+
+	acquired := make(chan int, 4)
+	second.OnAcquire(func(shard int) { acquired <- shard })
+	go func() { _ = second.Start(secondStop) }()
+	defer close(secondStop)
+	eventually(func() bool { return owned(first) == 2 && owned(second) == 2 }, "shards did not spread over replicas")
+	eventually(func() bool { return len(acquired) == 2 }, "OnAcquire was not called for the acquired shards")
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("application-%d", i)
+		if first.Owns(key) == second.Owns(key) {
+			t.Fatalf("%s must be owned by exactly one replica", key)
+		}
+	}
+
+	close(firstStop)
+	if err := <-firstDone; err != nil {
+		t.Fatal(err)
+	}
+	eventually(func() bool { return owned(second) == 4 }, "second replica did not take over released shards")
+}
+
+func TestNilShardsOwnEverything(t *testing.T) {
+	var s *Shards
+	if !s.Owns("sample") {
+		t.Fatal("nil Shards must own everything")
+	}
+}
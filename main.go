@@ -1,22 +1,37 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
-	"net/http"
+	"io"
+	"io/ioutil"
 	"os"
 	"spinnaker-dcd-controller/controllers"
+	"spinnaker-dcd-controller/internal/audit"
+	"spinnaker-dcd-controller/internal/config"
+	"spinnaker-dcd-controller/internal/gateway"
+	"spinnaker-dcd-controller/internal/sharding"
+	"spinnaker-dcd-controller/internal/tracing"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
 
 	applicationV1 "spinnaker-dcd-controller/api/v1"
 
-	"github.com/spinnaker/roer/spinnaker"
-	"github.com/spinnaker/spin/cmd/gateclient"
-	"github.com/spinnaker/spin/cmd/output"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
+	controllerCache "sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -32,87 +47,513 @@ func init() {
 }
 
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	var spinnakerEndpoint string
-	var verbose bool
-	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
-		"Enable leader election for controller manager.")
-	flag.StringVar(&spinnakerEndpoint, "spinnaker-endpoint", "http://spin-gate.spinnaker.svc.cluster.local:8084", "The endpoint of Spinnaker Gate.")
-	flag.BoolVar(&verbose, "verbose", false, "Make the operation more talkative.")
-	flag.Parse()
+	args := os.Args[1:]
+	cfg, configFile, err := loadConfiguration(args)
+	if err != nil {
+		setupLog.Error(err, "unable to load configuration")
+		os.Exit(1)
+	}
 
 	ctrl.SetLogger(zap.Logger(true))
 
+	syncPeriod := cfg.SyncPeriod.Duration
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "spinnaker-dcd-controller",
-		Port:               9443,
+		Scheme:                  scheme,
+		MetricsBindAddress:      cfg.MetricsBindAddress,
+		LeaderElection:          cfg.LeaderElection.LeaderElect,
+		LeaderElectionID:        cfg.LeaderElection.ResourceName,
+		LeaderElectionNamespace: cfg.LeaderElection.ResourceNamespace,
+		Port:                    cfg.Port,
+		SyncPeriod:              &syncPeriod,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if verbose {
-		logrus.SetLevel(logrus.DebugLevel)
+	pause := &controllers.Pause{Paused: cfg.Pause.Paused}
+	if cfg.Pause.ConfigMap != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(cfg.Pause.ConfigMap)
+		if err != nil || namespace == "" {
+			setupLog.Error(xerrors.Errorf("invalid pause ConfigMap %q: expected namespace/name", cfg.Pause.ConfigMap), "unable to watch pause ConfigMap")
+			os.Exit(1)
+		}
+		// The manager cache is cluster-wide, while the controller is only allowed to read ConfigMaps of its namespace.
+		configMapCache, err := controllerCache.New(mgr.GetConfig(), controllerCache.Options{
+			Scheme:    mgr.GetScheme(),
+			Mapper:    mgr.GetRESTMapper(),
+			Namespace: namespace,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create pause ConfigMap cache")
+			os.Exit(1)
+		}
+		if err := mgr.Add(configMapCache); err != nil {
+			setupLog.Error(err, "unable to add pause ConfigMap cache")
+			os.Exit(1)
+		}
+		pause.Reader = configMapCache
+		pause.ConfigMap = types.NamespacedName{Namespace: namespace, Name: name}
 	}
 
-	if err := (&controllers.ApplicationReconciler{
-		Client:          mgr.GetClient(),
-		Log:             ctrl.Log.WithName("controllers").WithName("Application"),
-		Scheme:          mgr.GetScheme(),
-		Recorder:        mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
-		SpinnakerClient: spinnaker.New(spinnakerEndpoint, &http.Client{}),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Application")
+	setVerbosity(cfg.Verbose)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Endpoint, cfg.Tracing.SampleRatio)
+	if err != nil {
+		setupLog.Error(err, "unable to configure tracing")
 		os.Exit(1)
 	}
-	if err := (&controllers.PipelineTemplateReconciler{
-		Client:          mgr.GetClient(),
-		Log:             ctrl.Log.WithName("controllers").WithName("PipelineTemplate"),
-		Scheme:          mgr.GetScheme(),
-		Recorder:        mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
-		SpinnakerClient: spinnaker.New(spinnakerEndpoint, &http.Client{}),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PipelineTemplate")
+	settings := controllers.NewSettings(settingValues(cfg))
+	selector, err := cfg.Selector()
+	if err != nil {
+		setupLog.Error(err, "unable to parse watch selector")
 		os.Exit(1)
 	}
-	if err := (&controllers.PipelineReconciler{
-		Client:          mgr.GetClient(),
-		Log:             ctrl.Log.WithName("controllers").WithName("Pipeline"),
-		Scheme:          mgr.GetScheme(),
-		Recorder:        mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
-		SpinnakerClient: spinnaker.New(spinnakerEndpoint, &http.Client{}),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Pipeline")
+
+	// Requests to Gate in flight are cancelled on shutdown, so that reconcilers do not outlive the manager.
+	stop := ctrl.SetupSignalHandler()
+	gatewayOptions, err := newGatewayOptions(cfg.Gate)
+	if err != nil {
+		setupLog.Error(err, "unable to configure Gate client")
 		os.Exit(1)
 	}
-	if err := (&controllers.CanaryConfigReconciler{
-		Client:          mgr.GetClient(),
-		Log:             ctrl.Log.WithName("controllers").WithName("CanaryConfig"),
-		Scheme:          mgr.GetScheme(),
-		Recorder:        mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
-		SpinnakerClient: buildSpinGateClient(spinnakerEndpoint),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "CanaryConfig")
+	gatewayOptions.Stop = stop
+	gatewayClient, err := gateway.New(cfg.Gate.Endpoint, gatewayOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to create Gate client")
 		os.Exit(1)
 	}
 
+	var shards *sharding.Shards
+	if cfg.Sharding.Shards > 0 {
+		shards, err = newShards(mgr, cfg.Sharding)
+		if err != nil {
+			setupLog.Error(err, "unable to configure sharding")
+			os.Exit(1)
+		}
+		if err := mgr.Add(shards); err != nil {
+			setupLog.Error(err, "unable to add sharding")
+			os.Exit(1)
+		}
+	}
+
+	var auditSink io.Writer
+	if cfg.Audit.File != "" {
+		auditFile, err := os.OpenFile(cfg.Audit.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			setupLog.Error(err, "unable to open audit log file")
+			os.Exit(1)
+		}
+		defer auditFile.Close()
+		auditSink = auditFile
+	}
+	auditor := audit.New(ctrl.Log.WithName("audit"), auditSink)
+
+	var revisions *controllers.Revisions
+	if cfg.RevisionHistory.Limit > 0 {
+		namespace, err := controllerNamespace(cfg.RevisionHistory.Namespace)
+		if err != nil {
+			// Running outside of a cluster, e.g. with make run, does not require a namespace.
+			setupLog.Info("revision history is disabled, set revisionHistory.namespace to enable it", "reason", err.Error())
+		} else {
+			revisions = &controllers.Revisions{
+				Client:    mgr.GetClient(),
+				Reader:    mgr.GetAPIReader(),
+				Scheme:    mgr.GetScheme(),
+				Namespace: namespace,
+				Limit:     cfg.RevisionHistory.Limit,
+			}
+		}
+	}
+
+	// Gate does not expose service accounts for writing, and Front50 trusts the user it is told without authenticating
+	// it, so that SpinnakerServiceAccount calls Front50 directly only when front50Endpoint is set explicitly. The calls
+	// are retried, rate limited and circuit broken like the ones to Gate, but sent without the credentials of Gate.
+	var front50Client controllers.Front50Client
+	if cfg.Front50Endpoint != "" {
+		front50Options := gatewayOptions
+		front50Options.Headers = nil
+		front50Options.TLSConfig = nil
+		front50Client = controllers.NewFront50Client(cfg.Front50Endpoint, gateway.NewHTTPClient("Front50", front50Options))
+	}
+	reconcilers := []struct {
+		kind       string
+		reconciler interface{ SetupWithManager(ctrl.Manager) error }
+	}{
+		{"Application", &controllers.ApplicationReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("Application"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Gateway:                 gatewayClient,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Revisions:               revisions,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("Application"),
+		}},
+		{"PipelineTemplate", &controllers.PipelineTemplateReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("PipelineTemplate"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Gateway:                 gatewayClient,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Revisions:               revisions,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("PipelineTemplate"),
+		}},
+		{"Pipeline", &controllers.PipelineReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("Pipeline"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Gateway:                 gatewayClient,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Revisions:               revisions,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("Pipeline"),
+		}},
+		{"CanaryConfig", &controllers.CanaryConfigReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("CanaryConfig"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Gateway:                 gatewayClient,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Revisions:               revisions,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("CanaryConfig"),
+		}},
+		{"PipelineExecution", &controllers.PipelineExecutionReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("PipelineExecution"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Gateway:                 gatewayClient,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("PipelineExecution"),
+		}},
+		{"Project", &controllers.ProjectReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("Project"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Gateway:                 gatewayClient,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Revisions:               revisions,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("Project"),
+		}},
+		{"SpinnakerServiceAccount", &controllers.SpinnakerServiceAccountReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("SpinnakerServiceAccount"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Front50Client:           front50Client,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Revisions:               revisions,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("SpinnakerServiceAccount"),
+		}},
+		{"CanaryAnalysis", &controllers.CanaryAnalysisReconciler{
+			Client:                  mgr.GetClient(),
+			Log:                     ctrl.Log.WithName("controllers").WithName("CanaryAnalysis"),
+			Scheme:                  mgr.GetScheme(),
+			Recorder:                mgr.GetEventRecorderFor("spinnaker-dcd-controller"),
+			Gateway:                 gatewayClient,
+			DeckEndpoint:            cfg.DeckEndpoint,
+			Pause:                   pause,
+			Settings:                settings,
+			Audit:                   auditor,
+			Selector:                selector,
+			Shards:                  shards,
+			MaxConcurrentReconciles: cfg.ConcurrentReconciles("CanaryAnalysis"),
+		}},
+	}
+	for _, r := range reconcilers {
+		if !cfg.Enabled(r.kind) {
+			setupLog.Info("skip disabled controller", "controller", r.kind)
+			continue
+		}
+		if r.kind == "SpinnakerServiceAccount" && front50Client == nil {
+			setupLog.Info("skip controller calling Front50 directly, set front50Endpoint to enable it", "controller", r.kind)
+			continue
+		}
+		if err := r.reconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", r.kind)
+			os.Exit(1)
+		}
+	}
+
+	if configFile != "" {
+		go config.Watch(configFile, configReloadInterval, stop, func() {
+			reloaded, _, err := loadConfiguration(args)
+			if err != nil {
+				setupLog.Error(err, "unable to reload configuration, keep the previous one")
+				return
+			}
+			if cfg.RequiresRestart(reloaded) {
+				setupLog.Info("configuration has changed in fields that take effect on restart", "file", configFile)
+			}
+			settings.Set(settingValues(reloaded))
+			setVerbosity(reloaded.Verbose)
+			setupLog.Info("reloaded configuration", "file", configFile)
+		})
+	}
+
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(stop)
+	// Spans of the last reconciliations are flushed before exit.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+	cancel()
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
 
-func buildSpinGateClient(spinnakerEndpoint string) gateclient.GatewayClient {
-	oft, _ := output.ParseOutputFormat("json")
-	ui := output.NewUI(true, true, oft, os.Stdout, os.Stderr)
-	gateClient, _ := gateclient.NewGateClient(ui, spinnakerEndpoint, "", "", false)
+const (
+	// configReloadInterval is how often the configuration file is checked for change
+	configReloadInterval = 10 * time.Second
+	// tracingShutdownTimeout bounds flushing spans on exit
+	tracingShutdownTimeout = 5 * time.Second
+	// inClusterNamespacePath is the namespace of the controller running in a cluster
+	inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// loadConfiguration returns the configuration of the file given by --config, overridden by the flags in args, and the
+// path of the file.
+func loadConfiguration(args []string) (*config.ControllerConfiguration, string, error) {
+	var configFile string
+	c := config.Default()
+	_ = newFlagSet(c, &configFile).Parse(args)
+	if configFile != "" {
+		loaded, err := config.Load(configFile)
+		if err != nil {
+			return nil, configFile, err
+		}
+		c = loaded
+		// Parsing again over the file keeps the flags given on the command line over its values.
+		_ = newFlagSet(c, &configFile).Parse(args)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, configFile, err
+	}
+	return c, configFile, nil
+}
+
+// newFlagSet returns the flags bound to the fields of c, whose values are the defaults of the flags.
+func newFlagSet(c *config.ControllerConfiguration, configFile *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	fs.StringVar(configFile, "config", "", "The ControllerConfiguration file, whose values are overridden by flags. Changes of its reconciliation fields are reloaded at runtime.")
+	fs.StringVar(&c.MetricsBindAddress, "metrics-addr", c.MetricsBindAddress, "The address the metric endpoint binds to.")
+	fs.BoolVar(&c.LeaderElection.LeaderElect, "enable-leader-election", c.LeaderElection.LeaderElect,
+		"Enable leader election for controller manager.")
+	fs.StringVar(&c.Gate.Endpoint, "spinnaker-endpoint", c.Gate.Endpoint, "The endpoint of Spinnaker Gate.")
+	fs.StringVar(&c.Front50Endpoint, "front50-endpoint", c.Front50Endpoint, "The endpoint of Spinnaker Front50, which enables SpinnakerServiceAccount by calling Front50 directly, since Gate does not expose service accounts for writing.")
+	fs.StringVar(&c.DeckEndpoint, "deck-endpoint", c.DeckEndpoint, "The endpoint of Spinnaker Deck, which is used to link canary analysis reports.")
+	fs.DurationVar(&c.Gate.Timeout.Duration, "gate-timeout", c.Gate.Timeout.Duration, "The timeout of each request to Spinnaker Gate.")
+	fs.IntVar(&c.Gate.MaxRetries, "gate-max-retries", c.Gate.MaxRetries, "The number of retries of a request to Spinnaker Gate that failed with 5xx or 429.")
+	fs.Float64Var(&c.Gate.QPS, "gate-qps", c.Gate.QPS, "The rate of requests to Spinnaker Gate shared by all controllers, or 0 to disable rate limiting.")
+	fs.IntVar(&c.Gate.Burst, "gate-burst", c.Gate.Burst, "The number of requests to Spinnaker Gate that can be sent at once above --gate-qps.")
+	fs.Var(concurrencyFlag{c}, "max-concurrent-reconciles", "The number of concurrent reconciliations of each controller, or <Kind>=<number> for one controller. Can be repeated.")
+	fs.BoolVar(&c.Pause.Paused, "paused", c.Pause.Paused, "Pause reconciliation of all resources, so that nothing is pushed to Spinnaker.")
+	fs.StringVar(&c.Pause.ConfigMap, "pause-config-map", c.Pause.ConfigMap, "The namespace/name of a ConfigMap which pauses reconciliation of all resources while its \"paused\" key is \"true\".")
+	fs.Var(controllersFlag{c}, "controllers", "The comma-separated kinds of the controllers to enable, e.g. CanaryConfig,CanaryAnalysis. All controllers are enabled by default.")
+	fs.IntVar(&c.Sharding.Shards, "shards", c.Sharding.Shards, "The number of shards that spread resources across replicas by their Spinnaker application, or 0 to disable sharding. Requires leader election to be disabled.")
+	fs.StringVar(&c.Audit.File, "audit-log-file", c.Audit.File, "The file that audit records of the changes pushed to Spinnaker are appended to, in addition to the log stream named audit.")
+	fs.IntVar(&c.RevisionHistory.Limit, "revision-history-limit", c.RevisionHistory.Limit, "The number of payloads applied to Spinnaker kept per resource in ControllerRevisions for rollback, or 0 to keep no history.")
+	fs.StringVar(&c.Tracing.Endpoint, "tracing-endpoint", c.Tracing.Endpoint, "The OTLP/HTTP endpoint that traces are exported to, e.g. http://otel-collector:4318, or empty to disable tracing.")
+	fs.StringVar(&c.WatchSelector, "watch-selector", c.WatchSelector, "The label selector of the resources managed by this controller, e.g. spinnaker.io/managed-by=team-a, so that several deployments can share a cluster.")
+	fs.BoolVar(&c.Verbose, "verbose", c.Verbose, "Make the operation more talkative.")
+	return fs
+}
+
+// newGatewayOptions returns the options of the Gate client, reading the credentials from their files.
+func newGatewayOptions(c config.GateConfiguration) (gateway.Options, error) {
+	options := gateway.DefaultOptions()
+	options.Timeout = c.Timeout.Duration
+	options.MaxRetries = c.MaxRetries
+	options.QPS = c.QPS
+	options.Burst = c.Burst
+
+	options.Headers = map[string]string{}
+	for key, value := range c.Auth.Headers {
+		options.Headers[key] = value
+	}
+	if c.Auth.BearerTokenFile != "" {
+		token, err := ioutil.ReadFile(c.Auth.BearerTokenFile)
+		if err != nil {
+			return options, xerrors.Errorf("failed to read bearer token: %w", err)
+		}
+		options.Headers["Authorization"] = "Bearer " + strings.TrimSpace(string(token))
+	}
+
+	if c.Auth.CertFile != "" || c.Auth.CAFile != "" {
+		tlsConfig := &tls.Config{}
+		if c.Auth.CertFile != "" {
+			certificate, err := tls.LoadX509KeyPair(c.Auth.CertFile, c.Auth.KeyFile)
+			if err != nil {
+				return options, xerrors.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+		if c.Auth.CAFile != "" {
+			ca, err := ioutil.ReadFile(c.Auth.CAFile)
+			if err != nil {
+				return options, xerrors.Errorf("failed to read certificate authorities: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return options, xerrors.Errorf("no certificate authority is found in %s", c.Auth.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		options.TLSConfig = tlsConfig
+	}
+	return options, nil
+}
 
-	return *gateClient
+// controllerNamespace returns configured, or the namespace of the controller when it is empty.
+func controllerNamespace(configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	data, err := ioutil.ReadFile(inClusterNamespacePath)
+	if err != nil {
+		return "", xerrors.Errorf("unable to find the namespace of the controller: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// newShards returns the shards of this replica, whose Leases live in the namespace of the controller unless configured.
+func newShards(mgr ctrl.Manager, c config.ShardingConfiguration) (*sharding.Shards, error) {
+	namespace, err := controllerNamespace(c.LeaseNamespace)
+	if err != nil {
+		return nil, xerrors.Errorf("set sharding.leaseNamespace: %w", err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	return sharding.New(clientset.CoordinationV1(), sharding.Options{
+		Shards:        c.Shards,
+		Namespace:     namespace,
+		Name:          c.LeaseName,
+		Identity:      hostname + "_" + string(uuid.NewUUID()),
+		LeaseDuration: c.LeaseDuration.Duration,
+		RenewDeadline: c.RenewDeadline.Duration,
+		RetryPeriod:   c.RetryPeriod.Duration,
+	}, ctrl.Log.WithName("sharding")), nil
+}
+
+func settingValues(c *config.ControllerConfiguration) controllers.SettingValues {
+	return controllers.SettingValues{
+		DependencyWaitInterval:  c.Reconciliation.DependencyWaitInterval.Duration,
+		ExecutionPollInterval:   c.Reconciliation.ExecutionPollInterval.Duration,
+		ExecutionResyncInterval: c.Reconciliation.ExecutionResyncInterval.Duration,
+		ExecutionTriggerTimeout: c.Reconciliation.ExecutionTriggerTimeout.Duration,
+		InvalidResyncInterval:   c.Reconciliation.InvalidResyncInterval.Duration,
+		TaskPollTimeout:         c.Reconciliation.TaskPollTimeout.Duration,
+		DeletionPolicy:          c.Reconciliation.DeletionPolicy,
+	}
+}
+
+func setVerbosity(verbose bool) {
+	if verbose {
+		logrus.SetLevel(logrus.DebugLevel)
+	} else {
+		logrus.SetLevel(logrus.InfoLevel)
+	}
+}
+
+// concurrencyFlag sets the number of concurrent reconciliations of each controller by its kind, or of all controllers
+// without kind.
+type concurrencyFlag struct {
+	c *config.ControllerConfiguration
+}
+
+func (f concurrencyFlag) String() string {
+	if f.c == nil {
+		return ""
+	}
+	return strconv.Itoa(f.c.MaxConcurrentReconciles)
+}
+
+func (f concurrencyFlag) Set(value string) error {
+	kind, number := "", value
+	if i := strings.Index(value, "="); i >= 0 {
+		kind, number = value[:i], value[i+1:]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		return xerrors.Errorf("invalid number of concurrent reconciliations %q", value)
+	}
+	if kind == "" {
+		f.c.MaxConcurrentReconciles = n
+		return nil
+	}
+	if f.c.Controllers == nil {
+		f.c.Controllers = map[string]config.ControllerOptions{}
+	}
+	options := f.c.Controllers[kind]
+	options.MaxConcurrentReconciles = n
+	f.c.Controllers[kind] = options
+	return nil
+}
+
+// controllersFlag enables only the listed controllers
+type controllersFlag struct {
+	c *config.ControllerConfiguration
+}
+
+func (f controllersFlag) String() string {
+	if f.c == nil {
+		return ""
+	}
+	var kinds []string
+	for _, kind := range config.Kinds {
+		if f.c.Enabled(kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return strings.Join(kinds, ",")
+}
+
+func (f controllersFlag) Set(value string) error {
+	var kinds []string
+	for _, kind := range strings.Split(value, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+	return f.c.EnableOnly(kinds)
 }